@@ -9,12 +9,45 @@ type Config struct {
 	Log   string `yaml:"log" default:"" env:"LOG_PATH"`
 	Debug bool   `yaml:"debug" default:"false" env:"DEBUG"` // Log file path
 	Fetch struct {
-		Timeout          int    `yaml:"timeout" default:"10" env:"FETCH_TIMEOUT"` // Timeout in seconds
-		UserAgent        string `yaml:"user_agent" default:"mcp-fetch/1.0" env:"FETCH_USER_AGENT"`
-		MaxURLs          int    `yaml:"max_urls" default:"20" env:"FETCH_MAX_URLS"`                       // Maximum number of URLs that can be processed at once
-		MaxWorkers       int    `yaml:"max_workers" default:"20" env:"FETCH_MAX_WORKERS"`                 // Number of workers used for parallel processing
-		DefaultMaxLength int    `yaml:"default_max_length" default:"5000" env:"FETCH_DEFAULT_MAX_LENGTH"` // Default maximum character count for returned content
+		Timeout           int    `yaml:"timeout" default:"10" env:"FETCH_TIMEOUT"` // Timeout in seconds
+		UserAgent         string `yaml:"user_agent" default:"mcp-fetch/1.0" env:"FETCH_USER_AGENT"`
+		MaxURLs           int    `yaml:"max_urls" default:"20" env:"FETCH_MAX_URLS"`                          // Maximum number of URLs that can be processed at once
+		MaxWorkers        int    `yaml:"max_workers" default:"20" env:"FETCH_MAX_WORKERS"`                    // Number of workers used for parallel processing
+		DefaultMaxLength  int    `yaml:"default_max_length" default:"5000" env:"FETCH_DEFAULT_MAX_LENGTH"`    // Default maximum character count for returned content
+		Tokenizer         string `yaml:"tokenizer" default:"heuristic" env:"FETCH_TOKENIZER"`                 // Tokenizer for max_tokens and token counts (heuristic or cl100k)
+		Robots            string `yaml:"robots" default:"enforce" env:"FETCH_ROBOTS"`                         // robots.txt policy (enforce, warn or off)
+		RobotsUserAgent   string `yaml:"robots_user_agent" default:"" env:"FETCH_ROBOTS_USER_AGENT"`          // Product token matched in robots.txt (defaults to the user_agent product name)
+		DeadlineMs        int    `yaml:"deadline_ms" default:"0" env:"FETCH_DEADLINE_MS"`                     // Default overall deadline for fetch_multiple in milliseconds (0 disables it)
+		HTTPErrors        string `yaml:"http_errors" default:"content" env:"FETCH_HTTP_ERRORS"`               // Default handling of 4xx/5xx responses (content, excerpt or error)
+		MaxBodySize       int64  `yaml:"max_body_size" default:"10485760" env:"FETCH_MAX_BODY_SIZE"`          // Largest response body read, in bytes
+		HistorySize       int    `yaml:"history_size" default:"50" env:"FETCH_HISTORY_SIZE"`                  // Number of documents listed by the fetch-history:// resource (0 disables it)
+		ResourceMaxLength int    `yaml:"resource_max_length" default:"50000" env:"FETCH_RESOURCE_MAX_LENGTH"` // Maximum character count of fetch:// resources
+		ResourceCacheTTL  int    `yaml:"resource_cache_ttl" default:"300" env:"FETCH_RESOURCE_CACHE_TTL"`     // Seconds fetch:// resource reads are cached (0 disables it)
+		WatchInterval     int    `yaml:"watch_interval" default:"300" env:"FETCH_WATCH_INTERVAL"`             // Default polling interval of watched URLs in seconds
+		WatchMinInterval  int    `yaml:"watch_min_interval" default:"30" env:"FETCH_WATCH_MIN_INTERVAL"`      // Shortest polling interval of watched URLs in seconds
+		MaxWatches        int    `yaml:"max_watches" default:"20" env:"FETCH_MAX_WATCHES"`                    // Maximum number of URLs watched at once, per auth client
+		SnapshotURLs      int    `yaml:"snapshot_urls" default:"100" env:"FETCH_SNAPSHOT_URLS"`               // Number of URLs whose snapshots are kept for fetch_diff (0 disables it)
+		SnapshotsPerURL   int    `yaml:"snapshots_per_url" default:"5" env:"FETCH_SNAPSHOTS_PER_URL"`         // Number of snapshots kept per URL
 	} `yaml:"fetch"`
+	Transport struct {
+		Type            string `yaml:"type" default:"stdio" env:"TRANSPORT_TYPE"`                      // MCP transport (stdio, sse or http for streamable HTTP)
+		Addr            string `yaml:"addr" default:"127.0.0.1:8080" env:"TRANSPORT_ADDR"`             // Listen address of the sse and http transports
+		BaseURL         string `yaml:"base_url" default:"" env:"TRANSPORT_BASE_URL"`                   // Public URL of the server, used in the SSE endpoint event (defaults to relative URLs)
+		ShutdownTimeout int    `yaml:"shutdown_timeout" default:"30" env:"TRANSPORT_SHUTDOWN_TIMEOUT"` // Seconds to wait for in-flight requests on shutdown
+	} `yaml:"transport"`
+	Auth struct {
+		Clients []AuthClient `yaml:"clients"` // Clients allowed on the sse and http transports (no authentication if empty)
+	} `yaml:"auth"`
+}
+
+// AuthClient - A client of the network transports, identified by its token
+type AuthClient struct {
+	Name             string   `yaml:"name"`               // Identity logged with every fetch
+	Token            string   `yaml:"token"`              // Bearer token or API key
+	AllowedDomains   []string `yaml:"allowed_domains"`    // Domains the client may fetch, with their subdomains (all if empty)
+	MaxURLs          int      `yaml:"max_urls"`           // Maximum number of URLs per fetch_multiple (fetch.max_urls if 0)
+	DefaultMaxLength int      `yaml:"default_max_length"` // Default maximum character count for returned content (fetch.default_max_length if 0)
+	RateLimit        int      `yaml:"rate_limit"`         // Fetches per minute (unlimited if 0)
 }
 
 // LoadConfig - Load configuration file
@@ -0,0 +1,83 @@
+package fetcher
+
+import (
+	"github.com/cnosuke/mcp-fetch/tokenizer"
+)
+
+// Names of the budget units, reported in types.MultipleFetchResponse.
+const (
+	charUnitName  = "chars"
+	tokenUnitName = "tokens"
+)
+
+// budgetUnit measures and trims content in the unit a length limit is expressed in,
+// so the same allocation logic can distribute characters or tokens.
+type budgetUnit struct {
+	name    string
+	measure func(content string) int
+	trim    func(content string, start int, limit int) string
+}
+
+// charUnit counts content length in characters (bytes), as max_length does.
+func charUnit() budgetUnit {
+	return budgetUnit{
+		name:    charUnitName,
+		measure: func(content string) int { return len(content) },
+		trim:    trimContent,
+	}
+}
+
+// tokenUnit counts content length in tokens of t, as max_tokens does.
+func tokenUnit(t tokenizer.Tokenizer) budgetUnit {
+	return budgetUnit{
+		name:    tokenUnitName,
+		measure: t.Count,
+		trim: func(content string, start int, limit int) string {
+			return tokenizer.Trim(t, content, start, limit)
+		},
+	}
+}
+
+// allocateBudget distributes budget among items in proportion to their
+// weights, giving no item more than its demand. Budget left by items that
+// need less than their share is redistributed among the others until every
+// item is satisfied or the budget is used up. Weights must be positive.
+func allocateBudget(budget int, demands []int, weights []float64) []int {
+	allocations := make([]int, len(demands))
+	active := []int{}
+	for i, d := range demands {
+		if d > 0 {
+			active = append(active, i)
+		}
+	}
+
+	remaining := budget
+	for len(active) > 0 && remaining > 0 {
+		totalWeight := 0.0
+		for _, i := range active {
+			totalWeight += weights[i]
+		}
+
+		// Satisfy every item whose demand fits in its share, then redistribute
+		unsatisfied := []int{}
+		released := 0
+		for _, i := range active {
+			share := float64(remaining) * weights[i] / totalWeight
+			if float64(demands[i]) <= share {
+				allocations[i] = demands[i]
+				released += demands[i]
+			} else {
+				unsatisfied = append(unsatisfied, i)
+			}
+		}
+		if len(unsatisfied) == len(active) {
+			for _, i := range active {
+				allocations[i] = int(float64(remaining) * weights[i] / totalWeight)
+			}
+			break
+		}
+		remaining -= released
+		active = unsatisfied
+	}
+	return allocations
+}
@@ -0,0 +1,30 @@
+package fetcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocateBudget(t *testing.T) {
+	tests := []struct {
+		name     string
+		budget   int
+		demands  []int
+		weights  []float64
+		expected []int
+	}{
+		{name: "leftover redistributed", budget: 30, demands: []int{10, 30, 5}, weights: []float64{1, 1, 1}, expected: []int{10, 15, 5}},
+		{name: "everything fits", budget: 50, demands: []int{5, 5, 5}, weights: []float64{1, 1, 1}, expected: []int{5, 5, 5}},
+		{name: "weighted split", budget: 30, demands: []int{30, 30}, weights: []float64{2, 1}, expected: []int{20, 10}},
+		{name: "satisfied heavy item releases budget", budget: 30, demands: []int{5, 30, 30}, weights: []float64{4, 1, 1}, expected: []int{5, 12, 12}},
+		{name: "capped demand", budget: 100, demands: []int{10, 200}, weights: []float64{5, 1}, expected: []int{10, 90}},
+		{name: "empty document", budget: 10, demands: []int{0, 20}, weights: []float64{1, 1}, expected: []int{0, 10}},
+		{name: "no budget", budget: 0, demands: []int{10}, weights: []float64{1}, expected: []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, allocateBudget(tt.budget, tt.demands, tt.weights))
+		})
+	}
+}
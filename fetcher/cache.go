@@ -0,0 +1,153 @@
+package fetcher
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+)
+
+// DefaultCacheSize is the number of responses a Cache keeps by default.
+const DefaultCacheSize = 100
+
+// Cache keeps the responses of recent fetches for a while, by URL and
+// options. The least recently stored responses are dropped first. It is safe
+// for concurrent use.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]cacheEntry
+	order   []string // Keys, least recently stored first
+}
+
+// cacheEntry is a cached response.
+type cacheEntry struct {
+	response *types.FetchResponse
+	expires  time.Time
+}
+
+// NewCache creates a Cache keeping up to size responses, or DefaultCacheSize
+// if size is not positive, for ttl each.
+func NewCache(size int, ttl time.Duration) *Cache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &Cache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// get returns a copy of the response stored under key, if it has not expired.
+func (c *Cache) get(key string) (*types.FetchResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || !c.now().Before(entry.expires) {
+		return nil, false
+	}
+	response := *entry.response
+	return &response, true
+}
+
+// put stores response under key, dropping expired responses and then the
+// oldest ones beyond the size of the cache.
+func (c *Cache) put(key string, response *types.FetchResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	stored := *response
+	c.entries[key] = cacheEntry{response: &stored, expires: now.Add(c.ttl)}
+
+	order := make([]string, 0, len(c.order)+1)
+	for _, k := range c.order {
+		if k != key && now.Before(c.entries[k].expires) {
+			order = append(order, k)
+		} else if k != key {
+			delete(c.entries, k)
+		}
+	}
+	order = append(order, key)
+	for len(order) > c.size {
+		delete(c.entries, order[0])
+		order = order[1:]
+	}
+	c.order = order
+}
+
+// cacheFetcher answers the single fetches of the Fetcher it wraps from a
+// Cache.
+type cacheFetcher struct {
+	Fetcher
+	cache *Cache
+	guard URLGuard // Checks the URLs of the responses served from the cache if set
+}
+
+// CacheResponses returns a Fetcher that behaves like f, except that Fetch and
+// FetchWithOptions return the response cached for the same URL and options
+// while it has not expired. Only successful fetches are cached.
+func CacheResponses(f Fetcher, cache *Cache) Fetcher {
+	return &cacheFetcher{Fetcher: f, cache: cache}
+}
+
+// WithGuard implements Fetcher. The guarded Fetcher shares the cache. The
+// responses it serves from the cache are checked with guard as if they were
+// requested again: the URL, then the final URL if it was redirected.
+func (f *cacheFetcher) WithGuard(guard URLGuard) Fetcher {
+	return &cacheFetcher{Fetcher: f.Fetcher.WithGuard(guard), cache: f.cache, guard: chainGuards(f.guard, guard)}
+}
+
+// checkCached checks the URLs of a cached response of urlStr with the guard.
+func (f *cacheFetcher) checkCached(urlStr string, response *types.FetchResponse) error {
+	if f.guard == nil {
+		return nil
+	}
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return ierrors.WithKind(ierrors.Wrap(err, "invalid URL"), ierrors.ErrInvalidArgument)
+	}
+	if err := f.guard(u, false); err != nil {
+		return err
+	}
+	if response.OriginalURL == "" || response.FinalURL == "" {
+		return nil
+	}
+	final, err := url.Parse(response.FinalURL)
+	if err != nil {
+		return ierrors.Wrap(err, "invalid final URL")
+	}
+	return f.guard(final, true)
+}
+
+// Fetch implements Fetcher.
+func (f *cacheFetcher) Fetch(urlStr string, maxLength int, startIndex int, raw bool) (*types.FetchResponse, error) {
+	return f.FetchWithOptions(urlStr, FetchOptions{MaxLength: maxLength, StartIndex: startIndex, Raw: raw})
+}
+
+// FetchWithOptions implements Fetcher. Conditional requests and requests
+// reporting progress are not cached.
+func (f *cacheFetcher) FetchWithOptions(urlStr string, opts FetchOptions) (*types.FetchResponse, error) {
+	if opts.Progress != nil || opts.IfNoneMatch != "" || opts.IfModifiedSince != "" {
+		return f.Fetcher.FetchWithOptions(urlStr, opts)
+	}
+	key := urlStr + "\x00" + fmt.Sprintf("%+v", opts)
+	if response, ok := f.cache.get(key); ok {
+		if err := f.checkCached(urlStr, response); err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+	response, err := f.Fetcher.FetchWithOptions(urlStr, opts)
+	if err != nil {
+		return nil, err
+	}
+	f.cache.put(key, response)
+	return response, nil
+}
@@ -0,0 +1,113 @@
+package fetcher
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	cache := NewCache(2, time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.put("a", &types.FetchResponse{Content: "A"})
+	cache.put("b", &types.FetchResponse{Content: "B"})
+	cache.put("c", &types.FetchResponse{Content: "C"})
+
+	// The oldest response is dropped beyond the size of the cache
+	_, ok := cache.get("a")
+	assert.False(t, ok)
+	response, ok := cache.get("c")
+	require.True(t, ok)
+	assert.Equal(t, "C", response.Content)
+
+	// Returned responses are copies
+	response.Content = "changed"
+	response, _ = cache.get("c")
+	assert.Equal(t, "C", response.Content)
+
+	now = now.Add(time.Minute)
+	_, ok = cache.get("c")
+	assert.False(t, ok)
+
+	assert.Equal(t, DefaultCacheSize, NewCache(0, time.Minute).size)
+}
+
+func TestCacheResponses(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&hits, 1)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("cached page"))
+	}))
+	t.Cleanup(server.Close)
+
+	fetcher := CacheResponses(newTestFetcherWithTokenizer(t, "heuristic"), NewCache(10, time.Minute))
+
+	for range 2 {
+		response, err := fetcher.FetchWithOptions(server.URL+"/page", FetchOptions{MaxLength: 100})
+		require.NoError(t, err)
+		assert.Equal(t, "cached page", response.Content)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+
+	// Other options are fetched again
+	response, err := fetcher.FetchWithOptions(server.URL+"/page", FetchOptions{MaxLength: 6})
+	require.NoError(t, err)
+	assert.Equal(t, "cached", response.Content)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+
+	// Failures are not cached
+	for range 2 {
+		_, err = fetcher.FetchWithOptions(server.URL+"/missing", FetchOptions{HTTPErrors: HTTPErrorsError})
+		require.Error(t, err)
+	}
+	assert.Equal(t, int32(4), atomic.LoadInt32(&hits))
+}
+
+func TestCacheResponses_Policy(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("cached page"))
+	}))
+	t.Cleanup(server.Close)
+
+	cached := CacheResponses(newTestFetcherWithTokenizer(t, "heuristic"), NewCache(10, time.Minute))
+	fetcher := WithPolicy(cached, NewPolicy("alice", nil, 0, 2))
+
+	// Responses served from the cache still count against the rate limit
+	for range 2 {
+		_, err := fetcher.FetchWithOptions(server.URL+"/page", FetchOptions{MaxLength: 100})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits))
+	_, err := fetcher.FetchWithOptions(server.URL+"/page", FetchOptions{MaxLength: 100})
+	assert.True(t, errors.Is(err, ierrors.ErrRateLimited))
+
+	// The shared cache is not restricted by the policy
+	_, err = cached.FetchWithOptions(server.URL+"/page", FetchOptions{MaxLength: 100})
+	assert.NoError(t, err)
+}
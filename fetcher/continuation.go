@@ -0,0 +1,89 @@
+package fetcher
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+)
+
+// continuationState is the content of a continuation token: the budget and
+// settings of the call that produced it and where to resume each truncated
+// document.
+type continuationState struct {
+	Unit                string             `json:"unit"`
+	MaxLength           int                `json:"max_length"`
+	HTTPErrors          string             `json:"http_errors,omitempty"`
+	DeadlineMs          int64              `json:"deadline_ms,omitempty"`
+	IncludeResponseInfo bool               `json:"include_response_info,omitempty"`
+	Items               []continuationItem `json:"items"`
+}
+
+// continuationItem resumes one document with the settings it was fetched with.
+type continuationItem struct {
+	URL        string  `json:"url"`
+	StartIndex int     `json:"start_index"`
+	Weight     float64 `json:"weight,omitempty"`
+	MaxLength  int     `json:"max_length,omitempty"`
+	Raw        bool    `json:"raw,omitempty"`
+	Format     Format  `json:"format,omitempty"`
+	Selector   string  `json:"selector,omitempty"`
+}
+
+// encodeContinuation serializes state as an opaque URL-safe token.
+func encodeContinuation(state continuationState) (string, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", ierrors.Wrap(err, "failed to encode continuation token")
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// ParseContinuation decodes a continuation token returned by
+// FetchMultipleWithOptions into the URLs and options of the follow-up call.
+// The options reuse the budget, unit, deadline and 4xx/5xx handling of the
+// call that returned the token, and whether it included response info.
+func ParseContinuation(token string) ([]string, MultipleFetchOptions, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, MultipleFetchOptions{}, ierrors.WithKind(ierrors.Wrap(err, "invalid continuation token"), ierrors.ErrInvalidArgument)
+	}
+	var state continuationState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, MultipleFetchOptions{}, ierrors.WithKind(ierrors.Wrap(err, "invalid continuation token"), ierrors.ErrInvalidArgument)
+	}
+	if len(state.Items) == 0 {
+		return nil, MultipleFetchOptions{}, ierrors.Newf(ierrors.ErrInvalidArgument, "invalid continuation token: no documents to continue")
+	}
+
+	opts := MultipleFetchOptions{
+		HTTPErrors:          state.HTTPErrors,
+		Deadline:            time.Duration(state.DeadlineMs) * time.Millisecond,
+		IncludeResponseInfo: state.IncludeResponseInfo,
+	}
+	switch state.Unit {
+	case charUnitName:
+		opts.MaxLength = state.MaxLength
+	case tokenUnitName:
+		opts.MaxTokens = state.MaxLength
+	default:
+		return nil, MultipleFetchOptions{}, ierrors.Newf(ierrors.ErrInvalidArgument, "invalid continuation token: unknown unit %q", state.Unit)
+	}
+
+	urls := make([]string, len(state.Items))
+	opts.Items = make([]MultipleFetchItem, len(state.Items))
+	for i, item := range state.Items {
+		raw := item.Raw
+		urls[i] = item.URL
+		opts.Items[i] = MultipleFetchItem{
+			Weight:     item.Weight,
+			MaxLength:  item.MaxLength,
+			StartIndex: item.StartIndex,
+			Raw:        &raw,
+			Format:     item.Format,
+			Selector:   item.Selector,
+		}
+	}
+	return urls, opts, nil
+}
@@ -0,0 +1,106 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPFetcher_FetchMultipleWithOptions_Items(t *testing.T) {
+	mockResponses := map[string]mockResponse{
+		"/main":  {Body: strings.Repeat("m", 100), ContentType: "text/plain", StatusCode: http.StatusOK},
+		"/side":  {Body: strings.Repeat("s", 100), ContentType: "text/plain", StatusCode: http.StatusOK},
+		"/small": {Body: "0123456789abcdefghij", ContentType: "text/plain", StatusCode: http.StatusOK},
+		"/page":  {Body: `<html><body><nav>Menu</nav><div class="content"><p>Body text</p></div></body></html>`, ContentType: "text/html", StatusCode: http.StatusOK},
+	}
+	server := startMockServer(t, mockResponses)
+	fetcher := newTestFetcher(t, server.URL)
+
+	urls := []string{server.URL + "/main", server.URL + "/side", server.URL + "/small"}
+	resp, err := fetcher.FetchMultipleWithOptions(context.Background(), urls, MultipleFetchOptions{
+		MaxLength: 60,
+		Raw:       true,
+		Items: []MultipleFetchItem{
+			{Weight: 3},
+			{},
+			{MaxLength: 4, StartIndex: 10},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 3)
+
+	// /small is capped at 4; the other 56 are split 3:1
+	assert.Equal(t, "abcd", resp.Results[2].Content)
+	assert.Equal(t, 10, resp.Results[2].StartIndex)
+	assert.Equal(t, 42, resp.Results[0].AllocatedLength)
+	assert.Equal(t, 14, resp.Results[1].AllocatedLength)
+
+	// Per-item selector and format
+	resp, err = fetcher.FetchMultipleWithOptions(context.Background(), []string{server.URL + "/page"}, MultipleFetchOptions{
+		MaxLength: 100,
+		Items:     []MultipleFetchItem{{Selector: ".content", Format: FormatText}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Body text", strings.TrimSpace(resp.Results[0].Content))
+	assert.Equal(t, "text", resp.Results[0].Format)
+
+	_, err = fetcher.FetchMultipleWithOptions(context.Background(), urls, MultipleFetchOptions{Items: []MultipleFetchItem{{}}})
+	require.Error(t, err)
+}
+
+func TestHTTPFetcher_FetchMultipleWithOptions_Continuation(t *testing.T) {
+	mockResponses := map[string]mockResponse{
+		"/long":  {Body: "0123456789abcdefghijklmnopqrstuvwxyz", ContentType: "text/plain", StatusCode: http.StatusOK},
+		"/short": {Body: "short", ContentType: "text/plain", StatusCode: http.StatusOK},
+		"/mid":   {Body: "ABCDEFGHIJKLMNOPQRST", ContentType: "text/plain", StatusCode: http.StatusOK},
+	}
+	server := startMockServer(t, mockResponses)
+	fetcher := newTestFetcher(t, server.URL)
+
+	urls := []string{server.URL + "/long", server.URL + "/short", server.URL + "/mid"}
+	resp, err := fetcher.FetchMultipleWithOptions(context.Background(), urls, MultipleFetchOptions{
+		MaxLength:           25,
+		Raw:                 true,
+		Deadline:            5 * time.Second,
+		HTTPErrors:          HTTPErrorsExcerpt,
+		IncludeResponseInfo: true,
+	})
+	require.NoError(t, err)
+	// 25 / 3 = 8 each; /short uses 5, the remaining 12 go to /long and /mid
+	assert.Equal(t, "0123456789", resp.Results[0].Content)
+	assert.Equal(t, "ABCDEFGHIJ", resp.Results[2].Content)
+	require.NotEmpty(t, resp.Continuation)
+
+	// The token resumes only the truncated documents, with the same budget
+	// and settings
+	nextURLs, opts, err := ParseContinuation(resp.Continuation)
+	require.NoError(t, err)
+	assert.Equal(t, []string{server.URL + "/long", server.URL + "/mid"}, nextURLs)
+	assert.Equal(t, 25, opts.MaxLength)
+	assert.Equal(t, 5*time.Second, opts.Deadline)
+	assert.Equal(t, HTTPErrorsExcerpt, opts.HTTPErrors)
+	assert.True(t, opts.IncludeResponseInfo)
+
+	next, err := fetcher.FetchMultipleWithOptions(context.Background(), nextURLs, opts)
+	require.NoError(t, err)
+	// /mid needs only 10 of its 12.5 share, so /long gets 15
+	assert.Equal(t, "abcdefghijklmno", next.Results[0].Content)
+	assert.Equal(t, 10, next.Results[0].StartIndex)
+	assert.Equal(t, "KLMNOPQRST", next.Results[1].Content)
+	require.NotEmpty(t, next.Continuation)
+
+	nextURLs, opts, err = ParseContinuation(next.Continuation)
+	require.NoError(t, err)
+	last, err := fetcher.FetchMultipleWithOptions(context.Background(), nextURLs, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "pqrstuvwxyz", last.Results[0].Content)
+	assert.Empty(t, last.Continuation)
+
+	_, _, err = ParseContinuation("not a token!")
+	require.Error(t, err)
+}
@@ -0,0 +1,390 @@
+package fetcher
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultCrawlDepth is the link depth the crawl tool follows unless told otherwise.
+	DefaultCrawlDepth = 2
+	// DefaultCrawlPages is the page limit used when CrawlOptions.MaxPages is not set.
+	DefaultCrawlPages = 20
+	// MaxCrawlPages is the upper bound for CrawlOptions.MaxPages.
+	MaxCrawlPages = 100
+	// maxSummaryLength caps the length of page summaries.
+	maxSummaryLength = 300
+)
+
+// Crawl scopes limiting which links are followed.
+const (
+	// CrawlScopeHost follows links to the start URL's host.
+	CrawlScopeHost = "host"
+	// CrawlScopePrefix follows links to the start URL's host under its directory path.
+	CrawlScopePrefix = "prefix"
+)
+
+// Crawl stop reasons reported in types.CrawlResponse.
+const (
+	crawlStopComplete  = "complete"
+	crawlStopMaxDepth  = "max_depth"
+	crawlStopMaxPages  = "max_pages"
+	crawlStopBudget    = "budget"
+	crawlStopCancelled = "cancelled"
+)
+
+// CrawlOptions controls the extent and output of Crawl.
+type CrawlOptions struct {
+	MaxDepth int    // Links followed from the start URL; 0 fetches only the start page
+	MaxPages int    // Pages fetched, including the start URL (default DefaultCrawlPages)
+	Scope    string // CrawlScopeHost (default) or CrawlScopePrefix
+	// PathPrefix restricts the crawl to this path on the start host, overriding Scope.
+	PathPrefix string
+	Include    []string // Regular expressions a URL must match to be followed
+	Exclude    []string // Regular expressions excluding matching URLs
+	// MaxLength is the total character budget for summaries or content of all pages.
+	MaxLength int
+	// IncludeContent returns processed page content instead of summaries.
+	IncludeContent bool
+	// Progress, if set, receives the number of pages crawled so far.
+	Progress ProgressFunc
+}
+
+// crawlScope decides which URLs a crawl may follow.
+type crawlScope struct {
+	host       string
+	pathPrefix string
+	include    []*regexp.Regexp
+	exclude    []*regexp.Regexp
+}
+
+// crawlTarget is a URL queued for fetching at a given depth.
+type crawlTarget struct {
+	url   string
+	depth int
+}
+
+// Crawl runs a breadth-first crawl from startURL within the limits of opts.
+// Pages of the same depth are fetched in parallel, up to the configured
+// number of workers, and processed in discovery order. When ctx is done, the
+// pages crawled so far are returned.
+func (f *httpFetcher) Crawl(ctx context.Context, startURL string, opts CrawlOptions) (*types.CrawlResponse, error) {
+	if opts.MaxDepth < 0 {
+		opts.MaxDepth = 0
+	}
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = DefaultCrawlPages
+	}
+	if opts.MaxPages > MaxCrawlPages {
+		opts.MaxPages = MaxCrawlPages
+	}
+	if opts.MaxLength <= 0 {
+		opts.MaxLength = f.defaultMaxLength
+	}
+
+	zap.S().Debugw("starting crawl",
+		"url", startURL,
+		"max_depth", opts.MaxDepth,
+		"max_pages", opts.MaxPages,
+		"scope", opts.Scope,
+		"path_prefix", opts.PathPrefix,
+		"include", opts.Include,
+		"exclude", opts.Exclude,
+		"max_length", opts.MaxLength,
+		"include_content", opts.IncludeContent)
+
+	start, err := url.Parse(startURL)
+	if err != nil || (start.Scheme != "http" && start.Scheme != "https") || start.Host == "" {
+		return nil, ierrors.Newf(ierrors.ErrInvalidArgument, "invalid start URL %q", startURL)
+	}
+	scope, err := newCrawlScope(start, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	startKey := normalizeCrawlURL(start)
+	discovered := map[string]bool{startKey: true}
+	frontier := []crawlTarget{{url: startKey, depth: 0}}
+	pages := []types.CrawlPage{}
+	remaining := opts.MaxLength
+	stopReason := ""
+
+	for len(frontier) > 0 && stopReason == "" {
+		if slots := opts.MaxPages - len(pages); len(frontier) > slots {
+			frontier = frontier[:slots]
+			stopReason = crawlStopMaxPages
+		}
+
+		urls := make([]string, len(frontier))
+		for i, target := range frontier {
+			urls[i] = target.url
+		}
+		results := f.fetchAll(ctx, urls)
+		if ctx.Err() != nil {
+			// The pages of the interrupted depth are incomplete
+			stopReason = crawlStopCancelled
+			break
+		}
+
+		next := []crawlTarget{}
+		for i, res := range results {
+			target := frontier[i]
+			page, links := f.crawlPage(target, res, scope)
+
+			// The budget covers whichever text is returned for the page
+			if opts.IncludeContent {
+				page.Summary = ""
+				page.Content = truncateText(page.Content, remaining)
+				remaining -= len(page.Content)
+			} else {
+				page.Content = ""
+				page.Summary = truncateText(page.Summary, remaining)
+				remaining -= len(page.Summary)
+			}
+			pages = append(pages, page)
+
+			for _, link := range links {
+				if discovered[link] {
+					continue
+				}
+				discovered[link] = true
+				if target.depth < opts.MaxDepth {
+					next = append(next, crawlTarget{url: link, depth: target.depth + 1})
+				}
+			}
+			if opts.Progress != nil {
+				opts.Progress(float64(len(pages)), float64(min(opts.MaxPages, len(discovered))), "crawled "+page.URL)
+			}
+
+			if remaining <= 0 {
+				stopReason = crawlStopBudget
+				break
+			}
+		}
+		frontier = next
+	}
+
+	unvisited := len(discovered) - len(pages)
+	if stopReason == "" {
+		stopReason = crawlStopComplete
+		if unvisited > 0 {
+			stopReason = crawlStopMaxDepth
+		}
+	}
+
+	zap.S().Infow("completed crawl",
+		"url", startURL,
+		"pages", len(pages),
+		"unvisited", unvisited,
+		"stop_reason", stopReason,
+		"budget_used", opts.MaxLength-remaining)
+
+	return &types.CrawlResponse{
+		StartURL:   startURL,
+		Pages:      pages,
+		StopReason: stopReason,
+		Unvisited:  unvisited,
+	}, nil
+}
+
+// crawlPage builds the page entry for a fetched target and returns the
+// normalized in-scope links found on it.
+func (f *httpFetcher) crawlPage(target crawlTarget, res *fetchResponse, scope *crawlScope) (types.CrawlPage, []string) {
+	page := types.CrawlPage{URL: target.url, Depth: target.depth}
+	if res.err != nil {
+		page.Error = res.err.Error()
+		return page, nil
+	}
+	page.OriginalURL = res.originalURL
+	page.StatusCode = res.status
+	page.ContentType = res.contentType
+
+	isHTML := strings.Contains(res.contentType, "text/html")
+	if !isHTML && !strings.HasPrefix(res.contentType, "text/") {
+		return page, nil
+	}
+
+	content := res.body
+	var links []string
+	if isHTML {
+		content = processHTMLContent(res.body, res.url)
+		if meta, err := extractMetadata(res.body, res.url); err == nil {
+			page.Title = meta.Title
+			page.Summary = meta.Description
+		}
+
+		found, err := extractLinks(res.body, res.url)
+		if err != nil {
+			zap.S().Warnw("failed to extract links while crawling", "url", res.url, "error", err)
+		}
+		seen := make(map[string]bool)
+		for _, link := range found {
+			u, err := url.Parse(link.URL)
+			if err != nil {
+				continue
+			}
+			key := normalizeCrawlURL(u)
+			if normalized, _ := url.Parse(key); !scope.allows(normalized) {
+				continue
+			}
+			if !seen[key] && key != target.url {
+				seen[key] = true
+				links = append(links, key)
+			}
+		}
+	}
+
+	page.ContentLength = len(content)
+	page.Content = content
+	page.Links = links
+
+	// Fall back to the first heading and paragraph of the processed content
+	for _, p := range splitParagraphs(content) {
+		if p.heading {
+			if page.Title == "" {
+				if m := markdownHeadingPattern.FindStringSubmatch(p.text); m != nil {
+					page.Title = headingText(m[2])
+				}
+			}
+			continue
+		}
+		if page.Summary == "" {
+			page.Summary = collapseWhitespace(p.text)
+		}
+		if page.Title != "" && page.Summary != "" {
+			break
+		}
+	}
+	page.Summary = truncateText(page.Summary, maxSummaryLength)
+	return page, links
+}
+
+// newCrawlScope compiles the scope and URL filters of opts for a crawl starting at start.
+func newCrawlScope(start *url.URL, opts CrawlOptions) (*crawlScope, error) {
+	scope := &crawlScope{host: strings.ToLower(start.Host)}
+
+	switch {
+	case opts.PathPrefix != "":
+		scope.pathPrefix = opts.PathPrefix
+		if !strings.HasPrefix(scope.pathPrefix, "/") {
+			scope.pathPrefix = "/" + scope.pathPrefix
+		}
+	case opts.Scope == CrawlScopePrefix:
+		// The directory of the start page: /docs/intro -> /docs/
+		scope.pathPrefix = start.Path[:strings.LastIndex(start.Path, "/")+1]
+	case opts.Scope == "" || opts.Scope == CrawlScopeHost:
+	default:
+		return nil, ierrors.Newf(ierrors.ErrInvalidArgument, "unsupported crawl scope %q (expected %s or %s)", opts.Scope, CrawlScopeHost, CrawlScopePrefix)
+	}
+
+	for _, pattern := range opts.Include {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, ierrors.WithKind(ierrors.Wrapf(err, "invalid include pattern %q", pattern), ierrors.ErrInvalidArgument)
+		}
+		scope.include = append(scope.include, re)
+	}
+	for _, pattern := range opts.Exclude {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, ierrors.WithKind(ierrors.Wrapf(err, "invalid exclude pattern %q", pattern), ierrors.ErrInvalidArgument)
+		}
+		scope.exclude = append(scope.exclude, re)
+	}
+	return scope, nil
+}
+
+// allows reports whether a crawl may follow a link to u.
+func (s *crawlScope) allows(u *url.URL) bool {
+	if (u.Scheme != "http" && u.Scheme != "https") || strings.ToLower(u.Host) != s.host {
+		return false
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	if s.pathPrefix != "" && !strings.HasPrefix(path, s.pathPrefix) {
+		return false
+	}
+
+	str := u.String()
+	if len(s.include) > 0 {
+		matched := false
+		for _, re := range s.include {
+			if re.MatchString(str) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, re := range s.exclude {
+		if re.MatchString(str) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeCrawlURL returns the form of u used to de-duplicate crawl targets:
+// no fragment, lower-case scheme and host, and "/" for an empty path.
+func normalizeCrawlURL(u *url.URL) string {
+	n := *u
+	n.Fragment = ""
+	n.RawFragment = ""
+	n.Scheme = strings.ToLower(n.Scheme)
+	n.Host = strings.ToLower(n.Host)
+	if n.Path == "" {
+		n.Path = "/"
+	}
+	return n.String()
+}
+
+// fetchAll fetches urls in parallel using at most maxWorkers concurrent
+// requests. Results are returned in the order of urls.
+func (f *httpFetcher) fetchAll(ctx context.Context, urls []string) []*fetchResponse {
+	workers := f.maxWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	results := make([]*fetchResponse, len(urls))
+	sem := make(chan struct{}, workers)
+	wg := &sync.WaitGroup{}
+	for i, u := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(index int, urlStr string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[index] = f.fetchWithContext(ctx, urlStr, requestOptions{})
+		}(i, u)
+	}
+	wg.Wait()
+	return results
+}
+
+// truncateText cuts s to at most maxLength bytes without splitting a UTF-8 character.
+func truncateText(s string, maxLength int) string {
+	if maxLength <= 0 {
+		return ""
+	}
+	if len(s) <= maxLength {
+		return s
+	}
+	end := maxLength
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end]
+}
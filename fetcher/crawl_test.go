@@ -0,0 +1,159 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cnosuke/mcp-fetch/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// crawlSite is a small docs site:
+//
+//	/docs/ -> /docs/a, /docs/b, /blog/post, external
+//	/docs/a -> /docs/a/deep, /docs/
+//	/docs/b -> /docs/b.txt
+func crawlSite() map[string]mockResponse {
+	page := func(title, description, body string) mockResponse {
+		html := "<html><head><title>" + title + "</title>"
+		if description != "" {
+			html += `<meta name="description" content="` + description + `">`
+		}
+		html += "</head><body>" + body + "</body></html>"
+		return mockResponse{Body: html, ContentType: "text/html", StatusCode: http.StatusOK}
+	}
+	return map[string]mockResponse{
+		"/docs/": page("Docs", "Documentation home",
+			`<a href="/docs/a">A</a> <a href="b">B</a> <a href="/docs/a#intro">A intro</a>
+<a href="/blog/post">Blog</a> <a href="https://other.example/">Elsewhere</a>`),
+		"/docs/a": page("Page A", "", "<article><p>Alpha page text.</p>"+
+			strings.Repeat("<p>This paragraph is long enough for readability to treat the page as an article body.</p>", 10)+
+			`</article><a href="/docs/a/deep">Deep</a> <a href="/docs/">Home</a>`),
+		"/docs/b":      page("Page B", "About B", `<a href="/docs/b.txt">Notes</a>`),
+		"/docs/a/deep": page("Deep", "Deep page", `<p>Bottom.</p>`),
+		"/docs/b.txt":  {Body: "Plain notes\n\nMore notes", ContentType: "text/plain", StatusCode: http.StatusOK},
+		"/blog/post":   page("Post", "A blog post", `<p>Blog.</p>`),
+	}
+}
+
+func crawlURLs(pages []types.CrawlPage) []string {
+	urls := []string{}
+	for _, p := range pages {
+		urls = append(urls, p.URL)
+	}
+	return urls
+}
+
+func TestHTTPFetcher_Crawl(t *testing.T) {
+	server := startMockServer(t, crawlSite())
+	fetcher := newTestFetcher(t, server.URL)
+
+	resp, err := fetcher.Crawl(context.Background(), server.URL+"/docs/", CrawlOptions{MaxDepth: 1, MaxLength: 10000})
+	require.NoError(t, err)
+
+	// Breadth-first, de-duplicated (fragments ignored) and limited to the host
+	assert.Equal(t, []string{
+		server.URL + "/docs/",
+		server.URL + "/docs/a",
+		server.URL + "/docs/b",
+		server.URL + "/blog/post",
+	}, crawlURLs(resp.Pages))
+	assert.Equal(t, "max_depth", resp.StopReason)
+	assert.Equal(t, 2, resp.Unvisited) // /docs/a/deep and /docs/b.txt
+
+	home := resp.Pages[0]
+	assert.Equal(t, 0, home.Depth)
+	assert.Equal(t, "Docs", home.Title)
+	assert.Equal(t, "Documentation home", home.Summary)
+	assert.Empty(t, home.Content)
+	assert.Equal(t, []string{server.URL + "/docs/a", server.URL + "/docs/b", server.URL + "/blog/post"}, home.Links)
+
+	// Without a description, the summary comes from the first paragraph
+	assert.Equal(t, 1, resp.Pages[1].Depth)
+	assert.Equal(t, "Alpha page text.", resp.Pages[1].Summary)
+}
+
+func TestHTTPFetcher_Crawl_Scope(t *testing.T) {
+	server := startMockServer(t, crawlSite())
+	fetcher := newTestFetcher(t, server.URL)
+
+	resp, err := fetcher.Crawl(context.Background(), server.URL+"/docs/", CrawlOptions{MaxDepth: 3, Scope: CrawlScopePrefix, MaxLength: 10000})
+	require.NoError(t, err)
+	assert.Equal(t, "complete", resp.StopReason)
+	assert.Equal(t, 0, resp.Unvisited)
+	assert.Equal(t, []string{
+		server.URL + "/docs/",
+		server.URL + "/docs/a",
+		server.URL + "/docs/b",
+		server.URL + "/docs/a/deep",
+		server.URL + "/docs/b.txt",
+	}, crawlURLs(resp.Pages))
+	assert.Equal(t, "Plain notes", resp.Pages[4].Summary)
+
+	resp, err = fetcher.Crawl(context.Background(), server.URL+"/docs/", CrawlOptions{MaxDepth: 3, Exclude: []string{`/a(/|$)`}, Include: []string{`/docs/`}, MaxLength: 10000})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		server.URL + "/docs/",
+		server.URL + "/docs/b",
+		server.URL + "/docs/b.txt",
+	}, crawlURLs(resp.Pages))
+
+	_, err = fetcher.Crawl(context.Background(), server.URL+"/docs/", CrawlOptions{Include: []string{"("}})
+	require.Error(t, err)
+	_, err = fetcher.Crawl(context.Background(), server.URL+"/docs/", CrawlOptions{Scope: "everything"})
+	require.Error(t, err)
+	_, err = fetcher.Crawl(context.Background(), "ftp://example.com/", CrawlOptions{})
+	require.Error(t, err)
+}
+
+func TestHTTPFetcher_Crawl_Limits(t *testing.T) {
+	server := startMockServer(t, crawlSite())
+	fetcher := newTestFetcher(t, server.URL)
+
+	resp, err := fetcher.Crawl(context.Background(), server.URL+"/docs/", CrawlOptions{MaxDepth: 3, MaxPages: 2, MaxLength: 10000})
+	require.NoError(t, err)
+	assert.Len(t, resp.Pages, 2)
+	assert.Equal(t, "max_pages", resp.StopReason)
+
+	// The budget is shared by all pages and ends the crawl once used up
+	resp, err = fetcher.Crawl(context.Background(), server.URL+"/docs/", CrawlOptions{MaxDepth: 3, MaxLength: 25})
+	require.NoError(t, err)
+	assert.Equal(t, "budget", resp.StopReason)
+	require.Len(t, resp.Pages, 2)
+	assert.Equal(t, "Documentation home", resp.Pages[0].Summary)
+	assert.Equal(t, "Alpha p", resp.Pages[1].Summary)
+
+	// Content mode returns trimmed content and reports the full length
+	resp, err = fetcher.Crawl(context.Background(), server.URL+"/docs/", CrawlOptions{MaxDepth: 0, MaxLength: 6, IncludeContent: true})
+	require.NoError(t, err)
+	require.Len(t, resp.Pages, 1)
+	assert.Equal(t, "# Docs", resp.Pages[0].Content)
+	assert.Empty(t, resp.Pages[0].Summary)
+	assert.Greater(t, resp.Pages[0].ContentLength, 6)
+}
+
+func TestHTTPFetcher_Crawl_Cancel(t *testing.T) {
+	server := startMockServer(t, crawlSite())
+	fetcher := newTestFetcher(t, server.URL)
+
+	// Progress reports the pages expected so far, bounded by max_pages
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var totals []float64
+	resp, err := fetcher.Crawl(ctx, server.URL+"/docs/", CrawlOptions{
+		MaxDepth:  3,
+		MaxPages:  2,
+		MaxLength: 10000,
+		Progress: func(progress float64, total float64, message string) {
+			totals = append(totals, total)
+			cancel()
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", resp.StopReason)
+	assert.Len(t, resp.Pages, 1)
+	assert.Equal(t, []float64{2}, totals)
+}
@@ -0,0 +1,158 @@
+package fetcher
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Page flags reported in types.FetchResponse and types.FetchResult.
+const (
+	// FlagHTTPError marks a 4xx or 5xx response returned as content or excerpt.
+	FlagHTTPError = "http_error"
+	// FlagSoft404 marks a successful response that looks like a "not found" page.
+	FlagSoft404 = "soft_404"
+	// FlagLoginWall marks a page that asks to sign in instead of showing content.
+	FlagLoginWall = "login_wall"
+	// FlagBotChallenge marks a CAPTCHA or browser check page of an anti-bot service.
+	FlagBotChallenge = "bot_challenge"
+)
+
+// smallPageLength is the visible text length under which a page is considered
+// to be only an error, login or challenge page rather than an article that
+// happens to mention one.
+const smallPageLength = 2000
+
+var (
+	// soft404Pattern matches titles and headings of "not found" pages.
+	soft404Pattern = regexp.MustCompile(`\b404\b|not found|(page|file|article|post) (does not|doesn['’]t|no longer) exists?|no longer (exists|available)|(could|can)( not|not|n['’]t|['’]t) be found|nothing (was )?found`)
+	// loginPattern matches titles, headings and URL paths of login pages.
+	loginPattern = regexp.MustCompile(`\b(sign[ _-]?in|log[ _-]?in|authenticate)\b`)
+)
+
+// botChallengeMarkers identify anti-bot interstitials wherever they appear.
+var botChallengeMarkers = []string{
+	"/cdn-cgi/challenge-platform/",
+	"cf-browser-verification",
+	"cf_chl_opt",
+	"attention required! | cloudflare",
+	"checking your browser before accessing",
+	"_incapsula_resource",
+	"captcha-delivery.com",
+	"px-captcha",
+}
+
+// captchaMarkers identify CAPTCHAs, which only make a challenge page when
+// there is little else on it.
+var captchaMarkers = []string{
+	"g-recaptcha",
+	"h-captcha",
+	"cf-turnstile",
+	"verify you are human",
+	"verify you are a human",
+	"are you a robot",
+	"just a moment...",
+}
+
+// pageSignals holds what detectPageFlags looks at in an HTML document.
+type pageSignals struct {
+	title       string // Lower-cased <title>
+	heading     string // Lower-cased first <h1>
+	hasPassword bool   // Whether the page has a password field
+	textLength  int    // Length of the visible text, whitespace collapsed
+}
+
+// detectPageFlags returns the flags of a fetched response: FlagHTTPError for
+// 4xx/5xx statuses, and for HTML the heuristics for soft 404s and login walls
+// (successful responses only) and bot challenges.
+func detectPageFlags(resp *fetchResponse) []string {
+	var flags []string
+	if isHTTPError(resp.status) {
+		flags = append(flags, FlagHTTPError)
+	}
+	if !strings.Contains(resp.contentType, "text/html") {
+		return flags
+	}
+
+	doc, err := html.Parse(strings.NewReader(resp.body))
+	if err != nil {
+		return flags
+	}
+	signals := collectPageSignals(doc)
+	small := signals.textLength < smallPageLength
+
+	if resp.status >= 200 && resp.status < 300 {
+		if small && (soft404Pattern.MatchString(signals.title) || soft404Pattern.MatchString(signals.heading)) {
+			flags = append(flags, FlagSoft404)
+		}
+		if signals.hasPassword && (small || loginPattern.MatchString(signals.title) ||
+			loginPattern.MatchString(signals.heading) || loginPattern.MatchString(urlPath(resp.url))) {
+			flags = append(flags, FlagLoginWall)
+		}
+	}
+
+	body := strings.ToLower(resp.body)
+	if containsAny(body, botChallengeMarkers) || (small && containsAny(body, captchaMarkers)) {
+		flags = append(flags, FlagBotChallenge)
+	}
+	return flags
+}
+
+// collectPageSignals walks an HTML document for detectPageFlags.
+func collectPageSignals(doc *html.Node) pageSignals {
+	signals := pageSignals{}
+	var text strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "noscript", "template":
+				return
+			case "title":
+				if signals.title == "" {
+					signals.title = strings.ToLower(collapseWhitespace(nodeText(n)))
+				}
+				return
+			case "h1":
+				if signals.heading == "" {
+					signals.heading = strings.ToLower(collapseWhitespace(nodeText(n)))
+				}
+			case "input":
+				if strings.EqualFold(getAttr(n, "type"), "password") {
+					signals.hasPassword = true
+				}
+			}
+		}
+		if n.Type == html.TextNode {
+			text.WriteString(n.Data)
+			text.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	signals.textLength = len(collapseWhitespace(text.String()))
+	return signals
+}
+
+// containsAny reports whether s contains any of the markers.
+func containsAny(s string, markers []string) bool {
+	for _, marker := range markers {
+		if strings.Contains(s, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// urlPath returns the lower-cased path of a URL, or "" if it does not parse.
+func urlPath(urlStr string) string {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Path)
+}
@@ -0,0 +1,111 @@
+package fetcher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectPageFlags(t *testing.T) {
+	article := "<p>" + strings.Repeat("A long paragraph about fetching pages. ", 100) + "</p>"
+
+	tests := []struct {
+		name        string
+		url         string
+		status      int
+		contentType string
+		body        string
+		expected    []string
+	}{
+		{
+			name:        "regular page",
+			status:      200,
+			contentType: "text/html",
+			body:        "<html><head><title>Guide</title></head><body><h1>Guide</h1>" + article + "</body></html>",
+		},
+		{
+			name:        "soft 404 title",
+			status:      200,
+			contentType: "text/html",
+			body:        "<html><head><title>Page Not Found | Example</title></head><body><p>Sorry.</p></body></html>",
+			expected:    []string{FlagSoft404},
+		},
+		{
+			name:        "soft 404 heading",
+			status:      200,
+			contentType: "text/html; charset=utf-8",
+			body:        "<html><body><h1>This page doesn't exist</h1><a href=\"/\">Home</a></body></html>",
+			expected:    []string{FlagSoft404},
+		},
+		{
+			name:        "long article mentioning 404 in its title",
+			status:      200,
+			contentType: "text/html",
+			body:        "<html><head><title>Handling 404 errors</title></head><body>" + article + "</body></html>",
+		},
+		{
+			name:        "login wall",
+			url:         "https://example.com/docs/private",
+			status:      200,
+			contentType: "text/html",
+			body:        `<html><head><title>Example</title></head><body><form><input name="user"><input type="password" name="pw"></form></body></html>`,
+			expected:    []string{FlagLoginWall},
+		},
+		{
+			name:        "long page with a login form at a login URL",
+			url:         "https://example.com/users/sign_in",
+			status:      200,
+			contentType: "text/html",
+			body:        `<html><body>` + article + `<form><input type="PASSWORD"></form></body></html>`,
+			expected:    []string{FlagLoginWall},
+		},
+		{
+			name:        "long page with a login form in the header",
+			url:         "https://example.com/blog/post",
+			status:      200,
+			contentType: "text/html",
+			body:        `<html><body><form><input type="password"></form>` + article + `</body></html>`,
+		},
+		{
+			name:        "cloudflare challenge",
+			status:      403,
+			contentType: "text/html",
+			body:        `<html><head><title>Just a moment...</title></head><body><script src="/cdn-cgi/challenge-platform/h/b/orchestrate/chl_page/v1"></script></body></html>`,
+			expected:    []string{FlagHTTPError, FlagBotChallenge},
+		},
+		{
+			name:        "captcha page",
+			status:      200,
+			contentType: "text/html",
+			body:        `<html><body><p>Please verify you are a human</p><div class="g-recaptcha"></div></body></html>`,
+			expected:    []string{FlagBotChallenge},
+		},
+		{
+			name:        "article with a recaptcha comment form",
+			status:      200,
+			contentType: "text/html",
+			body:        `<html><body>` + article + `<div class="g-recaptcha"></div></body></html>`,
+		},
+		{
+			name:        "not found status",
+			status:      404,
+			contentType: "text/html",
+			body:        "<html><head><title>Not Found</title></head><body><h1>Not Found</h1></body></html>",
+			expected:    []string{FlagHTTPError},
+		},
+		{
+			name:        "non-HTML error",
+			status:      500,
+			contentType: "application/json",
+			body:        `{"error":"internal"}`,
+			expected:    []string{FlagHTTPError},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := detectPageFlags(&fetchResponse{url: tt.url, status: tt.status, contentType: tt.contentType, body: tt.body})
+			assert.Equal(t, tt.expected, flags)
+		})
+	}
+}
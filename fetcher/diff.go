@@ -0,0 +1,354 @@
+package fetcher
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cnosuke/mcp-fetch/types"
+)
+
+// diffOp is the operation of a diffEdit.
+type diffOp int
+
+const (
+	diffEqual diffOp = iota
+	diffInsert
+	diffDelete
+)
+
+// diffEdit is one token of a diff: kept, inserted or deleted.
+type diffEdit struct {
+	op   diffOp
+	text string
+}
+
+// maxDiffEdits bounds the edit distance computed by diffTokens. Beyond it,
+// the documents are reported as entirely replaced.
+const maxDiffEdits = 2000
+
+// Limits of a types.DiffSummary.
+const (
+	diffSummaryChanges    = 10  // Changed lines listed
+	diffSummaryLineLength = 200 // Characters kept of each changed line
+)
+
+// diffTokens computes a shortest edit script turning a into b with Myers'
+// algorithm, after trimming their common prefix and suffix.
+func diffTokens(a, b []string) []diffEdit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]diffEdit, 0, len(a)+len(b)-prefix-suffix)
+	for _, token := range a[:prefix] {
+		edits = append(edits, diffEdit{op: diffEqual, text: token})
+	}
+	edits = append(edits, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, token := range a[len(a)-suffix:] {
+		edits = append(edits, diffEdit{op: diffEqual, text: token})
+	}
+	return edits
+}
+
+// myersDiff returns the edit script of a and b. It keeps the furthest
+// reaching paths of every step, trimmed to the diagonals in use, to backtrack.
+func myersDiff(a, b []string) []diffEdit {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replaceAll(a, b)
+	}
+
+	limit := n + m
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		if d > maxDiffEdits {
+			return replaceAll(a, b)
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+				return backtrackDiff(a, b, trace)
+			}
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+	return replaceAll(a, b)
+}
+
+// backtrackDiff rebuilds the edit script from the trace of myersDiff, where
+// trace[d][k+d] is the furthest x reached on diagonal k after d edits.
+func backtrackDiff(a, b []string, trace [][]int) []diffEdit {
+	var reversed []diffEdit
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && prev[k-1+d-1] < prev[k+1+d-1]) {
+			prevK = k + 1
+		}
+		prevX := prev[prevK+d-1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, diffEdit{op: diffEqual, text: a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			reversed = append(reversed, diffEdit{op: diffInsert, text: b[prevY]})
+		} else {
+			reversed = append(reversed, diffEdit{op: diffDelete, text: a[prevX]})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, diffEdit{op: diffEqual, text: a[x-1]})
+		x--
+		y--
+	}
+
+	edits := make([]diffEdit, len(reversed))
+	for i, edit := range reversed {
+		edits[len(reversed)-1-i] = edit
+	}
+	return edits
+}
+
+// replaceAll returns the edit script deleting all of a and inserting all of b.
+func replaceAll(a, b []string) []diffEdit {
+	edits := make([]diffEdit, 0, len(a)+len(b))
+	for _, token := range a {
+		edits = append(edits, diffEdit{op: diffDelete, text: token})
+	}
+	for _, token := range b {
+		edits = append(edits, diffEdit{op: diffInsert, text: token})
+	}
+	return edits
+}
+
+// normalizeLines splits processed content into the lines compared to detect
+// changes, ignoring cosmetic noise: runs of whitespace are collapsed and
+// blank lines dropped.
+func normalizeLines(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// summarizeDiff counts the lines added and removed by a line diff and lists
+// the first of them.
+func summarizeDiff(edits []diffEdit) *types.DiffSummary {
+	summary := &types.DiffSummary{}
+	for _, edit := range edits {
+		var prefix string
+		switch edit.op {
+		case diffInsert:
+			summary.AddedLines++
+			prefix = "+ "
+		case diffDelete:
+			summary.RemovedLines++
+			prefix = "- "
+		default:
+			continue
+		}
+		if len(summary.Changes) < diffSummaryChanges {
+			summary.Changes = append(summary.Changes, prefix+truncateText(edit.text, diffSummaryLineLength))
+		}
+	}
+	summary.Summary = fmt.Sprintf("%d lines added, %d removed", summary.AddedLines, summary.RemovedLines)
+	return summary
+}
+
+// diffHunks groups the changes of edits with up to context unchanged tokens
+// around them, merging groups that overlap. It returns the [start, end)
+// ranges of the groups in edits.
+func diffHunks(edits []diffEdit, context int) [][2]int {
+	var hunks [][2]int
+	for i, edit := range edits {
+		if edit.op == diffEqual {
+			continue
+		}
+		start := max(i-context, 0)
+		end := min(i+context+1, len(edits))
+		if n := len(hunks); n > 0 && start <= hunks[n-1][1] {
+			hunks[n-1][1] = end
+		} else {
+			hunks = append(hunks, [2]int{start, end})
+		}
+	}
+	return hunks
+}
+
+// hunkHeader returns the "@@ -l,s +l,s @@" header of edits[start:end], given
+// the number of lines of each side before start.
+func hunkHeader(edits []diffEdit, start int, end int, before int, after int) string {
+	var removed, added int
+	for _, edit := range edits[start:end] {
+		if edit.op != diffInsert {
+			removed++
+		}
+		if edit.op != diffDelete {
+			added++
+		}
+	}
+	// An empty side starts at the line before, as in GNU diff
+	if removed > 0 {
+		before++
+	}
+	if added > 0 {
+		after++
+	}
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", before, removed, after, added)
+}
+
+// renderUnified formats a line diff as the hunks of a unified diff, without
+// the file headers.
+func renderUnified(edits []diffEdit, context int) string {
+	var sb strings.Builder
+	before, after, next := 0, 0, 0
+	for _, hunk := range diffHunks(edits, context) {
+		for ; next < hunk[0]; next++ {
+			before, after = advanceLines(edits[next], before, after)
+		}
+		sb.WriteString(hunkHeader(edits, hunk[0], hunk[1], before, after))
+		sb.WriteString("\n")
+		for ; next < hunk[1]; next++ {
+			edit := edits[next]
+			switch edit.op {
+			case diffInsert:
+				sb.WriteString("+")
+			case diffDelete:
+				sb.WriteString("-")
+			default:
+				sb.WriteString(" ")
+			}
+			sb.WriteString(edit.text)
+			sb.WriteString("\n")
+			before, after = advanceLines(edit, before, after)
+		}
+	}
+	return sb.String()
+}
+
+// renderWords formats a line diff as hunks whose changed lines are compared
+// word by word, with removed words as [-...-] and added words as {+...+}. It
+// also returns the number of words added and removed.
+func renderWords(edits []diffEdit, context int) (string, int, int) {
+	var sb strings.Builder
+	var added, removed int
+	before, after, next := 0, 0, 0
+	for _, hunk := range diffHunks(edits, context) {
+		for ; next < hunk[0]; next++ {
+			before, after = advanceLines(edits[next], before, after)
+		}
+		sb.WriteString(hunkHeader(edits, hunk[0], hunk[1], before, after))
+		sb.WriteString("\n")
+		for next < hunk[1] {
+			if edits[next].op == diffEqual {
+				sb.WriteString(edits[next].text)
+				sb.WriteString("\n")
+				before, after = advanceLines(edits[next], before, after)
+				next++
+				continue
+			}
+			// Compare a block of changed lines as a whole
+			var oldWords, newWords []string
+			for ; next < hunk[1] && edits[next].op != diffEqual; next++ {
+				if edits[next].op == diffDelete {
+					oldWords = append(oldWords, strings.Fields(edits[next].text)...)
+				} else {
+					newWords = append(newWords, strings.Fields(edits[next].text)...)
+				}
+				before, after = advanceLines(edits[next], before, after)
+			}
+			line, a, r := renderWordChanges(diffTokens(oldWords, newWords))
+			sb.WriteString(line)
+			sb.WriteString("\n")
+			added += a
+			removed += r
+		}
+	}
+	return sb.String(), added, removed
+}
+
+// renderWordChanges formats a word diff on one line, wrapping runs of removed
+// and added words. It returns the line and the number of words added and
+// removed.
+func renderWordChanges(edits []diffEdit) (string, int, int) {
+	var parts []string
+	var added, removed []string
+	var addedCount, removedCount int
+	flush := func() {
+		if len(removed) > 0 {
+			parts = append(parts, "[-"+strings.Join(removed, " ")+"-]")
+			removed = nil
+		}
+		if len(added) > 0 {
+			parts = append(parts, "{+"+strings.Join(added, " ")+"+}")
+			added = nil
+		}
+	}
+	for _, edit := range edits {
+		switch edit.op {
+		case diffInsert:
+			added = append(added, edit.text)
+			addedCount++
+		case diffDelete:
+			removed = append(removed, edit.text)
+			removedCount++
+		default:
+			flush()
+			parts = append(parts, edit.text)
+		}
+	}
+	flush()
+	return strings.Join(parts, " "), addedCount, removedCount
+}
+
+// advanceLines counts edit in the lines of each side seen so far.
+func advanceLines(edit diffEdit, before int, after int) (int, int) {
+	if edit.op != diffInsert {
+		before++
+	}
+	if edit.op != diffDelete {
+		after++
+	}
+	return before, after
+}
+
+// splitLines splits processed content into the lines of a unified diff,
+// without trailing whitespace.
+func splitLines(content string) []string {
+	content = strings.TrimRight(content, " \t\r\n")
+	if content == "" {
+		return nil
+	}
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return lines
+}
@@ -0,0 +1,86 @@
+package fetcher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// applyDiff returns the two sides of an edit script.
+func applyDiff(edits []diffEdit) (before []string, after []string) {
+	for _, edit := range edits {
+		if edit.op != diffInsert {
+			before = append(before, edit.text)
+		}
+		if edit.op != diffDelete {
+			after = append(after, edit.text)
+		}
+	}
+	return before, after
+}
+
+func TestDiffTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		inserted int
+		deleted  int
+	}{
+		{name: "identical", a: "a b c", b: "a b c"},
+		{name: "insert", a: "a b c", b: "a b x c", inserted: 1},
+		{name: "delete", a: "a b c d", b: "a d", deleted: 2},
+		{name: "replace", a: "a b c", b: "a x c", inserted: 1, deleted: 1},
+		{name: "from empty", a: "", b: "a b", inserted: 2},
+		{name: "to empty", a: "a b", b: "", deleted: 2},
+		{name: "reordered", a: "a b c d e f", b: "b a c e d f", inserted: 2, deleted: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+			edits := diffTokens(a, b)
+
+			before, after := applyDiff(edits)
+			assert.Equal(t, strings.Join(a, " "), strings.Join(before, " "))
+			assert.Equal(t, strings.Join(b, " "), strings.Join(after, " "))
+
+			var inserted, deleted int
+			for _, edit := range edits {
+				switch edit.op {
+				case diffInsert:
+					inserted++
+				case diffDelete:
+					deleted++
+				}
+			}
+			assert.Equal(t, tt.inserted, inserted)
+			assert.Equal(t, tt.deleted, deleted)
+		})
+	}
+}
+
+func TestNormalizeLines(t *testing.T) {
+	assert.Equal(t, []string{"# Title", "Some text here."},
+		normalizeLines("# Title\n\n\n  Some   text\there.  \n\n"))
+}
+
+func TestSummarizeDiff(t *testing.T) {
+	before := normalizeLines("# Pricing\n\nBasic: $10\n\nPro: $20\n")
+	after := normalizeLines("# Pricing\n\nBasic:  $10\n\nPro: $25\n\nTeam: $50\n")
+
+	summary := summarizeDiff(diffTokens(before, after))
+	assert.Equal(t, 2, summary.AddedLines)
+	assert.Equal(t, 1, summary.RemovedLines)
+	assert.Equal(t, "2 lines added, 1 removed", summary.Summary)
+	assert.Equal(t, []string{"- Pro: $20", "+ Pro: $25", "+ Team: $50"}, summary.Changes)
+}
+
+func TestRenderUnified(t *testing.T) {
+	before := splitLines("a\nb\nc\nd\ne\nf\ng\nh\n")
+	after := splitLines("a\nB\nc\nd\ne\nf\ng\nh\ni  \n")
+
+	assert.Equal(t, "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n@@ -8,1 +8,2 @@\n h\n+i\n",
+		renderUnified(diffTokens(before, after), 1))
+	assert.Equal(t, "@@ -0,0 +1,1 @@\n+a\n", renderUnified(diffTokens(nil, []string{"a"}), 3))
+	assert.Empty(t, renderUnified(diffTokens(before, before), 3))
+}
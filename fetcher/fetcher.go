@@ -1,14 +1,18 @@
 package fetcher
 
 import (
+	"compress/gzip"
+	"context"
 	"errors"
 	"io"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
 	"strings"
-	"sync"
 	"time"
 
 	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/tokenizer"
 	"github.com/cnosuke/mcp-fetch/types"
 	"github.com/mackee/go-readability"
 	"go.uber.org/zap"
@@ -20,6 +24,77 @@ type Config struct {
 	MaxURLs          int
 	MaxWorkers       int
 	DefaultMaxLength int
+	Tokenizer        string // Tokenizer used for token-based limits and counts (see tokenizer.Names)
+	Robots           string // robots.txt policy: RobotsEnforce (default), RobotsWarn or RobotsOff
+	RobotsUserAgent  string // Product token matched in robots.txt; derived from UserAgent if empty
+	HTTPErrors       string // Default handling of 4xx/5xx responses: HTTPErrorsContent (default), HTTPErrorsExcerpt or HTTPErrorsError
+	MaxBodySize      int64  // Largest response body read, in bytes; DefaultMaxBodySize if 0
+}
+
+// DefaultMaxBodySize is the largest response body read by default, in bytes.
+const DefaultMaxBodySize = 10 * 1024 * 1024
+
+// FetchOptions holds the per-call settings for FetchWithOptions.
+type FetchOptions struct {
+	MaxLength  int
+	StartIndex int
+	Raw        bool
+	// MaxTokens and StartToken limit the content in tokens instead of characters.
+	// They take effect when either is set; MaxLength still caps the result if set.
+	MaxTokens  int
+	StartToken int
+	// Format selects the output format for HTML content (markdown by default).
+	Format Format
+	// Selector scopes HTML processing to the elements matching this CSS selector.
+	Selector string
+	// ExcludeSelectors removes matching elements before any other processing.
+	ExcludeSelectors []string
+	// IncludeMetadata adds page metadata (OpenGraph, JSON-LD, ...) to HTML responses.
+	IncludeMetadata bool
+	// Section scopes Markdown content to one section by heading anchor or text.
+	// If empty, the URL fragment is used when it matches a heading.
+	Section string
+	// Progress, if set, receives the number of bytes downloaded so far.
+	Progress ProgressFunc
+	// HTTPErrors overrides the fetcher's handling of 4xx/5xx responses when set.
+	HTTPErrors string
+	// IncludeResponseInfo adds headers, timings and transfer stats to the response.
+	IncludeResponseInfo bool
+	// IfNoneMatch and IfModifiedSince make the request conditional, with the
+	// ETag and LastModified of an earlier response. A 304 response is returned
+	// with NotModified set and no content.
+	IfNoneMatch     string
+	IfModifiedSince string
+}
+
+// MultipleFetchOptions holds the per-call settings for FetchMultipleWithOptions.
+type MultipleFetchOptions struct {
+	MaxLength int // Total character budget shared by all URLs
+	MaxTokens int // Total token budget shared by all URLs; replaces MaxLength when set
+	Raw       bool
+	Format    Format
+	// Items optionally overrides settings per URL: Items[i] applies to urls[i].
+	Items []MultipleFetchItem
+	// Progress, if set, receives the number of URLs fetched so far.
+	Progress ProgressFunc
+	// Deadline, if set, bounds the time spent fetching. URLs still pending
+	// when it passes are reported as timed out and left out of the allocation.
+	Deadline time.Duration
+	// HTTPErrors overrides the fetcher's handling of 4xx/5xx responses when set.
+	HTTPErrors string
+	// IncludeResponseInfo adds headers, timings and transfer stats to each result.
+	IncludeResponseInfo bool
+}
+
+// MultipleFetchItem holds the settings of one URL in FetchMultipleWithOptions.
+// Lengths and offsets are in the unit of the budget (characters or tokens).
+type MultipleFetchItem struct {
+	Weight     float64 // Share of the budget relative to the other URLs (default 1)
+	MaxLength  int     // Cap on the content returned for this URL
+	StartIndex int     // Offset into the processed content
+	Raw        *bool   // Overrides MultipleFetchOptions.Raw when set
+	Format     Format  // Overrides MultipleFetchOptions.Format when set
+	Selector   string  // CSS selector scoping HTML processing
 }
 
 // Fetcher defines the interface for fetching and processing URL content.
@@ -29,17 +104,66 @@ type Fetcher interface {
 	// Markdown conversion, and content trimming based on parameters.
 	Fetch(urlStr string, maxLength int, startIndex int, raw bool) (*types.FetchResponse, error)
 
+	// FetchWithOptions fetches and processes content from a single URL
+	// using the extended per-call options in opts.
+	FetchWithOptions(urlStr string, opts FetchOptions) (*types.FetchResponse, error)
+
 	// FetchMultiple fetches and processes content from multiple URLs.
 	// It handles parallel fetching and content reallocation logic.
 	FetchMultiple(urls []string, maxLength int, raw bool) (*types.MultipleFetchResponse, error)
+
+	// FetchMultipleWithOptions fetches and processes content from multiple URLs
+	// using the extended per-call options in opts. Pending fetches are
+	// abandoned when ctx is done.
+	FetchMultipleWithOptions(ctx context.Context, urls []string, opts MultipleFetchOptions) (*types.MultipleFetchResponse, error)
+
+	// FetchLinks fetches a page and extracts its anchors as structured links,
+	// filtered according to opts.
+	FetchLinks(urlStr string, opts LinkOptions) (*types.LinksResponse, error)
+
+	// FetchOutline fetches a page and returns its heading tree, with character
+	// offsets into the Markdown content returned by Fetch.
+	FetchOutline(urlStr string) (*types.OutlineResponse, error)
+
+	// FetchSearch fetches a page and returns the paragraphs of its processed
+	// content that match opts.Query, ranked by relevance.
+	FetchSearch(urlStr string, opts SearchOptions) (*types.SearchResponse, error)
+
+	// Crawl runs a bounded breadth-first crawl starting at startURL and
+	// returns the visited pages with their in-scope links. It stops when ctx
+	// is done.
+	Crawl(ctx context.Context, startURL string, opts CrawlOptions) (*types.CrawlResponse, error)
+
+	// FetchSitemap lists the URLs published in the sitemaps of a site (or in
+	// the sitemap at urlStr), expanding sitemap indexes and filtered by opts.
+	FetchSitemap(urlStr string, opts SitemapOptions) (*types.SitemapResponse, error)
+
+	// FetchSummary fetches a page and summarizes it by section with
+	// opts.Sampler, citing the sections the summary draws from.
+	FetchSummary(ctx context.Context, urlStr string, opts SummaryOptions) (*types.SummaryResponse, error)
+
+	// WithGuard returns a Fetcher that behaves like this one, except that
+	// every request it sends, including the redirects it follows, is first
+	// checked with guard.
+	WithGuard(guard URLGuard) Fetcher
 }
 
+// URLGuard checks a URL before it is requested: the URL of each fetch, with
+// redirect false, and then the target of each redirect followed, with
+// redirect true. An error refuses the request before it is sent.
+type URLGuard func(u *url.URL, redirect bool) error
+
 // httpFetcher implements the Fetcher interface using HTTP.
 type httpFetcher struct {
 	client           *http.Client
 	userAgent        string
 	maxWorkers       int
 	defaultMaxLength int
+	tokenizer        tokenizer.Tokenizer
+	robots           *robotsPolicy
+	httpErrors       string   // Default HTTP error policy
+	maxBodySize      int64    // Largest response body read, in bytes
+	guard            URLGuard // Checks every request if set
 }
 
 // NewHTTPFetcher creates a new httpFetcher.
@@ -48,7 +172,34 @@ func NewHTTPFetcher(cfg *Config) (Fetcher, error) {
 		"timeout", cfg.Timeout,
 		"user_agent", cfg.UserAgent,
 		"max_workers", cfg.MaxWorkers,
-		"default_max_length", cfg.DefaultMaxLength)
+		"default_max_length", cfg.DefaultMaxLength,
+		"tokenizer", cfg.Tokenizer,
+		"robots", cfg.Robots,
+		"robots_user_agent", cfg.RobotsUserAgent,
+		"http_errors", cfg.HTTPErrors,
+		"max_body_size", cfg.MaxBodySize)
+
+	tok, err := tokenizer.New(cfg.Tokenizer)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to create tokenizer")
+	}
+
+	robotsMode, err := ParseRobotsMode(cfg.Robots)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "invalid robots configuration")
+	}
+	httpErrors, err := ParseHTTPErrorPolicy(cfg.HTTPErrors)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "invalid http_errors configuration")
+	}
+	maxBodySize := cfg.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+	robotsAgent := cfg.RobotsUserAgent
+	if robotsAgent == "" {
+		robotsAgent = robotsAgentToken(cfg.UserAgent)
+	}
 
 	client := &http.Client{
 		Timeout: time.Duration(cfg.Timeout) * time.Second,
@@ -59,30 +210,116 @@ func NewHTTPFetcher(cfg *Config) (Fetcher, error) {
 		userAgent:        cfg.UserAgent,
 		maxWorkers:       cfg.MaxWorkers,
 		defaultMaxLength: cfg.DefaultMaxLength,
+		tokenizer:        tok,
+		robots:           newRobotsPolicy(robotsMode, robotsAgent, cfg.UserAgent, client),
+		httpErrors:       httpErrors,
+		maxBodySize:      maxBodySize,
 	}, nil
 }
 
+// WithGuard implements Fetcher. The guard is checked after any guard f
+// already has.
+func (f *httpFetcher) WithGuard(guard URLGuard) Fetcher {
+	guarded := *f
+	guarded.guard = chainGuards(f.guard, guard)
+	return &guarded
+}
+
+// chainGuards returns a guard checking with first, if not nil, then with
+// second.
+func chainGuards(first URLGuard, second URLGuard) URLGuard {
+	if first == nil {
+		return second
+	}
+	return func(u *url.URL, redirect bool) error {
+		if err := first(u, redirect); err != nil {
+			return err
+		}
+		return second(u, redirect)
+	}
+}
+
+// Stages reported in types.FetchError.
+const (
+	fetchStageRequest    = "request"
+	fetchStageProcessing = "processing"
+)
+
+// newFetchError reports err, classified by ierrors.Classify, as failing at stage.
+func newFetchError(stage string, err error) *types.FetchError {
+	kindErr := ierrors.Classify(err)
+	return &types.FetchError{
+		Code:      kindErr.Kind.Code(),
+		Message:   err.Error(),
+		Retryable: kindErr.Retryable,
+		Stage:     stage,
+	}
+}
+
 type fetchResponse struct {
-	url         string
-	status      int
-	body        string
-	contentType string
-	originalURL string // Set only if redirect occurred
-	err         error
+	url          string // Final URL after redirects, or the requested URL on error
+	status       int
+	body         string
+	contentType  string
+	originalURL  string              // Set only if redirect occurred
+	info         *types.ResponseInfo // Set only if requested with requestOptions.responseInfo
+	etag         string              // ETag header, for conditional requests
+	lastModified string              // Last-Modified header, for conditional requests
+	err          error
+}
+
+// requestOptions holds the optional settings of fetchWithContext.
+type requestOptions struct {
+	progress     ProgressFunc // Receives download progress if set
+	responseInfo bool         // Whether to trace the request and set fetchResponse.info
+	// ifNoneMatch and ifModifiedSince make the request conditional when set
+	ifNoneMatch     string
+	ifModifiedSince string
 }
 
 func (f *httpFetcher) fetch(urlStr string) *fetchResponse {
-	req, err := http.NewRequest("GET", urlStr, nil)
+	return f.fetchWithContext(context.Background(), urlStr, requestOptions{})
+}
+
+// fetchWithContext fetches urlStr, aborting the request when ctx is done.
+func (f *httpFetcher) fetchWithContext(ctx context.Context, urlStr string, ropts requestOptions) *fetchResponse {
+	var trace *responseTrace
+	if ropts.responseInfo {
+		trace = newResponseTrace()
+		ctx = httptrace.WithClientTrace(ctx, trace.clientTrace())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
 	if err != nil {
-		return &fetchResponse{err: ierrors.Wrap(err, "failed to create request")}
+		return &fetchResponse{url: urlStr, err: ierrors.Wrap(err, "failed to create request")}
 	}
 	req.Header.Set("User-Agent", f.userAgent)
+	if trace != nil {
+		// Requesting gzip explicitly keeps the transport from decompressing
+		// transparently, so the compressed size can be counted
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	if ropts.ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ropts.ifNoneMatch)
+	}
+	if ropts.ifModifiedSince != "" {
+		req.Header.Set("If-Modified-Since", ropts.ifModifiedSince)
+	}
 
-	// Track redirect chain for this request
+	if f.guard != nil {
+		if err := f.guard(req.URL, false); err != nil {
+			return &fetchResponse{url: urlStr, err: err}
+		}
+	}
+	if err := f.robots.check(ctx, req.URL); err != nil {
+		return &fetchResponse{url: urlStr, err: err}
+	}
+
+	// Track redirect chain for this request. The client is copied so that
+	// concurrent requests do not share the CheckRedirect hook.
 	var redirectChain []string
-	// Save original CheckRedirect to restore later
-	origCheckRedirect := f.client.CheckRedirect
-	f.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+	client := *f.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 		// Record the redirect chain
 		if len(via) == 1 {
 			redirectChain = append(redirectChain, via[0].URL.String())
@@ -92,21 +329,44 @@ func (f *httpFetcher) fetch(urlStr string) *fetchResponse {
 		if len(via) >= 10 {
 			return errors.New("stopped after 10 redirects")
 		}
-		return nil
+		// Every redirect target is subject to the guard and robots.txt as
+		// well, before it is requested
+		if f.guard != nil {
+			if err := f.guard(req.URL, true); err != nil {
+				return err
+			}
+		}
+		return f.robots.check(req.Context(), req.URL)
 	}
 
-	resp, err := f.client.Do(req)
-	// Restore original CheckRedirect
-	f.client.CheckRedirect = origCheckRedirect
-
+	resp, err := client.Do(req)
 	if err != nil {
-		return &fetchResponse{err: ierrors.Wrap(err, "failed to execute request")}
+		return &fetchResponse{url: urlStr, err: ierrors.Wrap(err, "failed to execute request")}
 	}
 
 	defer resp.Body.Close()
-	bodyBytes, err := io.ReadAll(resp.Body)
+	if resp.ContentLength > f.maxBodySize {
+		return &fetchResponse{url: urlStr, err: ierrors.Newf(ierrors.ErrTooLarge, "response body of %d bytes exceeds %d bytes", resp.ContentLength, f.maxBodySize)}
+	}
+	counter := &countingReader{reader: resp.Body}
+	var body io.Reader = counter
+	if ropts.progress != nil {
+		body = newProgressReader(body, resp.ContentLength, ropts.progress)
+	}
+	if trace != nil && strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return &fetchResponse{url: urlStr, err: ierrors.Wrap(err, "failed to decompress response body")}
+		}
+		defer zr.Close()
+		body = zr
+	}
+	bodyBytes, err := io.ReadAll(io.LimitReader(body, f.maxBodySize+1))
 	if err != nil {
-		return &fetchResponse{err: ierrors.Wrap(err, "failed to read response body")}
+		return &fetchResponse{url: urlStr, err: ierrors.Wrap(err, "failed to read response body")}
+	}
+	if int64(len(bodyBytes)) > f.maxBodySize {
+		return &fetchResponse{url: urlStr, err: ierrors.Newf(ierrors.ErrTooLarge, "response body exceeds %d bytes", f.maxBodySize)}
 	}
 
 	zap.S().Debugw(
@@ -124,60 +384,199 @@ func (f *httpFetcher) fetch(urlStr string) *fetchResponse {
 	}
 
 	return &fetchResponse{
-		url:         resp.Request.URL.String(),
-		status:      resp.StatusCode,
-		body:        string(bodyBytes),
-		contentType: resp.Header.Get("Content-Type"),
-		originalURL: originalURL,
-		err:         nil,
+		url:          resp.Request.URL.String(),
+		status:       resp.StatusCode,
+		body:         string(bodyBytes),
+		contentType:  resp.Header.Get("Content-Type"),
+		originalURL:  originalURL,
+		info:         responseInfo(trace, resp, counter.count, bodyBytes),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		err:          nil,
+	}
+}
+
+// responseInfo returns the types.ResponseInfo of a traced response, or nil if
+// the request was not traced.
+func responseInfo(trace *responseTrace, resp *http.Response, compressed int64, body []byte) *types.ResponseInfo {
+	if trace == nil {
+		return nil
+	}
+	return trace.responseInfo(resp, compressed, body)
+}
+
+// finalURL returns the URL resp was fetched from if a redirect occurred, or
+// "".
+func finalURL(resp *fetchResponse) string {
+	if resp.originalURL == "" {
+		return ""
 	}
+	return resp.url
 }
 
 // Fetch fetches and processes content from a single URL.
 func (f *httpFetcher) Fetch(urlStr string, maxLength int, startIndex int, raw bool) (*types.FetchResponse, error) {
+	return f.FetchWithOptions(urlStr, FetchOptions{
+		MaxLength:  maxLength,
+		StartIndex: startIndex,
+		Raw:        raw,
+	})
+}
+
+// FetchWithOptions fetches and processes content from a single URL using opts.
+func (f *httpFetcher) FetchWithOptions(urlStr string, opts FetchOptions) (*types.FetchResponse, error) {
 	zap.S().Debugw("fetching URL",
 		"url", urlStr,
-		"max_length", maxLength,
-		"start_index", startIndex,
-		"raw", raw)
+		"max_length", opts.MaxLength,
+		"start_index", opts.StartIndex,
+		"max_tokens", opts.MaxTokens,
+		"start_token", opts.StartToken,
+		"raw", opts.Raw,
+		"format", opts.Format,
+		"selector", opts.Selector,
+		"exclude_selectors", opts.ExcludeSelectors,
+		"include_metadata", opts.IncludeMetadata,
+		"section", opts.Section,
+		"http_errors", opts.HTTPErrors,
+		"include_response_info", opts.IncludeResponseInfo,
+		"if_none_match", opts.IfNoneMatch,
+		"if_modified_since", opts.IfModifiedSince)
+
+	httpErrors, err := f.httpErrorPolicy(opts.HTTPErrors)
+	if err != nil {
+		return nil, err
+	}
 
 	// Fetch the URL using the internal fetch method
-	resp := f.fetch(urlStr)
+	resp := f.fetchWithContext(context.Background(), urlStr, requestOptions{
+		progress:        opts.Progress,
+		responseInfo:    opts.IncludeResponseInfo,
+		ifNoneMatch:     opts.IfNoneMatch,
+		ifModifiedSince: opts.IfModifiedSince,
+	})
 	if resp.err != nil {
 		// Error is already wrapped in f.fetch
 		return nil, resp.err
 	}
-	var processedContent string
+	if resp.status == http.StatusNotModified {
+		return &types.FetchResponse{
+			URL:          urlStr,
+			ContentType:  resp.contentType,
+			StatusCode:   resp.status,
+			NotModified:  true,
+			ETag:         resp.etag,
+			LastModified: resp.lastModified,
+			ResponseInfo: resp.info,
+		}, nil
+	}
+	if isHTTPError(resp.status) && httpErrors == HTTPErrorsError {
+		return nil, ierrors.HTTPStatus(resp.status)
+	}
 
-	if raw {
-		processedContent = resp.body
-		zap.S().Debugw("raw mode enabled", "url", urlStr)
-	} else if strings.Contains(resp.contentType, "text/html") {
-		processedContent = processHTMLContent(resp.body, urlStr)
-	} else {
-		processedContent = resp.body // Non-HTML content
-		zap.S().Debugw("non-HTML content", "url", urlStr, "content_type", resp.contentType)
+	processingStart := time.Now()
+	processedContent, selectorMatches, err := processContent(resp, urlStr, opts)
+	if err != nil {
+		return nil, err
+	}
+	processedContent, section, err := scopeToSection(processedContent, resp, urlStr, opts)
+	if err != nil {
+		return nil, err
+	}
+	if resp.info != nil {
+		resp.info.Timings.ProcessingMs = durationMs(time.Since(processingStart))
+	}
+	if isHTTPError(resp.status) && httpErrors == HTTPErrorsExcerpt {
+		processedContent = truncateText(processedContent, HTTPErrorExcerptLength)
 	}
 
 	// Apply trimming
-	trimmedContent := trimContent(processedContent, startIndex, maxLength)
+	var trimmedContent string
+	if opts.MaxTokens > 0 || opts.StartToken > 0 {
+		trimmedContent = tokenizer.Trim(f.tokenizer, processedContent, opts.StartToken, opts.MaxTokens)
+		trimmedContent = trimContent(trimmedContent, 0, opts.MaxLength)
+	} else {
+		trimmedContent = trimContent(processedContent, opts.StartIndex, opts.MaxLength)
+	}
 	if len(processedContent) != len(trimmedContent) {
 		zap.S().Debugw("content trimmed",
 			"original_length", len(processedContent),
-			"start_index", startIndex,
+			"start_index", opts.StartIndex,
+			"start_token", opts.StartToken,
 			"trimmed_length", len(trimmedContent))
 	}
 
+	var metadata *types.PageMetadata
+	if opts.IncludeMetadata && strings.Contains(resp.contentType, "text/html") {
+		metadata, err = extractMetadata(resp.body, resp.url)
+		if err != nil {
+			zap.S().Warnw("metadata extraction failed", "url", urlStr, "error", err)
+		}
+	}
+
 	return &types.FetchResponse{
 		URL:         urlStr,
 		ContentType: resp.contentType,
 		Content:     trimmedContent,
 		StatusCode:  resp.status,
+		Format:      contentFormat(resp, opts.Raw, opts.Format),
 		// Set only if redirect occurred
-		OriginalURL: resp.originalURL,
+		OriginalURL:     resp.originalURL,
+		FinalURL:        finalURL(resp),
+		SelectorMatches: selectorMatches,
+		Metadata:        metadata,
+		Section:         section,
+		Tokens:          f.tokenizer.Count(trimmedContent),
+		TotalTokens:     f.tokenizer.Count(processedContent),
+		Flags:           detectPageFlags(resp),
+		ResponseInfo:    resp.info,
+		ETag:            resp.etag,
+		LastModified:    resp.lastModified,
 	}, nil
 }
 
+// httpErrorPolicy returns the HTTP error policy named by a call, or the
+// fetcher's default if name is empty.
+func (f *httpFetcher) httpErrorPolicy(name string) (string, error) {
+	if name == "" {
+		return f.httpErrors, nil
+	}
+	return ParseHTTPErrorPolicy(name)
+}
+
+// processContent converts a fetched body into the content returned to the caller,
+// before any trimming. It also returns the number of selector matches, if a
+// selector was used.
+func processContent(resp *fetchResponse, urlStr string, opts FetchOptions) (string, int, error) {
+	isHTML := strings.Contains(resp.contentType, "text/html")
+
+	if isHTML && (opts.Selector != "" || len(opts.ExcludeSelectors) > 0) {
+		return selectHTMLContent(resp.body, urlStr, opts.Selector, opts.ExcludeSelectors, opts.Raw, opts.Format)
+	}
+
+	if opts.Raw {
+		zap.S().Debugw("raw mode enabled", "url", urlStr)
+		return resp.body, 0, nil
+	}
+	if isHTML {
+		return formatHTMLContent(resp.body, urlStr, opts.Format), 0, nil
+	}
+
+	zap.S().Debugw("non-HTML content", "url", urlStr, "content_type", resp.contentType)
+	return resp.body, 0, nil
+}
+
+// contentFormat returns the format reported for a response. It is empty when
+// the body was returned unconverted.
+func contentFormat(resp *fetchResponse, raw bool, format Format) string {
+	if raw || !strings.Contains(resp.contentType, "text/html") {
+		return ""
+	}
+	if format == "" {
+		return string(FormatMarkdown)
+	}
+	return string(format)
+}
+
 // trimContent helper function to trim content based on startIndex and maxLength
 func trimContent(content string, startIndex int, maxLength int) string {
 	contentLength := len(content)
@@ -231,196 +630,283 @@ func processHTMLContent(body string, urlStr string) string {
 
 // FetchMultiple fetches content from multiple URLs in parallel and allocates content length.
 func (f *httpFetcher) FetchMultiple(urls []string, maxLength int, raw bool) (*types.MultipleFetchResponse, error) {
+	return f.FetchMultipleWithOptions(context.Background(), urls, MultipleFetchOptions{
+		MaxLength: maxLength,
+		Raw:       raw,
+	})
+}
+
+// FetchMultipleWithOptions fetches content from multiple URLs in parallel using opts.
+func (f *httpFetcher) FetchMultipleWithOptions(ctx context.Context, urls []string, opts MultipleFetchOptions) (*types.MultipleFetchResponse, error) {
+	maxLength := opts.MaxLength
+
 	zap.S().Debugw("fetching multiple URLs",
 		"count", len(urls),
 		"max_length", maxLength,
-		"raw", raw,
+		"max_tokens", opts.MaxTokens,
+		"raw", opts.Raw,
+		"format", opts.Format,
+		"items", len(opts.Items),
+		"deadline", opts.Deadline,
+		"http_errors", opts.HTTPErrors,
 		"workers", f.maxWorkers)
 
+	if len(opts.Items) > 0 && len(opts.Items) != len(urls) {
+		return nil, ierrors.Newf(ierrors.ErrInvalidArgument, "got %d items for %d URLs", len(opts.Items), len(urls))
+	}
+	httpErrors, err := f.httpErrorPolicy(opts.HTTPErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	// Distribute a token budget instead of characters when max_tokens is given
+	unit := charUnit()
+	if opts.MaxTokens > 0 {
+		unit = tokenUnit(f.tokenizer)
+		maxLength = opts.MaxTokens
+	}
+
 	// Default value if maxLength is not specified
 	if maxLength <= 0 {
 		maxLength = f.defaultMaxLength
 	}
 
-	// Slice to store initial fetch results
+	// Resolve the settings of each URL
+	items := make([]MultipleFetchItem, len(urls))
+	for i := range urls {
+		if len(opts.Items) > 0 {
+			items[i] = opts.Items[i]
+		}
+		if items[i].Weight <= 0 {
+			items[i].Weight = 1
+		}
+		if items[i].Raw == nil {
+			items[i].Raw = &opts.Raw
+		}
+		if items[i].Format == "" {
+			items[i].Format = opts.Format
+		}
+		if items[i].StartIndex < 0 {
+			items[i].StartIndex = 0
+		}
+	}
+
+	var cancel context.CancelFunc
+	if opts.Deadline > 0 {
+		ctx, cancel = context.WithTimeout(ctx, opts.Deadline)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	// Slice to store initial fetch results; nil entries did not finish in time
 	results := make([]*fetchResponse, len(urls))
 
-	wg := &sync.WaitGroup{}
+	// Completed fetches are sent to a buffered channel, so fetches still
+	// running at the deadline can finish without anyone waiting for them
+	type completion struct {
+		index int
+		resp  *fetchResponse
+	}
+	completions := make(chan completion, len(urls))
 
 	// Fetch URLs in parallel
 	for i, url := range urls {
-		wg.Add(1)
 		go func(index int, urlStr string) {
-			defer wg.Done()
-
 			zap.S().Debugw("initiating fetch for URL", "url", urlStr)
 
-			resp := f.fetch(urlStr)
-			results[index] = resp
+			resp := f.fetchWithContext(ctx, urlStr, requestOptions{responseInfo: opts.IncludeResponseInfo})
+			completions <- completion{index: index, resp: resp}
 		}(i, url)
 	}
 
-	// Wait for all initial fetches to complete
-	wg.Wait()
+	// Wait for all initial fetches to complete or the deadline to pass
+wait:
+	for received := 0; received < len(urls); received++ {
+		select {
+		case c := <-completions:
+			results[c.index] = c.resp
+			// Reported here rather than by the fetches, so that no progress
+			// is reported after the call returns
+			if opts.Progress != nil {
+				opts.Progress(float64(received+1), float64(len(urls)), "fetched "+urls[c.index])
+			}
+		case <-ctx.Done():
+			zap.S().Warnw("deadline exceeded while fetching multiple URLs",
+				"deadline", opts.Deadline,
+				"pending", len(urls)-received)
+			break wait
+		}
+	}
 
 	// --- Content Processing and Allocation ---
 
 	// Structure to hold processed content before final trimming
 	type processedResult struct {
-		URL                 string // The original URL
-		FullContent         string // Content after readability/markdown, before any trimming
-		ContentType         string
-		StatusCode          int
-		OriginalIndex       int    // To maintain order if needed
-		FinalTrimmedContent string // Content after allocation and trimming
-		WasTruncated        bool   // Flag if initial allocation truncated content
+		URL         string             // The requested URL
+		Item        MultipleFetchItem  // Settings of the URL
+		Result      *types.FetchResult // Entry of the response for this URL
+		FullContent string             // Content after readability/markdown, before any trimming
+		FullLength  int                // Length of FullContent in the budget unit
 	}
 
 	processedResults := []*processedResult{}
-	finalErrors := make(map[string]string)
+	// One entry per requested URL, so order and duplicate URLs are preserved
+	finalResults := make([]*types.FetchResult, len(urls))
+	timedOut := 0
+	// Only the batch deadline times URLs out; the timeout of each request is
+	// an ordinary request error
+	deadlinePassed := opts.Deadline > 0 && ctx.Err() != nil
 
 	// Process successful fetches results
 	for i, res := range results {
+		item := items[i]
+		result := &types.FetchResult{URL: urls[i], StartIndex: item.StartIndex}
+		finalResults[i] = result
+
+		// Fetches that did not finish, or were cut off, by the deadline
+		if deadlinePassed && (res == nil || (res.err != nil && errors.Is(res.err, context.DeadlineExceeded))) {
+			result.TimedOut = true
+			result.Error = newFetchError(fetchStageRequest, ierrors.Newf(ierrors.ErrTimeout, "deadline of %s exceeded", opts.Deadline))
+			timedOut++
+			continue
+		}
+		// Fetches abandoned because ctx was done
 		if res == nil {
-			zap.S().Errorw("encountered nil initial result", "index", i, "url", urls[i])
-			finalErrors[urls[i]] = "internal error: nil initial result"
+			result.Error = newFetchError(fetchStageRequest, ierrors.Wrap(ctx.Err(), "fetch interrupted"))
 			continue
 		}
 		if res.err != nil {
-			finalErrors[res.url] = res.err.Error()
+			result.Error = newFetchError(fetchStageRequest, res.err)
+			continue
+		}
+		result.FinalURL = res.url
+		result.StatusCode = res.status
+		result.ContentType = res.contentType
+		result.ResponseInfo = res.info
+		if isHTTPError(res.status) && httpErrors == HTTPErrorsError {
+			result.Error = newFetchError(fetchStageRequest, ierrors.HTTPStatus(res.status))
 			continue
 		}
+		result.Flags = detectPageFlags(res)
 
-		var processedContent string
-		if raw {
-			processedContent = res.body
-			zap.S().Debugw("raw mode enabled for multiple fetch", "url", res.url)
-		} else if strings.Contains(res.contentType, "text/html") {
-			processedContent = processHTMLContent(res.body, res.url)
-		} else {
-			processedContent = string(res.body) // Non-HTML content
-			zap.S().Debugw("non-HTML content for multiple fetch", "url", res.url, "content_type", res.contentType)
+		processingStart := time.Now()
+		processedContent, _, err := processContent(res, res.url, FetchOptions{Raw: *item.Raw, Format: item.Format, Selector: item.Selector})
+		if res.info != nil {
+			res.info.Timings.ProcessingMs = durationMs(time.Since(processingStart))
+		}
+		if err != nil {
+			result.Error = newFetchError(fetchStageProcessing, err)
+			continue
 		}
+		if isHTTPError(res.status) && httpErrors == HTTPErrorsExcerpt {
+			processedContent = truncateText(processedContent, HTTPErrorExcerptLength)
+		}
+		result.Format = contentFormat(res, *item.Raw, item.Format)
+
 		// Append the processed content result
 		processedResults = append(processedResults, &processedResult{
-			URL:           res.url,
-			FullContent:   processedContent,
-			ContentType:   res.contentType,
-			StatusCode:    res.status,
-			OriginalIndex: i,
+			URL:         urls[i],
+			Item:        item,
+			Result:      result,
+			FullContent: processedContent,
+			FullLength:  unit.measure(processedContent),
 		})
 	} // End of for loop processing results
 
 	// --- Allocation Logic ---
-	numSuccessful := len(processedResults)
-	var initialAllocation int
-	if numSuccessful > 0 {
-		initialAllocation = maxLength / numSuccessful
-	} else {
-		initialAllocation = 0 // Avoid division by zero if all fetches failed
-	}
-
-	zap.S().Debugw("calculated initial allocation",
-		"num_successful", numSuccessful,
-		"total_max_length", maxLength,
-		"initial_allocation_per_url", initialAllocation)
 
-	totalUsed := 0
-	beneficiaries := []*processedResult{} // URLs that were truncated by initial allocation
-
-	// Initial trimming and identify beneficiaries
-	for _, res := range processedResults {
-		trimmed := trimContent(res.FullContent, 0, initialAllocation)
-		res.FinalTrimmedContent = trimmed
-		usedChars := len(trimmed)
-		totalUsed += usedChars
-
-		// Check if content was actually longer than the allocation allowed
-		if len(res.FullContent) > initialAllocation {
-			res.WasTruncated = true
-			beneficiaries = append(beneficiaries, res)
-			zap.S().Debugw("URL identified as beneficiary due to truncation",
-				"url", res.URL,
-				"full_length", len(res.FullContent),
-				"allocated", initialAllocation,
-				"trimmed_length", usedChars)
-		} else {
-			zap.S().Debugw("URL content fits initial allocation or is shorter",
-				"url", res.URL,
-				"full_length", len(res.FullContent),
-				"used", usedChars)
+	// Each URL asks for the rest of its content after start_index, up to its own cap
+	demands := make([]int, len(processedResults))
+	weights := make([]float64, len(processedResults))
+	for i, res := range processedResults {
+		demands[i] = max(res.FullLength-res.Item.StartIndex, 0)
+		if res.Item.MaxLength > 0 {
+			demands[i] = min(demands[i], res.Item.MaxLength)
 		}
+		weights[i] = res.Item.Weight
 	}
+	allocations := allocateBudget(maxLength, demands, weights)
 
-	// Reallocation
-	remainingChars := maxLength - totalUsed
-	numBeneficiaries := len(beneficiaries)
-
-	zap.S().Debugw("reallocation status",
-		"total_used_after_initial", totalUsed,
-		"remaining_chars", remainingChars,
-		"num_beneficiaries", numBeneficiaries)
-
-	if remainingChars > 0 && numBeneficiaries > 0 {
-		perURLReallocation := remainingChars / numBeneficiaries
-		zap.S().Debugw("performing reallocation",
-			"per_url_reallocation", perURLReallocation)
-
-		extraCharsDistributed := 0
-		for _, b := range beneficiaries {
-			// Calculate the target length for this beneficiary
-			targetLength := initialAllocation + perURLReallocation
-			// Re-trim the *full* content with the new target length
-			finalTrimmed := trimContent(b.FullContent, 0, targetLength)
-
-			// Calculate how many characters were actually added in this step
-			additionalCharsAdded := len(finalTrimmed) - len(b.FinalTrimmedContent)
-			if additionalCharsAdded < 0 {
-				additionalCharsAdded = 0
-			} // Should not happen, but safety
-
-			// Update the total used characters and the beneficiary's content
-			totalUsed += additionalCharsAdded
-			extraCharsDistributed += additionalCharsAdded
-			b.FinalTrimmedContent = finalTrimmed
-
-			zap.S().Debugw("reallocated content to beneficiary",
-				"url", b.URL,
-				"previous_length", len(b.FinalTrimmedContent)-additionalCharsAdded,
-				"additional_chars_added", additionalCharsAdded,
-				"new_length", len(b.FinalTrimmedContent),
-				"target_length", targetLength)
+	numSuccessful := len(processedResults)
+	totalUsed := 0
+	for i, res := range processedResults {
+		// A zero limit means no limit to trim, so skip URLs left without budget
+		trimmed := ""
+		if allocations[i] > 0 {
+			trimmed = unit.trim(res.FullContent, res.Item.StartIndex, allocations[i])
 		}
-		zap.S().Debugw("reallocation complete", "extra_chars_distributed", extraCharsDistributed, "final_total_used", totalUsed)
-		// Note: A small amount of remainingChars might be left unused due to integer division or if beneficiaries' full content was shorter than targetLength.
+		used := unit.measure(trimmed)
+		totalUsed += used
+
+		res.Result.Content = trimmed
+		res.Result.AllocatedLength = used
+		res.Result.FullLength = res.FullLength
+		res.Result.Truncated = res.Item.StartIndex+used < res.FullLength
+		res.Result.Tokens = f.tokenizer.Count(trimmed)
+		res.Result.TotalTokens = f.tokenizer.Count(res.FullContent)
+
+		zap.S().Debugw("allocated content to URL",
+			"url", res.URL,
+			"weight", res.Item.Weight,
+			"start_index", res.Item.StartIndex,
+			"full_length", res.FullLength,
+			"demand", demands[i],
+			"allocated", allocations[i],
+			"used", used)
 	}
 
 	// Build final response
 	finalResponse := &types.MultipleFetchResponse{
-		Responses: make(map[string]*types.FetchResponse),
-		Errors:    finalErrors,
+		Results:   finalResults,
+		Unit:      unit.name,
+		MaxLength: maxLength,
+		Succeeded: numSuccessful,
+		Failed:    len(urls) - numSuccessful,
+		TimedOut:  timedOut,
 	}
 
-	for _, res := range processedResults {
-		finalResponse.Responses[res.URL] = &types.FetchResponse{
-			URL:         res.URL,
-			ContentType: res.ContentType,
-			Content:     res.FinalTrimmedContent,
-			StatusCode:  res.StatusCode,
+	// A continuation token resumes every truncated document where this call
+	// stopped, and retries the URLs that timed out
+	continuation := continuationState{
+		Unit:                unit.name,
+		MaxLength:           maxLength,
+		HTTPErrors:          httpErrors,
+		DeadlineMs:          opts.Deadline.Milliseconds(),
+		IncludeResponseInfo: opts.IncludeResponseInfo,
+	}
+	for i, result := range finalResults {
+		if !result.TimedOut && !result.Truncated {
+			continue
 		}
+		continuation.Items = append(continuation.Items, continuationItem{
+			URL:        urls[i],
+			StartIndex: items[i].StartIndex + result.AllocatedLength,
+			Weight:     items[i].Weight,
+			MaxLength:  items[i].MaxLength,
+			Raw:        *items[i].Raw,
+			Format:     items[i].Format,
+			Selector:   items[i].Selector,
+		})
+	}
+	if len(continuation.Items) > 0 {
+		token, err := encodeContinuation(continuation)
+		if err != nil {
+			return nil, err
+		}
+		finalResponse.Continuation = token
 	}
 
 	// Log completion
-	finalTotalContentLength := 0
-	for _, r := range finalResponse.Responses {
-		finalTotalContentLength += len(r.Content)
-	}
 	zap.S().Infow("completed fetching multiple URLs",
 		"total_urls_requested", len(urls),
 		"successful_fetches", numSuccessful,
-		"error_count", len(finalResponse.Errors),
-		"final_total_content_length", finalTotalContentLength,
-		"max_length_limit", maxLength)
+		"error_count", finalResponse.Failed,
+		"timed_out", timedOut,
+		"total_used", totalUsed,
+		"max_length_limit", maxLength,
+		"unit", unit.name,
+		"continued_urls", len(continuation.Items))
 
 	return finalResponse, nil
 }
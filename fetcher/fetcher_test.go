@@ -1,10 +1,13 @@
 package fetcher
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -334,16 +337,16 @@ func TestHTTPFetcher_FetchMultiple_Success_Simple(t *testing.T) {
 
 	require.NoError(t, err)
 	require.NotNil(t, resp)
-	assert.Empty(t, resp.Errors)
-	require.Len(t, resp.Responses, 2)
+	assert.Equal(t, 0, resp.Failed)
+	require.Len(t, resp.Results, 2)
 
-	r1, ok1 := resp.Responses[urls[0]]
-	require.True(t, ok1)
+	r1 := resp.Results[0]
+	assert.Equal(t, urls[0], r1.URL)
 	assert.Equal(t, mockResponses["/page1"].Body, r1.Content)
 	assert.Equal(t, http.StatusOK, r1.StatusCode)
 
-	r2, ok2 := resp.Responses[urls[1]]
-	require.True(t, ok2)
+	r2 := resp.Results[1]
+	assert.Equal(t, urls[1], r2.URL)
 	assert.Equal(t, mockResponses["/page2"].Body, r2.Content)
 	assert.Equal(t, http.StatusOK, r2.StatusCode)
 
@@ -369,8 +372,8 @@ func TestHTTPFetcher_FetchMultiple_Success_Allocation(t *testing.T) {
 
 	require.NoError(t, err)
 	require.NotNil(t, resp)
-	assert.Empty(t, resp.Errors)
-	require.Len(t, resp.Responses, 3)
+	assert.Equal(t, 0, resp.Failed)
+	require.Len(t, resp.Results, 3)
 
 	// Initial allocation: 30 / 3 = 10 per URL
 	// /short1: uses 10 (fits)
@@ -383,9 +386,9 @@ func TestHTTPFetcher_FetchMultiple_Success_Allocation(t *testing.T) {
 	// Final lengths: /short1=10, /long1=10+5=15, /short2=5
 	// Final total: 10 + 15 + 5 = 30
 
-	rShort1, _ := resp.Responses[urls[0]]
-	rLong1, _ := resp.Responses[urls[1]]
-	rShort2, _ := resp.Responses[urls[2]]
+	rShort1 := resp.Results[0]
+	rLong1 := resp.Results[1]
+	rShort2 := resp.Results[2]
 
 	assert.Equal(t, "1234567890", rShort1.Content, "short1 content mismatch")    // Full content
 	assert.Equal(t, "This content is", rLong1.Content, "long1 content mismatch") // Truncated to 15
@@ -397,6 +400,15 @@ func TestHTTPFetcher_FetchMultiple_Success_Allocation(t *testing.T) {
 
 	totalLen := len(rShort1.Content) + len(rLong1.Content) + len(rShort2.Content)
 	assert.Equal(t, maxLength, totalLen, "Total length should match maxLength")
+
+	// Allocated and full lengths are reported per URL
+	assert.Equal(t, 15, rLong1.AllocatedLength)
+	assert.Equal(t, len(mockResponses["/long1"].Body), rLong1.FullLength)
+	assert.True(t, rLong1.Truncated)
+	assert.Equal(t, 10, rShort1.FullLength)
+	assert.False(t, rShort1.Truncated)
+	assert.Equal(t, "chars", resp.Unit)
+	assert.Equal(t, maxLength, resp.MaxLength)
 }
 
 func TestHTTPFetcher_FetchMultiple_Success_Allocation_MoreRemaining(t *testing.T) {
@@ -417,8 +429,8 @@ func TestHTTPFetcher_FetchMultiple_Success_Allocation_MoreRemaining(t *testing.T
 
 	require.NoError(t, err)
 	require.NotNil(t, resp)
-	assert.Empty(t, resp.Errors)
-	require.Len(t, resp.Responses, 3)
+	assert.Equal(t, 0, resp.Failed)
+	require.Len(t, resp.Results, 3)
 
 	// Initial allocation: 50 / 3 = 16 per URL
 	// /s1: uses 5 (fits)
@@ -429,9 +441,9 @@ func TestHTTPFetcher_FetchMultiple_Success_Allocation_MoreRemaining(t *testing.T
 	// Beneficiaries: 0 (no one was truncated)
 	// No reallocation needed.
 
-	r1, _ := resp.Responses[urls[0]]
-	r2, _ := resp.Responses[urls[1]]
-	r3, _ := resp.Responses[urls[2]]
+	r1 := resp.Results[0]
+	r2 := resp.Results[1]
+	r3 := resp.Results[2]
 
 	assert.Equal(t, "12345", r1.Content)
 	assert.Equal(t, "abcde", r2.Content)
@@ -464,28 +476,38 @@ func TestHTTPFetcher_FetchMultiple_PartialFailure(t *testing.T) {
 	require.NoError(t, err) // FetchMultiple itself shouldn't error on partial failures
 	require.NotNil(t, resp)
 
+	// Results are in request order
+	require.Len(t, resp.Results, 4)
+	for i, r := range resp.Results {
+		assert.Equal(t, urls[i], r.URL)
+	}
+
 	// Check successful response
-	require.Contains(t, resp.Responses, urls[0])
-	rOk, _ := resp.Responses[urls[0]]
+	rOk := resp.Results[0]
+	assert.Nil(t, rOk.Error)
 	assert.Equal(t, "This is okay", rOk.Content)
 	assert.Equal(t, http.StatusOK, rOk.StatusCode)
 
 	// Check response with server error status
-	require.Contains(t, resp.Responses, urls[1])
-	rErrStatus, _ := resp.Responses[urls[1]]
+	rErrStatus := resp.Results[1]
+	assert.Nil(t, rErrStatus.Error)
 	assert.Equal(t, "Server Error", rErrStatus.Content) // Content is still fetched
 	assert.Equal(t, http.StatusInternalServerError, rErrStatus.StatusCode)
 
 	// Check response for 404
-	require.Contains(t, resp.Responses, urls[2])
-	rNotFound, _ := resp.Responses[urls[2]]
+	rNotFound := resp.Results[2]
+	assert.Nil(t, rNotFound.Error)
 	assert.Equal(t, "Not Found", rNotFound.Content) // Content from mock 404 handler
 	assert.Equal(t, http.StatusNotFound, rNotFound.StatusCode)
 
-	// Ensure only the successful/fetched ones are in Responses
-	assert.Len(t, resp.Responses, 3)
-	// Ensure only the connection error is in Errors
-	assert.Len(t, resp.Errors, 1)
+	// Only the connection error is reported as failed
+	rDown := resp.Results[3]
+	require.NotNil(t, rDown.Error)
+	assert.Equal(t, "request", rDown.Error.Stage)
+	assert.Contains(t, rDown.Error.Message, "failed to execute request")
+	assert.Empty(t, rDown.Content)
+	assert.Equal(t, 3, resp.Succeeded)
+	assert.Equal(t, 1, resp.Failed)
 }
 
 func TestHTTPFetcher_FetchMultiple_AllFailures(t *testing.T) {
@@ -503,7 +525,33 @@ func TestHTTPFetcher_FetchMultiple_AllFailures(t *testing.T) {
 
 	require.NoError(t, err)
 	require.NotNil(t, resp)
-	assert.Empty(t, resp.Responses) // No successful responses
+	assert.Equal(t, 0, resp.Succeeded) // No successful responses
+
+	// Each failure is attributed to its own URL
+	require.Len(t, resp.Results, 2)
+	for i, r := range resp.Results {
+		assert.Equal(t, urls[i], r.URL)
+		require.NotNil(t, r.Error)
+	}
+}
+
+func TestHTTPFetcher_FetchMultiple_DuplicateURLs(t *testing.T) {
+	mockResponses := map[string]mockResponse{
+		"/same":  {Body: "Same page", ContentType: "text/plain", StatusCode: http.StatusOK},
+		"/other": {Body: "Other page", ContentType: "text/plain", StatusCode: http.StatusOK},
+	}
+	server := startMockServer(t, mockResponses)
+	fetcher := newTestFetcher(t, server.URL)
+
+	urls := []string{server.URL + "/same", server.URL + "/other", server.URL + "/same"}
+	resp, err := fetcher.FetchMultiple(urls, 100, true)
+
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 3)
+	assert.Equal(t, "Same page", resp.Results[0].Content)
+	assert.Equal(t, "Other page", resp.Results[1].Content)
+	assert.Equal(t, "Same page", resp.Results[2].Content)
+	assert.Equal(t, urls[2], resp.Results[2].URL)
 }
 
 func TestHTTPFetcher_FetchMultiple_DefaultMaxLength(t *testing.T) {
@@ -534,8 +582,8 @@ func TestHTTPFetcher_FetchMultiple_DefaultMaxLength(t *testing.T) {
 
 	require.NoError(t, err)
 	require.NotNil(t, resp)
-	assert.Empty(t, resp.Errors)
-	require.Len(t, resp.Responses, 2)
+	assert.Equal(t, 0, resp.Failed)
+	require.Len(t, resp.Results, 2)
 
 	// Default MaxLength = 1500
 	// Initial allocation: 1500 / 2 = 750 per URL
@@ -546,8 +594,8 @@ func TestHTTPFetcher_FetchMultiple_DefaultMaxLength(t *testing.T) {
 	// No reallocation possible.
 	// Final lengths: 750, 750
 
-	r1, _ := resp.Responses[urls[0]]
-	r2, _ := resp.Responses[urls[1]]
+	r1 := resp.Results[0]
+	r2 := resp.Results[1]
 
 	assert.Len(t, r1.Content, 750)
 	assert.Len(t, r2.Content, 750)
@@ -572,17 +620,15 @@ func TestHTTPFetcher_FetchMultiple_HTMLProcessing(t *testing.T) {
 
 	require.NoError(t, err)
 	require.NotNil(t, resp)
-	assert.Empty(t, resp.Errors)
-	require.Len(t, resp.Responses, 2)
+	assert.Equal(t, 0, resp.Failed)
+	require.Len(t, resp.Results, 2)
 
-	r1, ok1 := resp.Responses[urls[0]]
-	require.True(t, ok1)
+	r1 := resp.Results[0]
 	assert.NotEqual(t, mockResponses["/html1"].Body, r1.Content) // Check it's processed
 	assert.Contains(t, r1.Content, "T1")                         // Check title
 	// assert.Contains(t, r1.Content, "C1")                      // Removed: Avoid testing readability details
 
-	r2, ok2 := resp.Responses[urls[1]]
-	require.True(t, ok2)
+	r2 := resp.Results[1]
 	assert.NotEqual(t, mockResponses["/html2"].Body, r2.Content) // Check it's processed
 	assert.Contains(t, r2.Content, "T2")                         // Check title
 	// assert.Contains(t, r2.Content, "C2")                      // Removed: Avoid testing readability details
@@ -623,3 +669,228 @@ func TestHTTPFetcher_Fetch_FollowsRedirect(t *testing.T) {
 	// Check that original_url is set to the initial URL when redirect occurs
 	assert.Equal(t, server.URL+"/redirect", resp.OriginalURL, "original_url should be set to the initial URL when redirect occurs")
 }
+
+// --- Test Cases for token limits ---
+
+func newTestFetcherWithTokenizer(t *testing.T, tokenizerName string) Fetcher {
+	t.Helper()
+	cfg := &Config{
+		Timeout:          5,
+		UserAgent:        "test-agent/1.0",
+		MaxURLs:          20,
+		MaxWorkers:       5,
+		DefaultMaxLength: 1000,
+		Tokenizer:        tokenizerName,
+	}
+	fetcher, err := NewHTTPFetcher(cfg)
+	require.NoError(t, err, "Failed to create test fetcher")
+	return fetcher
+}
+
+func TestNewHTTPFetcher_InvalidTokenizer(t *testing.T) {
+	_, err := NewHTTPFetcher(&Config{Timeout: 5, Tokenizer: "unknown"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported tokenizer")
+}
+
+func TestHTTPFetcher_FetchWithOptions_TokenLimits(t *testing.T) {
+	mockResponses := map[string]mockResponse{
+		"/words": {Body: "one two six ten red big", ContentType: "text/plain", StatusCode: http.StatusOK},
+	}
+	server := startMockServer(t, mockResponses)
+	fetcher := newTestFetcherWithTokenizer(t, "heuristic")
+
+	resp, err := fetcher.FetchWithOptions(server.URL+"/words", FetchOptions{MaxTokens: 3})
+	require.NoError(t, err)
+	assert.Equal(t, "one two six", resp.Content)
+	assert.Equal(t, 3, resp.Tokens)
+	assert.Equal(t, 6, resp.TotalTokens)
+
+	// Paging with start_token
+	resp, err = fetcher.FetchWithOptions(server.URL+"/words", FetchOptions{StartToken: 3, MaxTokens: 3})
+	require.NoError(t, err)
+	assert.Equal(t, " ten red big", resp.Content)
+	assert.Equal(t, 3, resp.Tokens)
+
+	// max_length still caps the token-limited content
+	resp, err = fetcher.FetchWithOptions(server.URL+"/words", FetchOptions{MaxTokens: 3, MaxLength: 5})
+	require.NoError(t, err)
+	assert.Equal(t, "one t", resp.Content)
+
+	// Character mode reports token counts as well
+	resp, err = fetcher.FetchWithOptions(server.URL+"/words", FetchOptions{MaxLength: 7})
+	require.NoError(t, err)
+	assert.Equal(t, "one two", resp.Content)
+	assert.Equal(t, 2, resp.Tokens)
+	assert.Equal(t, 6, resp.TotalTokens)
+}
+
+func TestHTTPFetcher_FetchMultipleWithOptions_TokenBudget(t *testing.T) {
+	// Token counts with the heuristic tokenizer: 2, 8, 1
+	mockResponses := map[string]mockResponse{
+		"/short": {Body: "one two", ContentType: "text/plain", StatusCode: http.StatusOK},
+		"/long":  {Body: "a b c d e f g h", ContentType: "text/plain", StatusCode: http.StatusOK},
+		"/tiny":  {Body: "z", ContentType: "text/plain", StatusCode: http.StatusOK},
+	}
+	server := startMockServer(t, mockResponses)
+	fetcher := newTestFetcherWithTokenizer(t, "heuristic")
+
+	urls := []string{server.URL + "/short", server.URL + "/long", server.URL + "/tiny"}
+	resp, err := fetcher.FetchMultipleWithOptions(context.Background(), urls, MultipleFetchOptions{MaxTokens: 9, Raw: true})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 3)
+	assert.Equal(t, "tokens", resp.Unit)
+
+	// Initial allocation: 9 / 3 = 3 tokens per URL
+	// /short uses 2, /long uses 3 (truncated), /tiny uses 1 -> 6 used, 3 remaining
+	// /long is the only beneficiary and receives 3 more tokens: 6 in total
+	assert.Equal(t, "one two", resp.Results[0].Content)
+	assert.Equal(t, "a b c d e f", resp.Results[1].Content)
+	assert.Equal(t, "z", resp.Results[2].Content)
+
+	total := 0
+	for _, r := range resp.Results {
+		total += r.Tokens
+	}
+	assert.Equal(t, 9, total)
+	assert.Equal(t, 8, resp.Results[1].TotalTokens)
+	assert.Equal(t, 6, resp.Results[1].AllocatedLength)
+	assert.Equal(t, 8, resp.Results[1].FullLength)
+}
+
+func TestHTTPFetcher_FetchMultipleWithOptions_Deadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			// Hang until the client gives up
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		case "/long":
+			_, _ = w.Write([]byte("0123456789abcdefghij"))
+		case "/short":
+			_, _ = w.Write([]byte("short"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	fetcher := newTestFetcher(t, server.URL)
+
+	urls := []string{server.URL + "/long", server.URL + "/slow", server.URL + "/short"}
+	start := time.Now()
+	resp, err := fetcher.FetchMultipleWithOptions(context.Background(), urls, MultipleFetchOptions{MaxLength: 20, Raw: true, Deadline: 300 * time.Millisecond})
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	require.Len(t, resp.Results, 3)
+	slow := resp.Results[1]
+	assert.True(t, slow.TimedOut)
+	require.NotNil(t, slow.Error)
+	assert.Contains(t, slow.Error.Message, "deadline")
+	assert.Equal(t, "timeout", slow.Error.Code)
+	assert.True(t, slow.Error.Retryable)
+	assert.Equal(t, 1, resp.TimedOut)
+	assert.Equal(t, 2, resp.Succeeded)
+	assert.Equal(t, 1, resp.Failed)
+
+	// The budget is shared by the finished URLs only: 10 each, with the 5 left by /short going to /long
+	assert.Equal(t, "0123456789abcde", resp.Results[0].Content)
+	assert.Equal(t, "short", resp.Results[2].Content)
+
+	// The continuation retries the timed out URL and resumes the truncated one
+	nextURLs, opts, err := ParseContinuation(resp.Continuation)
+	require.NoError(t, err)
+	assert.Equal(t, []string{server.URL + "/long", server.URL + "/slow"}, nextURLs)
+	assert.Equal(t, 15, opts.Items[0].StartIndex)
+	assert.Equal(t, 0, opts.Items[1].StartIndex)
+}
+
+func TestHTTPFetcher_FetchMultipleWithOptions_Cancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		_, _ = w.Write([]byte("fast"))
+	}))
+	t.Cleanup(server.Close)
+	fetcher := newTestFetcher(t, server.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	resp, err := fetcher.FetchMultipleWithOptions(ctx, []string{server.URL + "/fast", server.URL + "/slow"}, MultipleFetchOptions{MaxLength: 100, Raw: true})
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	require.Len(t, resp.Results, 2)
+	assert.Equal(t, "fast", resp.Results[0].Content)
+	assert.NotNil(t, resp.Results[1].Error)
+	assert.False(t, resp.Results[1].TimedOut)
+	assert.Equal(t, 0, resp.TimedOut)
+}
+
+func TestHTTPFetcher_FetchMultipleWithOptions_RequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		_, _ = w.Write([]byte("fast"))
+	}))
+	t.Cleanup(server.Close)
+	fetcher, err := NewHTTPFetcher(&Config{Timeout: 1, UserAgent: "test-agent/1.0", MaxWorkers: 5, DefaultMaxLength: 1000})
+	require.NoError(t, err)
+
+	// The timeout of a request is not the batch deadline
+	resp, err := fetcher.FetchMultipleWithOptions(context.Background(), []string{server.URL + "/fast", server.URL + "/slow"}, MultipleFetchOptions{MaxLength: 100, Raw: true})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 2)
+	slow := resp.Results[1]
+	require.NotNil(t, slow.Error)
+	assert.False(t, slow.TimedOut)
+	assert.NotContains(t, slow.Error.Message, "deadline of")
+	assert.Equal(t, "timeout", slow.Error.Code)
+	assert.Equal(t, 0, resp.TimedOut)
+	assert.Equal(t, 1, resp.Failed)
+}
+
+func TestHTTPFetcher_MaxBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		switch r.URL.Path {
+		case "/small":
+			_, _ = w.Write([]byte("0123456789"))
+		case "/large":
+			_, _ = w.Write([]byte("0123456789abcdef"))
+		case "/chunked":
+			// Flushing first sends the body without a Content-Length
+			w.(http.Flusher).Flush()
+			_, _ = w.Write([]byte("0123456789abcdef"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	fetcher, err := NewHTTPFetcher(&Config{Timeout: 5, UserAgent: "test-agent/1.0", MaxWorkers: 5, DefaultMaxLength: 1000, MaxBodySize: 10})
+	require.NoError(t, err)
+
+	resp, err := fetcher.Fetch(server.URL+"/small", 100, 0, true)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", resp.Content)
+
+	for _, path := range []string{"/large", "/chunked"} {
+		_, err = fetcher.Fetch(server.URL+path, 100, 0, true)
+		require.Error(t, err, path)
+		assert.Equal(t, "too_large", ierrors.Code(err), path)
+	}
+}
@@ -0,0 +1,350 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"html"
+	"strings"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"github.com/mackee/go-readability"
+	"go.uber.org/zap"
+	xhtml "golang.org/x/net/html"
+)
+
+// Format is the output format HTML content is converted to.
+type Format string
+
+const (
+	// FormatMarkdown converts content to Markdown (the default).
+	FormatMarkdown Format = "markdown"
+	// FormatText strips all markup and returns plain text.
+	FormatText Format = "text"
+	// FormatHTML returns the readability-cleaned HTML.
+	FormatHTML Format = "html"
+	// FormatJSON returns a types.StructuredDocument block tree encoded as JSON.
+	FormatJSON Format = "json"
+)
+
+// Formats lists every supported output format.
+var Formats = []string{string(FormatMarkdown), string(FormatText), string(FormatHTML), string(FormatJSON)}
+
+// ParseFormat validates a format name. An empty name selects FormatMarkdown.
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(name))) {
+	case "", FormatMarkdown:
+		return FormatMarkdown, nil
+	case FormatText:
+		return FormatText, nil
+	case FormatHTML:
+		return FormatHTML, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return "", ierrors.Newf(ierrors.ErrInvalidArgument, "unsupported format %q (expected one of %s)", name, strings.Join(Formats, ", "))
+}
+
+// formatHTMLContent extracts the main content of an HTML document with readability
+// and converts it to the requested format.
+// It falls back to the raw body string if readability fails.
+func formatHTMLContent(body string, urlStr string, format Format) string {
+	if format == "" || format == FormatMarkdown {
+		return processHTMLContent(body, urlStr)
+	}
+
+	opts := readability.DefaultOptions()
+	article, readErr := readability.Extract(body, opts)
+	if readErr != nil {
+		zap.S().Warnw("readability extraction failed, falling back to raw body", "url", urlStr, "error", readErr)
+		return body
+	}
+
+	// The cleaned HTML of the main content is the source for every other format
+	cleanedHTML := readability.ToHTML(article.Root)
+
+	var result string
+	switch format {
+	case FormatHTML:
+		if article.Title != "" {
+			result += "<h1>" + html.EscapeString(article.Title) + "</h1>\n"
+		}
+		result += cleanedHTML
+		if article.Byline != "" {
+			result += "\n<hr/>\n<p>Author: " + html.EscapeString(article.Byline) + "</p>"
+		}
+	case FormatText:
+		if article.Title != "" {
+			result += article.Title + "\n\n"
+		}
+		result += blocksToText(htmlToBlocks(cleanedHTML), 0)
+		if article.Byline != "" {
+			result += "\n\nAuthor: " + article.Byline
+		}
+	case FormatJSON:
+		result = encodeStructuredDocument(&types.StructuredDocument{
+			Title:  article.Title,
+			Byline: article.Byline,
+			Blocks: nestUnderHeadings(htmlToBlocks(cleanedHTML)),
+		})
+	}
+
+	zap.S().Debugw("processed HTML with readability",
+		"url", urlStr,
+		"format", format,
+		"title", article.Title,
+		"byline", article.Byline,
+		"content_length", len(result))
+
+	return result
+}
+
+// formatFragment converts an HTML fragment to the requested format without
+// running readability's main-content detection.
+func formatFragment(fragment string, format Format) (string, error) {
+	switch format {
+	case FormatHTML:
+		return fragment, nil
+	case FormatText:
+		return blocksToText(htmlToBlocks(fragment), 0), nil
+	case FormatJSON:
+		return encodeStructuredDocument(&types.StructuredDocument{
+			Blocks: nestUnderHeadings(htmlToBlocks(fragment)),
+		}), nil
+	}
+	return htmlFragmentToMarkdown(fragment)
+}
+
+// encodeStructuredDocument marshals doc as JSON. Marshalling plain structs
+// cannot fail, so errors are only logged.
+func encodeStructuredDocument(doc *types.StructuredDocument) string {
+	if doc.Blocks == nil {
+		doc.Blocks = []*types.Block{}
+	}
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		zap.S().Errorw("failed to marshal structured document", "error", err)
+		return ""
+	}
+	return string(encoded)
+}
+
+// htmlToBlocks parses an HTML fragment into a flat list of top-level blocks.
+// Lists, list items and blockquotes carry their nested blocks as children.
+func htmlToBlocks(fragment string) []*types.Block {
+	doc, err := xhtml.Parse(strings.NewReader(fragment))
+	if err != nil {
+		zap.S().Warnw("failed to parse HTML for block conversion", "error", ierrors.Wrap(err, "failed to parse HTML"))
+		return nil
+	}
+	return collectBlocks(doc)
+}
+
+// collectBlocks converts the children of n to blocks. Inline content between
+// block elements is gathered into paragraphs.
+func collectBlocks(n *xhtml.Node) []*types.Block {
+	blocks := []*types.Block{}
+	var inline strings.Builder
+	flush := func() {
+		if text := collapseWhitespace(inline.String()); text != "" {
+			blocks = append(blocks, &types.Block{Type: "paragraph", Text: text})
+		}
+		inline.Reset()
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == xhtml.TextNode {
+			inline.WriteString(c.Data)
+			continue
+		}
+		if c.Type != xhtml.ElementNode {
+			continue
+		}
+
+		switch c.Data {
+		case "script", "style", "noscript", "template", "head":
+			// Not content
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			flush()
+			if text := collapseWhitespace(nodeText(c)); text != "" {
+				blocks = append(blocks, &types.Block{Type: "heading", Level: int(c.Data[1] - '0'), Text: text})
+			}
+		case "p":
+			flush()
+			if text := collapseWhitespace(nodeText(c)); text != "" {
+				blocks = append(blocks, &types.Block{Type: "paragraph", Text: text})
+			}
+		case "ul", "ol":
+			flush()
+			blocks = append(blocks, listBlock(c))
+		case "pre":
+			flush()
+			blocks = append(blocks, codeBlock(c))
+		case "table":
+			flush()
+			if table := tableBlock(c); len(table.Rows) > 0 {
+				blocks = append(blocks, table)
+			}
+		case "blockquote":
+			flush()
+			blocks = append(blocks, &types.Block{Type: "blockquote", Children: collectBlocks(c)})
+		case "br":
+			inline.WriteString(" ")
+		case "hr":
+			flush()
+		default:
+			if isBlockContainer(c.Data) {
+				flush()
+				blocks = append(blocks, collectBlocks(c)...)
+			} else {
+				inline.WriteString(nodeText(c))
+			}
+		}
+	}
+	flush()
+	return blocks
+}
+
+// isBlockContainer reports whether a tag groups block content without a meaning of its own.
+func isBlockContainer(tag string) bool {
+	switch tag {
+	case "html", "body", "div", "section", "article", "main", "header", "footer", "nav", "aside",
+		"figure", "figcaption", "details", "summary", "dl", "dt", "dd", "form", "fieldset", "address":
+		return true
+	}
+	return false
+}
+
+// listBlock converts a <ul> or <ol> element into a list block with item children.
+func listBlock(n *xhtml.Node) *types.Block {
+	list := &types.Block{Type: "list", Ordered: n.Data == "ol"}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != xhtml.ElementNode || c.Data != "li" {
+			continue
+		}
+		item := &types.Block{Type: "item"}
+		children := collectBlocks(c)
+		// The leading paragraph of an item is its text; anything else is nested
+		if len(children) > 0 && children[0].Type == "paragraph" {
+			item.Text = children[0].Text
+			children = children[1:]
+		}
+		if len(children) > 0 {
+			item.Children = children
+		}
+		list.Children = append(list.Children, item)
+	}
+	return list
+}
+
+// codeBlock converts a <pre> element into a code block, keeping its whitespace.
+func codeBlock(n *xhtml.Node) *types.Block {
+	block := &types.Block{Type: "code", Text: strings.Trim(nodeText(n), "\n")}
+	for el := n; el != nil; el = el.FirstChild {
+		if el.Type != xhtml.ElementNode {
+			break
+		}
+		for _, class := range strings.Fields(getAttr(el, "class")) {
+			if lang, ok := strings.CutPrefix(class, "language-"); ok {
+				block.Language = lang
+				return block
+			}
+		}
+	}
+	return block
+}
+
+// tableBlock converts a <table> element into a table block of cell texts.
+func tableBlock(n *xhtml.Node) *types.Block {
+	table := &types.Block{Type: "table"}
+	var walk func(*xhtml.Node)
+	walk = func(el *xhtml.Node) {
+		for c := el.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != xhtml.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "tr":
+				row := []string{}
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == xhtml.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+						row = append(row, collapseWhitespace(nodeText(cell)))
+					}
+				}
+				if len(row) > 0 {
+					table.Rows = append(table.Rows, row)
+				}
+			case "table":
+				// Nested tables are flattened into their parent cell text
+			default:
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	return table
+}
+
+// nestUnderHeadings turns a flat block list into a tree where each heading
+// contains the blocks up to the next heading of the same or a higher level.
+func nestUnderHeadings(blocks []*types.Block) []*types.Block {
+	root := []*types.Block{}
+	stack := []*types.Block{}
+	for _, b := range blocks {
+		if b.Type == "heading" {
+			for len(stack) > 0 && stack[len(stack)-1].Level >= b.Level {
+				stack = stack[:len(stack)-1]
+			}
+		}
+		if len(stack) == 0 {
+			root = append(root, b)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, b)
+		}
+		if b.Type == "heading" {
+			stack = append(stack, b)
+		}
+	}
+	return root
+}
+
+// blocksToText renders blocks as plain text. Blocks are separated by blank
+// lines and nested list items are indented by two spaces per level.
+func blocksToText(blocks []*types.Block, depth int) string {
+	indent := strings.Repeat("  ", depth)
+	parts := []string{}
+	for _, b := range blocks {
+		switch b.Type {
+		case "list":
+			lines := []string{}
+			for _, item := range b.Children {
+				line := indent + item.Text
+				if nested := blocksToText(item.Children, depth+1); nested != "" {
+					line += "\n" + nested
+				}
+				lines = append(lines, line)
+			}
+			parts = append(parts, strings.Join(lines, "\n"))
+		case "table":
+			rows := make([]string, 0, len(b.Rows))
+			for _, row := range b.Rows {
+				rows = append(rows, indent+strings.Join(row, "\t"))
+			}
+			parts = append(parts, strings.Join(rows, "\n"))
+		case "blockquote":
+			parts = append(parts, blocksToText(b.Children, depth))
+		default:
+			if b.Text != "" {
+				parts = append(parts, indent+b.Text)
+			}
+			if len(b.Children) > 0 {
+				parts = append(parts, blocksToText(b.Children, depth))
+			}
+		}
+	}
+	separator := "\n\n"
+	if depth > 0 {
+		separator = "\n"
+	}
+	return strings.Join(parts, separator)
+}
@@ -0,0 +1,176 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cnosuke/mcp-fetch/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const formatFragmentHTML = `<div>
+<h2>Install</h2>
+<p>Run the <a href="/dl">installer</a>.</p>
+<ul><li>Linux<ul><li>deb</li><li>rpm</li></ul></li><li>macOS</li></ul>
+<h3>From source</h3>
+<pre><code class="language-sh">make build
+make install</code></pre>
+<h2>Usage</h2>
+<table><tr><th>Flag</th><th>Meaning</th></tr><tr><td>-v</td><td>verbose</td></tr></table>
+</div>`
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Format
+		wantErr  bool
+	}{
+		{name: "empty defaults to markdown", input: "", expected: FormatMarkdown},
+		{name: "markdown", input: "markdown", expected: FormatMarkdown},
+		{name: "case insensitive", input: "TEXT", expected: FormatText},
+		{name: "html", input: "html", expected: FormatHTML},
+		{name: "json", input: " json ", expected: FormatJSON},
+		{name: "unknown", input: "pdf", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := ParseFormat(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "unsupported format")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestFormatFragment_JSON(t *testing.T) {
+	content, err := formatFragment(formatFragmentHTML, FormatJSON)
+	require.NoError(t, err)
+
+	var doc types.StructuredDocument
+	require.NoError(t, json.Unmarshal([]byte(content), &doc))
+	require.Len(t, doc.Blocks, 2) // Two h2 sections
+
+	install := doc.Blocks[0]
+	assert.Equal(t, "heading", install.Type)
+	assert.Equal(t, 2, install.Level)
+	assert.Equal(t, "Install", install.Text)
+	require.Len(t, install.Children, 3) // paragraph, list, h3
+
+	assert.Equal(t, "paragraph", install.Children[0].Type)
+	assert.Equal(t, "Run the installer.", install.Children[0].Text)
+
+	list := install.Children[1]
+	assert.Equal(t, "list", list.Type)
+	assert.False(t, list.Ordered)
+	require.Len(t, list.Children, 2)
+	assert.Equal(t, "Linux", list.Children[0].Text)
+	require.Len(t, list.Children[0].Children, 1)
+	assert.Equal(t, "deb", list.Children[0].Children[0].Children[0].Text)
+
+	fromSource := install.Children[2]
+	assert.Equal(t, "heading", fromSource.Type)
+	assert.Equal(t, 3, fromSource.Level)
+	require.Len(t, fromSource.Children, 1)
+	assert.Equal(t, "code", fromSource.Children[0].Type)
+	assert.Equal(t, "sh", fromSource.Children[0].Language)
+	assert.Equal(t, "make build\nmake install", fromSource.Children[0].Text)
+
+	usage := doc.Blocks[1]
+	assert.Equal(t, "Usage", usage.Text)
+	require.Len(t, usage.Children, 1)
+	assert.Equal(t, [][]string{{"Flag", "Meaning"}, {"-v", "verbose"}}, usage.Children[0].Rows)
+}
+
+func TestFormatFragment_Text(t *testing.T) {
+	content, err := formatFragment(formatFragmentHTML, FormatText)
+	require.NoError(t, err)
+
+	expected := strings.Join([]string{
+		"Install",
+		"Run the installer.",
+		"Linux\n  deb\n  rpm\nmacOS",
+		"From source",
+		"make build\nmake install",
+		"Usage",
+		"Flag\tMeaning\n-v\tverbose",
+	}, "\n\n")
+	assert.Equal(t, expected, content)
+	assert.NotContains(t, content, "<")
+	assert.NotContains(t, content, "#")
+}
+
+func TestHTTPFetcher_FetchWithOptions_Formats(t *testing.T) {
+	page := "<html><head><title>Guide</title></head><body><article>" +
+		strings.Repeat("<p>This paragraph is long enough for readability to treat the page as an article body.</p>", 10) +
+		"</article></body></html>"
+	mockResponses := map[string]mockResponse{
+		"/guide": {Body: page, ContentType: "text/html", StatusCode: http.StatusOK},
+		"/plain": {Body: "plain", ContentType: "text/plain", StatusCode: http.StatusOK},
+	}
+	server := startMockServer(t, mockResponses)
+	fetcher := newTestFetcher(t, server.URL)
+
+	resp, err := fetcher.FetchWithOptions(server.URL+"/guide", FetchOptions{Format: FormatText})
+	require.NoError(t, err)
+	assert.Equal(t, "text", resp.Format)
+	assert.True(t, strings.HasPrefix(resp.Content, "Guide\n\nThis paragraph"))
+	assert.NotContains(t, resp.Content, "<p>")
+
+	resp, err = fetcher.FetchWithOptions(server.URL+"/guide", FetchOptions{Format: FormatHTML})
+	require.NoError(t, err)
+	assert.Equal(t, "html", resp.Format)
+	assert.Contains(t, resp.Content, "<h1>Guide</h1>")
+	assert.Contains(t, resp.Content, "<p>This paragraph")
+
+	resp, err = fetcher.FetchWithOptions(server.URL+"/guide", FetchOptions{Format: FormatJSON})
+	require.NoError(t, err)
+	var doc types.StructuredDocument
+	require.NoError(t, json.Unmarshal([]byte(resp.Content), &doc))
+	assert.Equal(t, "Guide", doc.Title)
+	assert.NotEmpty(t, doc.Blocks)
+
+	// Trimming applies to every format
+	resp, err = fetcher.FetchWithOptions(server.URL+"/guide", FetchOptions{Format: FormatText, StartIndex: 7, MaxLength: 4})
+	require.NoError(t, err)
+	assert.Equal(t, "This", resp.Content)
+
+	// Raw and non-HTML content are returned unconverted
+	resp, err = fetcher.FetchWithOptions(server.URL+"/guide", FetchOptions{Format: FormatJSON, Raw: true})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Format)
+	assert.Equal(t, page[:len(resp.Content)], resp.Content)
+
+	resp, err = fetcher.FetchWithOptions(server.URL+"/plain", FetchOptions{Format: FormatJSON})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Format)
+	assert.Equal(t, "plain", resp.Content)
+}
+
+func TestHTTPFetcher_FetchMultipleWithOptions_Format(t *testing.T) {
+	mockResponses := map[string]mockResponse{
+		"/a": {Body: "<html><title>A</title><body><p>Alpha</p></body></html>", ContentType: "text/html", StatusCode: http.StatusOK},
+		"/b": {Body: "<html><title>B</title><body><p>Beta</p></body></html>", ContentType: "text/html", StatusCode: http.StatusOK},
+	}
+	server := startMockServer(t, mockResponses)
+	fetcher := newTestFetcher(t, server.URL)
+
+	urls := []string{server.URL + "/a", server.URL + "/b"}
+	resp, err := fetcher.FetchMultipleWithOptions(context.Background(), urls, MultipleFetchOptions{MaxLength: 200, Format: FormatText})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 2)
+	for _, r := range resp.Results {
+		require.Nil(t, r.Error)
+		assert.Equal(t, "text", r.Format)
+		assert.NotContains(t, r.Content, "#")
+	}
+}
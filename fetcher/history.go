@@ -0,0 +1,136 @@
+package fetcher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cnosuke/mcp-fetch/types"
+)
+
+// DefaultHistorySize is the number of documents a History keeps by default.
+const DefaultHistorySize = 50
+
+// History records the documents recently returned by a Fetcher, most recent
+// first. It is safe for concurrent use.
+type History struct {
+	mu      sync.Mutex
+	size    int
+	entries []types.HistoryEntry
+}
+
+// NewHistory creates a History keeping the last size documents, or
+// DefaultHistorySize if size is not positive.
+func NewHistory(size int) *History {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	return &History{size: size}
+}
+
+// Entries returns the recorded documents, most recent first.
+func (h *History) Entries() []types.HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entries := make([]types.HistoryEntry, len(h.entries))
+	copy(entries, h.entries)
+	return entries
+}
+
+// record adds a document. A document fetched again replaces its earlier entry.
+func (h *History) record(entry types.HistoryEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entries := []types.HistoryEntry{entry}
+	for _, e := range h.entries {
+		if e.URL != entry.URL && len(entries) < h.size {
+			entries = append(entries, e)
+		}
+	}
+	h.entries = entries
+}
+
+// historyFetcher records the documents returned by the Fetcher it wraps.
+type historyFetcher struct {
+	Fetcher
+	history *History
+}
+
+// RecordHistory returns a Fetcher that behaves like f and records the
+// documents returned by Fetch, FetchWithOptions, FetchMultiple and
+// FetchMultipleWithOptions in history.
+func RecordHistory(f Fetcher, history *History) Fetcher {
+	return &historyFetcher{Fetcher: f, history: history}
+}
+
+// WithGuard implements Fetcher.
+func (f *historyFetcher) WithGuard(guard URLGuard) Fetcher {
+	return &historyFetcher{Fetcher: f.Fetcher.WithGuard(guard), history: f.history}
+}
+
+// Fetch implements Fetcher.
+func (f *historyFetcher) Fetch(urlStr string, maxLength int, startIndex int, raw bool) (*types.FetchResponse, error) {
+	resp, err := f.Fetcher.Fetch(urlStr, maxLength, startIndex, raw)
+	f.recordResponse(resp)
+	return resp, err
+}
+
+// FetchWithOptions implements Fetcher.
+func (f *historyFetcher) FetchWithOptions(urlStr string, opts FetchOptions) (*types.FetchResponse, error) {
+	resp, err := f.Fetcher.FetchWithOptions(urlStr, opts)
+	f.recordResponse(resp)
+	return resp, err
+}
+
+// FetchMultiple implements Fetcher.
+func (f *historyFetcher) FetchMultiple(urls []string, maxLength int, raw bool) (*types.MultipleFetchResponse, error) {
+	resp, err := f.Fetcher.FetchMultiple(urls, maxLength, raw)
+	f.recordResults(resp)
+	return resp, err
+}
+
+// FetchMultipleWithOptions implements Fetcher.
+func (f *historyFetcher) FetchMultipleWithOptions(ctx context.Context, urls []string, opts MultipleFetchOptions) (*types.MultipleFetchResponse, error) {
+	resp, err := f.Fetcher.FetchMultipleWithOptions(ctx, urls, opts)
+	f.recordResults(resp)
+	return resp, err
+}
+
+// recordResponse records the document of a fetch, if it succeeded and
+// returned content.
+func (f *historyFetcher) recordResponse(resp *types.FetchResponse) {
+	if resp == nil || resp.NotModified {
+		return
+	}
+	f.history.record(types.HistoryEntry{
+		URL:         resp.URL,
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.ContentType,
+		Format:      resp.Format,
+		TotalTokens: resp.TotalTokens,
+		Flags:       resp.Flags,
+		FetchedAt:   time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// recordResults records the documents of a multiple fetch that succeeded.
+func (f *historyFetcher) recordResults(resp *types.MultipleFetchResponse) {
+	if resp == nil {
+		return
+	}
+	fetchedAt := time.Now().UTC().Format(time.RFC3339)
+	for _, result := range resp.Results {
+		if result.Error != nil {
+			continue
+		}
+		f.history.record(types.HistoryEntry{
+			URL:         result.URL,
+			StatusCode:  result.StatusCode,
+			ContentType: result.ContentType,
+			Format:      result.Format,
+			TotalTokens: result.TotalTokens,
+			Flags:       result.Flags,
+			FetchedAt:   fetchedAt,
+		})
+	}
+}
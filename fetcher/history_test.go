@@ -0,0 +1,55 @@
+package fetcher
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/cnosuke/mcp-fetch/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	history := NewHistory(3)
+	for i := 1; i <= 4; i++ {
+		history.record(types.HistoryEntry{URL: fmt.Sprintf("https://example.com/%d", i)})
+	}
+	// A document fetched again moves to the front
+	history.record(types.HistoryEntry{URL: "https://example.com/3", StatusCode: 200})
+
+	entries := history.Entries()
+	require.Len(t, entries, 3)
+	assert.Equal(t, "https://example.com/3", entries[0].URL)
+	assert.Equal(t, 200, entries[0].StatusCode)
+	assert.Equal(t, "https://example.com/4", entries[1].URL)
+	assert.Equal(t, "https://example.com/2", entries[2].URL)
+
+	assert.Equal(t, DefaultHistorySize, NewHistory(0).size)
+}
+
+func TestRecordHistory(t *testing.T) {
+	server := startMockServer(t, map[string]mockResponse{
+		"/a": {Body: "<html><head><title>A</title></head><body><p>Page A</p></body></html>", ContentType: "text/html", StatusCode: http.StatusOK},
+		"/b": {Body: "Page B", ContentType: "text/plain", StatusCode: http.StatusOK},
+	})
+	history := NewHistory(10)
+	fetcher := RecordHistory(newTestFetcherWithTokenizer(t, "heuristic"), history)
+
+	_, err := fetcher.Fetch(server.URL+"/a", 100, 0, false)
+	require.NoError(t, err)
+	_, err = fetcher.FetchMultiple([]string{server.URL + "/b", "ftp://example.com/"}, 100, false)
+	require.NoError(t, err)
+	_, err = fetcher.FetchOutline(server.URL + "/a")
+	require.NoError(t, err)
+
+	// Failed URLs and other tools are not recorded
+	entries := history.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, server.URL+"/b", entries[0].URL)
+	assert.Equal(t, "text/plain", entries[0].ContentType)
+	assert.Equal(t, server.URL+"/a", entries[1].URL)
+	assert.Equal(t, "markdown", entries[1].Format)
+	assert.Equal(t, http.StatusOK, entries[1].StatusCode)
+	assert.NotEmpty(t, entries[1].FetchedAt)
+}
@@ -0,0 +1,42 @@
+package fetcher
+
+import (
+	"strings"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+)
+
+// HTTP error policies, deciding how 4xx and 5xx responses are returned.
+const (
+	// HTTPErrorsContent returns the page like any other response (the default).
+	HTTPErrorsContent = "content"
+	// HTTPErrorsExcerpt returns only the beginning of the processed page.
+	HTTPErrorsExcerpt = "excerpt"
+	// HTTPErrorsError fails with an ierrors.ErrHTTPStatus error.
+	HTTPErrorsError = "error"
+)
+
+// HTTPErrorPolicies lists every supported HTTP error policy.
+var HTTPErrorPolicies = []string{HTTPErrorsContent, HTTPErrorsExcerpt, HTTPErrorsError}
+
+// HTTPErrorExcerptLength is the number of bytes of processed content kept by
+// HTTPErrorsExcerpt: enough for the error message of a typical error page.
+const HTTPErrorExcerptLength = 500
+
+// ParseHTTPErrorPolicy validates an HTTP error policy name. An empty name
+// selects HTTPErrorsContent.
+func ParseHTTPErrorPolicy(name string) (string, error) {
+	switch policy := strings.ToLower(strings.TrimSpace(name)); policy {
+	case "":
+		return HTTPErrorsContent, nil
+	case HTTPErrorsContent, HTTPErrorsExcerpt, HTTPErrorsError:
+		return policy, nil
+	}
+	return "", ierrors.Newf(ierrors.ErrInvalidArgument, "unsupported http_errors policy %q (expected %s, %s or %s)",
+		name, HTTPErrorsContent, HTTPErrorsExcerpt, HTTPErrorsError)
+}
+
+// isHTTPError reports whether status is a client or server error.
+func isHTTPError(status int) bool {
+	return status >= 400
+}
@@ -0,0 +1,87 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHTTPErrorPolicy(t *testing.T) {
+	policy, err := ParseHTTPErrorPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, HTTPErrorsContent, policy)
+
+	policy, err = ParseHTTPErrorPolicy(" Excerpt ")
+	require.NoError(t, err)
+	assert.Equal(t, HTTPErrorsExcerpt, policy)
+
+	_, err = ParseHTTPErrorPolicy("ignore")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ierrors.ErrInvalidArgument))
+}
+
+func TestHTTPFetcher_HTTPErrors(t *testing.T) {
+	errorPage := "<html><head><title>Server Error</title></head><body><h1>Something went wrong</h1><p>" +
+		strings.Repeat("Details of the failure. ", 100) + "</p></body></html>"
+	server := startMockServer(t, map[string]mockResponse{
+		"/ok":    {Body: "<html><body><h1>Hello</h1><p>World</p></body></html>", ContentType: "text/html", StatusCode: http.StatusOK},
+		"/error": {Body: errorPage, ContentType: "text/html", StatusCode: http.StatusInternalServerError},
+	})
+	fetcher := newTestFetcherWithTokenizer(t, "heuristic")
+
+	// The default policy returns the page, flagged
+	resp, err := fetcher.FetchWithOptions(server.URL+"/error", FetchOptions{MaxLength: 10000})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Greater(t, len(resp.Content), HTTPErrorExcerptLength)
+	assert.Equal(t, []string{FlagHTTPError}, resp.Flags)
+
+	resp, err = fetcher.FetchWithOptions(server.URL+"/error", FetchOptions{MaxLength: 10000, HTTPErrors: HTTPErrorsExcerpt})
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(resp.Content), HTTPErrorExcerptLength)
+	assert.Contains(t, resp.Content, "Something went wrong")
+
+	_, err = fetcher.FetchWithOptions(server.URL+"/error", FetchOptions{HTTPErrors: HTTPErrorsError})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ierrors.ErrHTTPStatus))
+	assert.True(t, ierrors.IsRetryable(err))
+
+	// Successful responses are unaffected
+	resp, err = fetcher.FetchWithOptions(server.URL+"/ok", FetchOptions{HTTPErrors: HTTPErrorsError})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Flags)
+
+	_, err = fetcher.FetchWithOptions(server.URL+"/ok", FetchOptions{HTTPErrors: "ignore"})
+	require.Error(t, err)
+}
+
+func TestHTTPFetcher_FetchMultiple_HTTPErrors(t *testing.T) {
+	server := startMockServer(t, map[string]mockResponse{
+		"/ok": {Body: "<html><body><h1>Hello</h1><p>World</p></body></html>", ContentType: "text/html", StatusCode: http.StatusOK},
+	})
+	fetcher := newTestFetcherWithTokenizer(t, "heuristic")
+	urls := []string{server.URL + "/ok", server.URL + "/missing"}
+
+	resp, err := fetcher.FetchMultipleWithOptions(context.Background(), urls, MultipleFetchOptions{MaxLength: 1000})
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.Succeeded)
+	assert.Equal(t, "Not Found", resp.Results[1].Content)
+	assert.Equal(t, []string{FlagHTTPError}, resp.Results[1].Flags)
+
+	resp, err = fetcher.FetchMultipleWithOptions(context.Background(), urls, MultipleFetchOptions{MaxLength: 1000, HTTPErrors: HTTPErrorsError})
+	require.NoError(t, err)
+	assert.Equal(t, 1, resp.Succeeded)
+	missing := resp.Results[1]
+	assert.Equal(t, http.StatusNotFound, missing.StatusCode)
+	assert.Empty(t, missing.Content)
+	require.NotNil(t, missing.Error)
+	assert.Equal(t, "http_status", missing.Error.Code)
+	assert.False(t, missing.Error.Retryable)
+	assert.Equal(t, fetchStageRequest, missing.Error.Stage)
+}
@@ -0,0 +1,222 @@
+package fetcher
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+)
+
+// LinkOptions controls which anchors FetchLinks returns.
+type LinkOptions struct {
+	Pattern  string // Regular expression matched against the absolute URL
+	SameHost bool   // Only return links pointing to the page's host
+	Unique   bool   // Drop links whose URL (without fragment) was already returned
+}
+
+// sectionTags maps landmark elements and ARIA roles to the section name reported for links.
+var sectionTags = map[string]string{
+	"nav":           "nav",
+	"header":        "header",
+	"footer":        "footer",
+	"aside":         "aside",
+	"main":          "main",
+	"article":       "main",
+	"navigation":    "nav",
+	"banner":        "header",
+	"contentinfo":   "footer",
+	"complementary": "aside",
+}
+
+// FetchLinks fetches a page and returns its anchors as structured data.
+func (f *httpFetcher) FetchLinks(urlStr string, opts LinkOptions) (*types.LinksResponse, error) {
+	zap.S().Debugw("fetching links",
+		"url", urlStr,
+		"pattern", opts.Pattern,
+		"same_host", opts.SameHost,
+		"unique", opts.Unique)
+
+	var pattern *regexp.Regexp
+	if opts.Pattern != "" {
+		var err error
+		pattern, err = regexp.Compile(opts.Pattern)
+		if err != nil {
+			return nil, ierrors.WithKind(ierrors.Wrap(err, "invalid pattern"), ierrors.ErrInvalidArgument)
+		}
+	}
+
+	resp := f.fetch(urlStr)
+	if resp.err != nil {
+		return nil, resp.err
+	}
+	if !strings.Contains(resp.contentType, "text/html") {
+		return nil, fmt.Errorf("content type %q is not HTML", resp.contentType)
+	}
+
+	links, err := extractLinks(resp.body, resp.url)
+	if err != nil {
+		return nil, err
+	}
+	total := len(links)
+	links = filterLinks(links, pattern, opts.SameHost, opts.Unique)
+
+	zap.S().Debugw("extracted links",
+		"url", urlStr,
+		"total", total,
+		"returned", len(links))
+
+	return &types.LinksResponse{
+		URL:         urlStr,
+		StatusCode:  resp.status,
+		OriginalURL: resp.originalURL,
+		Links:       links,
+		Total:       total,
+	}, nil
+}
+
+// extractLinks parses an HTML document and returns every anchor with an href,
+// resolved against pageURL (or the document's <base href> if present). Links
+// are on the same host if they share the host of pageURL, whatever the base.
+func extractLinks(body string, pageURL string) ([]types.Link, error) {
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to parse HTML")
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to parse page URL")
+	}
+	pageHost := strings.ToLower(base.Hostname())
+	if href := findBaseHref(doc); href != "" {
+		if b, err := base.Parse(href); err == nil {
+			base = b
+		}
+	}
+
+	links := []types.Link{}
+	var walk func(n *html.Node, section string)
+	walk = func(n *html.Node, section string) {
+		if n.Type == html.ElementNode {
+			if s, ok := sectionTags[n.Data]; ok {
+				section = s
+			} else if s, ok := sectionTags[getAttr(n, "role")]; ok {
+				section = s
+			}
+
+			if n.Data == "a" {
+				if link, ok := buildLink(n, base, pageHost, section); ok {
+					links = append(links, link)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, section)
+		}
+	}
+	walk(doc, "body")
+
+	return links, nil
+}
+
+// buildLink converts an <a> element into a Link. It reports false for anchors
+// without a usable href.
+func buildLink(n *html.Node, base *url.URL, pageHost string, section string) (types.Link, bool) {
+	href := strings.TrimSpace(getAttr(n, "href"))
+	if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return types.Link{}, false
+	}
+	u, err := base.Parse(href)
+	if err != nil {
+		return types.Link{}, false
+	}
+
+	text := collapseWhitespace(nodeText(n))
+	if text == "" {
+		// Fall back to accessible names for icon links
+		text = getAttr(n, "aria-label")
+		if text == "" {
+			text = getAttr(n, "title")
+		}
+	}
+
+	return types.Link{
+		URL:      u.String(),
+		Text:     text,
+		Rel:      getAttr(n, "rel"),
+		SameHost: strings.ToLower(u.Hostname()) == pageHost,
+		Section:  section,
+	}, true
+}
+
+// filterLinks applies the pattern, same-host and de-duplication filters.
+func filterLinks(links []types.Link, pattern *regexp.Regexp, sameHost bool, unique bool) []types.Link {
+	seen := make(map[string]bool)
+	filtered := []types.Link{}
+	for _, link := range links {
+		if sameHost && !link.SameHost {
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(link.URL) {
+			continue
+		}
+		if unique {
+			key, _, _ := strings.Cut(link.URL, "#")
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		filtered = append(filtered, link)
+	}
+	return filtered
+}
+
+// findBaseHref returns the href of the first <base> element, if any.
+func findBaseHref(n *html.Node) string {
+	if n.Type == html.ElementNode && n.Data == "base" {
+		return getAttr(n, "href")
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if href := findBaseHref(c); href != "" {
+			return href
+		}
+	}
+	return ""
+}
+
+// getAttr returns the value of the named attribute, or an empty string.
+func getAttr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// nodeText returns the concatenated text content of a node and its descendants.
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// collapseWhitespace trims s and replaces runs of whitespace with a single space.
+func collapseWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
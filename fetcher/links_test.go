@@ -0,0 +1,109 @@
+package fetcher
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const linksPageHTML = `<html><head><title>Links</title></head><body>
+<header><a href="/">Home</a></header>
+<nav role="navigation"><a href="/docs">Docs</a><a href="/docs#intro">Docs intro</a></nav>
+<main>
+  <p>See <a href="https://other.example.org/page" rel="nofollow">the other page</a>.</p>
+  <a href="guide/start"><img alt="icon"></a>
+  <a href="javascript:void(0)">Ignored</a>
+  <div role="contentinfo"><a href="mailto:team@example.com" aria-label="Mail us"></a></div>
+</main>
+<footer><a href="/legal">Legal</a></footer>
+</body></html>`
+
+func TestExtractLinks(t *testing.T) {
+	links, err := extractLinks(linksPageHTML, "https://example.com/base/index.html")
+	require.NoError(t, err)
+	require.Len(t, links, 7)
+
+	assert.Equal(t, "https://example.com/", links[0].URL)
+	assert.Equal(t, "Home", links[0].Text)
+	assert.Equal(t, "header", links[0].Section)
+	assert.True(t, links[0].SameHost)
+
+	assert.Equal(t, "https://example.com/docs", links[1].URL)
+	assert.Equal(t, "nav", links[1].Section)
+
+	assert.Equal(t, "https://other.example.org/page", links[3].URL)
+	assert.Equal(t, "the other page", links[3].Text)
+	assert.Equal(t, "nofollow", links[3].Rel)
+	assert.Equal(t, "main", links[3].Section)
+	assert.False(t, links[3].SameHost)
+
+	// Relative links are resolved against the page URL
+	assert.Equal(t, "https://example.com/base/guide/start", links[4].URL)
+
+	// Empty anchor text falls back to aria-label
+	assert.Equal(t, "Mail us", links[5].Text)
+	assert.Equal(t, "footer", links[5].Section)
+
+	assert.Equal(t, "footer", links[6].Section)
+}
+
+func TestExtractLinks_BaseHref(t *testing.T) {
+	body := `<html><head><base href="https://cdn.example.com/root/"></head><body><a href="a.html">A</a><a href="https://example.com/about">About</a></body></html>`
+	links, err := extractLinks(body, "https://example.com/page")
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+
+	// The base only resolves relative links: the host is the page's
+	assert.Equal(t, "https://cdn.example.com/root/a.html", links[0].URL)
+	assert.False(t, links[0].SameHost)
+	assert.Equal(t, "https://example.com/about", links[1].URL)
+	assert.True(t, links[1].SameHost)
+}
+
+func TestHTTPFetcher_FetchLinks_Filters(t *testing.T) {
+	mockResponses := map[string]mockResponse{
+		"/links": {Body: linksPageHTML, ContentType: "text/html; charset=utf-8", StatusCode: http.StatusOK},
+		"/plain": {Body: "no links here", ContentType: "text/plain", StatusCode: http.StatusOK},
+	}
+	server := startMockServer(t, mockResponses)
+	fetcher := newTestFetcher(t, server.URL)
+
+	// No filters
+	resp, err := fetcher.FetchLinks(server.URL+"/links", LinkOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 7, resp.Total)
+	assert.Len(t, resp.Links, 7)
+
+	// Same host and de-duplication
+	resp, err = fetcher.FetchLinks(server.URL+"/links", LinkOptions{SameHost: true, Unique: true})
+	require.NoError(t, err)
+	assert.Equal(t, 7, resp.Total)
+	urls := []string{}
+	for _, l := range resp.Links {
+		urls = append(urls, l.URL)
+	}
+	assert.Equal(t, []string{
+		server.URL + "/",
+		server.URL + "/docs",
+		server.URL + "/guide/start",
+		server.URL + "/legal",
+	}, urls)
+
+	// Pattern
+	resp, err = fetcher.FetchLinks(server.URL+"/links", LinkOptions{Pattern: `/docs`})
+	require.NoError(t, err)
+	assert.Len(t, resp.Links, 2)
+
+	// Invalid pattern
+	_, err = fetcher.FetchLinks(server.URL+"/links", LinkOptions{Pattern: `(`})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid pattern")
+
+	// Non-HTML content
+	_, err = fetcher.FetchLinks(server.URL+"/plain", LinkOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not HTML")
+}
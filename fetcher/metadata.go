@@ -0,0 +1,268 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+)
+
+// jsonLDTypes lists the schema.org types kept from JSON-LD blocks.
+var jsonLDTypes = map[string]bool{
+	"Article":          true,
+	"NewsArticle":      true,
+	"BlogPosting":      true,
+	"TechArticle":      true,
+	"ScholarlyArticle": true,
+	"Product":          true,
+	"Recipe":           true,
+	"FAQPage":          true,
+	"BreadcrumbList":   true,
+}
+
+// feedTypes lists the MIME types recognized as feeds in <link rel="alternate">.
+var feedTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/json":      true,
+}
+
+// extractMetadata parses an HTML document and collects its metadata.
+// Relative URLs are resolved against pageURL.
+func extractMetadata(body string, pageURL string) (*types.PageMetadata, error) {
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to parse HTML")
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to parse page URL")
+	}
+
+	meta := &types.PageMetadata{
+		OpenGraph: make(map[string]string),
+		Twitter:   make(map[string]string),
+	}
+	named := make(map[string]string) // <meta name|itemprop|http-equiv> values, lower-cased keys
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "html":
+				meta.Language = strings.TrimSpace(getAttr(n, "lang"))
+			case "title":
+				if meta.Title == "" {
+					meta.Title = collapseWhitespace(nodeText(n))
+				}
+			case "meta":
+				collectMetaTag(n, meta, named)
+			case "link":
+				collectLinkTag(n, base, meta)
+			case "script":
+				if strings.EqualFold(strings.TrimSpace(getAttr(n, "type")), "application/ld+json") {
+					meta.JSONLD = append(meta.JSONLD, parseJSONLD(nodeText(n))...)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if meta.Language == "" {
+		meta.Language = named["content-language"]
+	}
+	meta.Description = firstNonEmpty(named["description"], meta.OpenGraph["description"], meta.Twitter["description"])
+	meta.Author = firstNonEmpty(named["author"], meta.OpenGraph["article:author"], jsonLDString(meta.JSONLD, "author"))
+	meta.PublishedTime = firstNonEmpty(
+		meta.OpenGraph["article:published_time"],
+		named["datepublished"],
+		named["date"],
+		named["dcterms.created"],
+		jsonLDString(meta.JSONLD, "datePublished"),
+	)
+	meta.ModifiedTime = firstNonEmpty(
+		meta.OpenGraph["article:modified_time"],
+		meta.OpenGraph["updated_time"],
+		named["datemodified"],
+		named["last-modified"],
+		named["dcterms.modified"],
+		jsonLDString(meta.JSONLD, "dateModified"),
+	)
+	if meta.CanonicalURL == "" {
+		meta.CanonicalURL = meta.OpenGraph["url"]
+	}
+
+	if len(meta.OpenGraph) == 0 {
+		meta.OpenGraph = nil
+	}
+	if len(meta.Twitter) == 0 {
+		meta.Twitter = nil
+	}
+
+	zap.S().Debugw("extracted page metadata",
+		"url", pageURL,
+		"canonical_url", meta.CanonicalURL,
+		"open_graph_fields", len(meta.OpenGraph),
+		"twitter_fields", len(meta.Twitter),
+		"json_ld_items", len(meta.JSONLD),
+		"feeds", len(meta.Feeds))
+
+	return meta, nil
+}
+
+// collectMetaTag records a <meta> element. OpenGraph and article:* properties go
+// into meta.OpenGraph, twitter:* into meta.Twitter and everything else into named.
+func collectMetaTag(n *html.Node, meta *types.PageMetadata, named map[string]string) {
+	content := strings.TrimSpace(getAttr(n, "content"))
+	if content == "" {
+		return
+	}
+
+	key := strings.ToLower(strings.TrimSpace(getAttr(n, "property")))
+	if key == "" {
+		key = strings.ToLower(strings.TrimSpace(getAttr(n, "name")))
+	}
+
+	switch {
+	case strings.HasPrefix(key, "og:"):
+		setIfEmpty(meta.OpenGraph, strings.TrimPrefix(key, "og:"), content)
+	case strings.HasPrefix(key, "article:"):
+		setIfEmpty(meta.OpenGraph, key, content)
+	case strings.HasPrefix(key, "twitter:"):
+		setIfEmpty(meta.Twitter, strings.TrimPrefix(key, "twitter:"), content)
+	case key != "":
+		setIfEmpty(named, key, content)
+	default:
+		if itemprop := strings.ToLower(getAttr(n, "itemprop")); itemprop != "" {
+			setIfEmpty(named, itemprop, content)
+		} else if equiv := strings.ToLower(getAttr(n, "http-equiv")); equiv != "" {
+			setIfEmpty(named, equiv, content)
+		}
+	}
+}
+
+// collectLinkTag records canonical and feed <link> elements.
+func collectLinkTag(n *html.Node, base *url.URL, meta *types.PageMetadata) {
+	href := strings.TrimSpace(getAttr(n, "href"))
+	if href == "" {
+		return
+	}
+	u, err := base.Parse(href)
+	if err != nil {
+		return
+	}
+
+	for _, rel := range strings.Fields(strings.ToLower(getAttr(n, "rel"))) {
+		switch rel {
+		case "canonical":
+			if meta.CanonicalURL == "" {
+				meta.CanonicalURL = u.String()
+			}
+		case "alternate":
+			linkType := strings.ToLower(strings.TrimSpace(getAttr(n, "type")))
+			if feedTypes[linkType] {
+				meta.Feeds = append(meta.Feeds, types.FeedLink{
+					URL:   u.String(),
+					Type:  linkType,
+					Title: strings.TrimSpace(getAttr(n, "title")),
+				})
+			}
+		}
+	}
+}
+
+// parseJSONLD decodes a JSON-LD script body and returns the items whose @type
+// is one of jsonLDTypes. Top-level arrays and @graph containers are flattened.
+func parseJSONLD(raw string) []map[string]any {
+	var decoded any
+	if err := json.Unmarshal([]byte(strings.TrimSpace(raw)), &decoded); err != nil {
+		zap.S().Debugw("ignoring invalid JSON-LD block", "error", err)
+		return nil
+	}
+
+	var items []map[string]any
+	var collect func(v any)
+	collect = func(v any) {
+		switch val := v.(type) {
+		case []any:
+			for _, item := range val {
+				collect(item)
+			}
+		case map[string]any:
+			if graph, ok := val["@graph"]; ok {
+				collect(graph)
+			}
+			if hasJSONLDType(val["@type"]) {
+				items = append(items, val)
+			}
+		}
+	}
+	collect(decoded)
+	return items
+}
+
+// hasJSONLDType reports whether an @type value (string or array) contains a supported type.
+func hasJSONLDType(v any) bool {
+	switch t := v.(type) {
+	case string:
+		return jsonLDTypes[t]
+	case []any:
+		for _, item := range t {
+			if s, ok := item.(string); ok && jsonLDTypes[s] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonLDString returns the first string value of key found in items.
+// Objects with a "name" field (e.g. author Person) are reduced to that name.
+func jsonLDString(items []map[string]any, key string) string {
+	for _, item := range items {
+		switch v := item[key].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case map[string]any:
+			if name, ok := v["name"].(string); ok && name != "" {
+				return name
+			}
+		case []any:
+			for _, elem := range v {
+				if obj, ok := elem.(map[string]any); ok {
+					if name, ok := obj["name"].(string); ok && name != "" {
+						return name
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// setIfEmpty stores value under key unless the key is already set.
+func setIfEmpty(m map[string]string, key, value string) {
+	if _, ok := m[key]; !ok {
+		m[key] = value
+	}
+}
+
+// firstNonEmpty returns the first non-empty string in values.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package fetcher
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const metadataPageHTML = `<!DOCTYPE html>
+<html lang="en-US"><head>
+<title>Release notes</title>
+<meta name="description" content="What changed in v2.">
+<meta name="author" content="Jane Doe">
+<link rel="canonical" href="/blog/v2">
+<link rel="alternate" type="application/rss+xml" title="Blog feed" href="/feed.xml">
+<link rel="alternate" hreflang="de" href="/de/blog/v2">
+<meta property="og:title" content="Release notes v2">
+<meta property="og:type" content="article">
+<meta property="article:published_time" content="2024-05-01T10:00:00Z">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="twitter:site" content="@example">
+<script type="application/ld+json">
+{"@context":"https://schema.org","@graph":[
+  {"@type":"BlogPosting","headline":"Release notes v2","dateModified":"2024-05-03","author":{"@type":"Person","name":"Jane Doe"}},
+  {"@type":"WebSite","name":"Example"},
+  {"@type":"BreadcrumbList","itemListElement":[{"@type":"ListItem","position":1,"name":"Blog"}]}
+]}
+</script>
+<script type="application/ld+json">{ not json }</script>
+</head><body><p>Hello</p></body></html>`
+
+func TestExtractMetadata(t *testing.T) {
+	meta, err := extractMetadata(metadataPageHTML, "https://example.com/blog/v2?ref=home")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Release notes", meta.Title)
+	assert.Equal(t, "What changed in v2.", meta.Description)
+	assert.Equal(t, "https://example.com/blog/v2", meta.CanonicalURL)
+	assert.Equal(t, "en-US", meta.Language)
+	assert.Equal(t, "Jane Doe", meta.Author)
+	assert.Equal(t, "2024-05-01T10:00:00Z", meta.PublishedTime)
+	assert.Equal(t, "2024-05-03", meta.ModifiedTime) // From JSON-LD
+
+	assert.Equal(t, "Release notes v2", meta.OpenGraph["title"])
+	assert.Equal(t, "article", meta.OpenGraph["type"])
+	assert.Equal(t, "summary_large_image", meta.Twitter["card"])
+	assert.Equal(t, "@example", meta.Twitter["site"])
+
+	// WebSite is not a supported JSON-LD type and the invalid block is skipped
+	require.Len(t, meta.JSONLD, 2)
+	assert.Equal(t, "BlogPosting", meta.JSONLD[0]["@type"])
+	assert.Equal(t, "BreadcrumbList", meta.JSONLD[1]["@type"])
+
+	require.Len(t, meta.Feeds, 1)
+	assert.Equal(t, "https://example.com/feed.xml", meta.Feeds[0].URL)
+	assert.Equal(t, "application/rss+xml", meta.Feeds[0].Type)
+	assert.Equal(t, "Blog feed", meta.Feeds[0].Title)
+}
+
+func TestExtractMetadata_Fallbacks(t *testing.T) {
+	body := `<html><head>
+<meta http-equiv="Content-Language" content="ja">
+<meta property="og:url" content="https://example.com/canonical">
+<meta property="og:description" content="OG description">
+<meta itemprop="datePublished" content="2023-01-02">
+</head><body></body></html>`
+	meta, err := extractMetadata(body, "https://example.com/page")
+	require.NoError(t, err)
+
+	assert.Equal(t, "ja", meta.Language)
+	assert.Equal(t, "https://example.com/canonical", meta.CanonicalURL)
+	assert.Equal(t, "OG description", meta.Description)
+	assert.Equal(t, "2023-01-02", meta.PublishedTime)
+	assert.Nil(t, meta.Twitter)
+	assert.Empty(t, meta.JSONLD)
+}
+
+func TestHTTPFetcher_FetchWithOptions_IncludeMetadata(t *testing.T) {
+	mockResponses := map[string]mockResponse{
+		"/page":  {Body: metadataPageHTML, ContentType: "text/html", StatusCode: http.StatusOK},
+		"/plain": {Body: "plain", ContentType: "text/plain", StatusCode: http.StatusOK},
+	}
+	server := startMockServer(t, mockResponses)
+	fetcher := newTestFetcher(t, server.URL)
+
+	// Metadata is omitted unless requested
+	resp, err := fetcher.FetchWithOptions(server.URL+"/page", FetchOptions{MaxLength: 100})
+	require.NoError(t, err)
+	assert.Nil(t, resp.Metadata)
+
+	resp, err = fetcher.FetchWithOptions(server.URL+"/page", FetchOptions{MaxLength: 100, IncludeMetadata: true})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Metadata)
+	assert.Equal(t, server.URL+"/blog/v2", resp.Metadata.CanonicalURL)
+
+	// Non-HTML content has no metadata
+	resp, err = fetcher.FetchWithOptions(server.URL+"/plain", FetchOptions{IncludeMetadata: true})
+	require.NoError(t, err)
+	assert.Nil(t, resp.Metadata)
+}
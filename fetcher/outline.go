@@ -0,0 +1,395 @@
+package fetcher
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+)
+
+var (
+	// markdownHeadingPattern matches ATX headings, without an optional closing sequence.
+	markdownHeadingPattern = regexp.MustCompile(`^(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+	// markdownLinkPattern matches inline links and images so only their text is kept.
+	markdownLinkPattern = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+)
+
+// headingAnchorSymbols are permalink markers commonly appended to headings.
+// A "#" marker is only removed when separated by a space, to keep names like "C#".
+const headingAnchorSymbols = "¶§🔗"
+
+// htmlHeading is a heading found in the source HTML, used to recover anchor IDs
+// that are lost in the Markdown conversion.
+type htmlHeading struct {
+	text string
+	id   string
+}
+
+// FetchOutline fetches a page and returns its heading tree with offsets into
+// the Markdown content returned by Fetch.
+func (f *httpFetcher) FetchOutline(urlStr string) (*types.OutlineResponse, error) {
+	zap.S().Debugw("fetching outline", "url", urlStr)
+
+	resp := f.fetch(urlStr)
+	if resp.err != nil {
+		return nil, resp.err
+	}
+	content, sourceHTML, err := markdownContent(resp, urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	headings := documentOutline(content, sourceHTML)
+
+	zap.S().Debugw("built document outline",
+		"url", urlStr,
+		"headings", len(headings),
+		"content_length", len(content))
+
+	return &types.OutlineResponse{
+		URL:           urlStr,
+		StatusCode:    resp.status,
+		OriginalURL:   resp.originalURL,
+		ContentLength: len(content),
+		Headings:      nestHeadings(headings),
+	}, nil
+}
+
+// markdownContent returns the Markdown content fetch produces by default for
+// an HTML or Markdown response, along with the source HTML, if any.
+func markdownContent(resp *fetchResponse, urlStr string) (string, string, error) {
+	switch {
+	case strings.Contains(resp.contentType, "text/html"):
+		return processHTMLContent(resp.body, urlStr), resp.body, nil
+	case isMarkdownType(resp.contentType):
+		return resp.body, "", nil
+	}
+	return "", "", ierrors.Newf(ierrors.ErrInvalidArgument, "content type %q is not HTML or Markdown", resp.contentType)
+}
+
+// scopeToSection narrows processed content to a single section. The section
+// comes from opts.Section or, failing that, from the URL fragment.
+// An unknown fragment leaves the content untouched, since fragments often
+// address things other than headings; an unknown explicit section is an error.
+// It returns the scoped content and the anchor of the selected heading.
+func scopeToSection(content string, resp *fetchResponse, urlStr string, opts FetchOptions) (string, string, error) {
+	section := opts.Section
+	explicit := section != ""
+	if !explicit {
+		if u, err := url.Parse(urlStr); err == nil {
+			section = u.Fragment
+		}
+	}
+	if section == "" {
+		return content, "", nil
+	}
+
+	if !hasMarkdownOutput(resp, opts) {
+		if explicit {
+			return "", "", ierrors.Newf(ierrors.ErrInvalidArgument, "section requires Markdown content, got %q", resp.contentType)
+		}
+		return content, "", nil
+	}
+
+	sourceHTML := ""
+	if strings.Contains(resp.contentType, "text/html") {
+		sourceHTML = resp.body
+	}
+	headings := documentOutline(content, sourceHTML)
+	heading := findSection(headings, section)
+	if heading == nil {
+		if explicit {
+			return "", "", ierrors.Newf(ierrors.ErrInvalidArgument, "section %q not found", section)
+		}
+		zap.S().Debugw("URL fragment does not match a section, returning full content",
+			"url", urlStr,
+			"fragment", section)
+		return content, "", nil
+	}
+
+	zap.S().Debugw("scoped content to section",
+		"url", urlStr,
+		"section", section,
+		"anchor", heading.Anchor,
+		"offset", heading.Offset,
+		"length", heading.Length)
+
+	return content[heading.Offset : heading.Offset+heading.Length], heading.Anchor, nil
+}
+
+// hasMarkdownOutput reports whether processContent produced Markdown for resp.
+func hasMarkdownOutput(resp *fetchResponse, opts FetchOptions) bool {
+	if strings.Contains(resp.contentType, "text/html") {
+		return !opts.Raw && (opts.Format == "" || opts.Format == FormatMarkdown)
+	}
+	return isMarkdownType(resp.contentType)
+}
+
+// isMarkdownType reports whether a Content-Type header denotes Markdown.
+func isMarkdownType(contentType string) bool {
+	return strings.Contains(strings.ToLower(contentType), "markdown")
+}
+
+// documentOutline returns the headings of Markdown content in document order,
+// with offsets and section lengths. Anchors are taken from the matching
+// headings of sourceHTML when available and generated from the text otherwise.
+func documentOutline(content string, sourceHTML string) []*types.Heading {
+	headings := parseMarkdownHeadings(content)
+
+	var source []htmlHeading
+	if sourceHTML != "" {
+		var err error
+		source, err = collectHTMLHeadings(sourceHTML)
+		if err != nil {
+			zap.S().Warnw("failed to collect HTML headings, generating anchors", "error", err)
+		}
+	}
+	assignAnchors(headings, source)
+	return headings
+}
+
+// parseMarkdownHeadings finds the ATX headings of Markdown content, skipping
+// fenced code blocks. Each section extends to the next heading of the same or
+// a higher level.
+func parseMarkdownHeadings(content string) []*types.Heading {
+	headings := []*types.Heading{}
+	fence := ""
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		lineStart := offset
+		offset += len(line)
+
+		trimmed := strings.TrimRight(line, "\r\n")
+		if fence != "" {
+			if strings.HasPrefix(strings.TrimSpace(trimmed), fence) {
+				fence = ""
+			}
+			continue
+		}
+		if stripped := strings.TrimLeft(trimmed, " "); strings.HasPrefix(stripped, "```") || strings.HasPrefix(stripped, "~~~") {
+			fence = stripped[:3]
+			continue
+		}
+
+		m := markdownHeadingPattern.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+		text := headingText(markdownLinkPattern.ReplaceAllString(m[2], "$1"))
+		text = strings.NewReplacer("*", "", "`", "").Replace(text)
+		if text == "" {
+			continue
+		}
+		headings = append(headings, &types.Heading{
+			Level:  len(m[1]),
+			Text:   text,
+			Offset: lineStart,
+		})
+	}
+
+	for i, h := range headings {
+		end := len(content)
+		for _, next := range headings[i+1:] {
+			if next.Level <= h.Level {
+				end = next.Offset
+				break
+			}
+		}
+		h.Length = end - h.Offset
+	}
+	return headings
+}
+
+// collectHTMLHeadings returns the text and anchor ID of every h1-h6 element.
+func collectHTMLHeadings(body string) ([]htmlHeading, error) {
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to parse HTML")
+	}
+
+	headings := []htmlHeading{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && isHeadingTag(n.Data) {
+			headings = append(headings, htmlHeading{
+				text: headingText(nodeText(n)),
+				id:   headingID(n),
+			})
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return headings, nil
+}
+
+// isHeadingTag reports whether tag is h1-h6.
+func isHeadingTag(tag string) bool {
+	return len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6'
+}
+
+// headingID finds the anchor a heading can be linked with: its own id, an
+// id or name on an anchor inside it or directly before it, or the id of a
+// sectioning element it opens.
+func headingID(n *html.Node) string {
+	if id := strings.TrimSpace(getAttr(n, "id")); id != "" {
+		return id
+	}
+
+	var inner string
+	var walk func(c *html.Node)
+	walk = func(c *html.Node) {
+		for ; c != nil && inner == ""; c = c.NextSibling {
+			if c.Type == html.ElementNode {
+				if inner = anchorName(c); inner == "" {
+					walk(c.FirstChild)
+				}
+			}
+		}
+	}
+	walk(n.FirstChild)
+	if inner != "" {
+		return inner
+	}
+
+	prev := n.PrevSibling
+	for prev != nil && prev.Type == html.TextNode && strings.TrimSpace(prev.Data) == "" {
+		prev = prev.PrevSibling
+	}
+	if prev != nil && prev.Type == html.ElementNode && prev.Data == "a" && collapseWhitespace(nodeText(prev)) == "" {
+		if name := anchorName(prev); name != "" {
+			return name
+		}
+	}
+
+	if parent := n.Parent; parent != nil && firstElementChild(parent) == n {
+		switch parent.Data {
+		case "section", "article", "div":
+			return strings.TrimSpace(getAttr(parent, "id"))
+		}
+	}
+	return ""
+}
+
+// anchorName returns the id of an element, or the name of an <a> element.
+func anchorName(n *html.Node) string {
+	if id := strings.TrimSpace(getAttr(n, "id")); id != "" {
+		return id
+	}
+	if n.Data == "a" {
+		return strings.TrimSpace(getAttr(n, "name"))
+	}
+	return ""
+}
+
+// firstElementChild returns the first element child of n.
+func firstElementChild(n *html.Node) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			return c
+		}
+	}
+	return nil
+}
+
+// headingText normalizes heading text for display and matching.
+func headingText(s string) string {
+	s = strings.TrimRight(collapseWhitespace(s), headingAnchorSymbols+" ")
+	return strings.TrimSpace(strings.TrimSuffix(s, " #"))
+}
+
+// assignAnchors sets the anchor of each heading. Headings are matched in order
+// against the source HTML headings by text; unmatched headings and headings
+// without an ID get a slug of their text, made unique with a numeric suffix.
+func assignAnchors(headings []*types.Heading, source []htmlHeading) {
+	used := make(map[string]bool)
+	next := 0
+	for _, h := range headings {
+		for i := next; i < len(source); i++ {
+			if strings.EqualFold(source[i].text, h.Text) {
+				h.Anchor = source[i].id
+				next = i + 1
+				break
+			}
+		}
+		if h.Anchor == "" {
+			base := slugify(h.Text)
+			h.Anchor = base
+			for n := 1; used[h.Anchor]; n++ {
+				h.Anchor = base + "-" + strconv.Itoa(n)
+			}
+		}
+		used[h.Anchor] = true
+	}
+}
+
+// slugify converts heading text to a GitHub-style anchor: lower case, spaces
+// replaced by hyphens and punctuation removed.
+func slugify(text string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			sb.WriteRune(r)
+		case unicode.IsSpace(r):
+			sb.WriteRune('-')
+		}
+	}
+	return sb.String()
+}
+
+// findSection returns the heading addressed by section, which may be an
+// anchor (with or without a leading "#", possibly percent-encoded) or the
+// heading text. Exact anchor matches win over case-insensitive ones.
+func findSection(headings []*types.Heading, section string) *types.Heading {
+	section = strings.TrimPrefix(strings.TrimSpace(section), "#")
+	if unescaped, err := url.PathUnescape(section); err == nil {
+		section = unescaped
+	}
+	if section == "" {
+		return nil
+	}
+
+	matchers := []func(h *types.Heading) bool{
+		func(h *types.Heading) bool { return h.Anchor == section },
+		func(h *types.Heading) bool { return strings.EqualFold(h.Anchor, section) },
+		func(h *types.Heading) bool { return strings.EqualFold(h.Text, headingText(section)) },
+		func(h *types.Heading) bool { return h.Anchor == slugify(section) },
+	}
+	for _, match := range matchers {
+		for _, h := range headings {
+			if match(h) {
+				return h
+			}
+		}
+	}
+	return nil
+}
+
+// nestHeadings turns a flat heading list into a tree where each heading
+// contains the headings of its subsections.
+func nestHeadings(headings []*types.Heading) []*types.Heading {
+	root := []*types.Heading{}
+	stack := []*types.Heading{}
+	for _, h := range headings {
+		for len(stack) > 0 && stack[len(stack)-1].Level >= h.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			root = append(root, h)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, h)
+		}
+		stack = append(stack, h)
+	}
+	return root
+}
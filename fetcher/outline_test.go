@@ -0,0 +1,179 @@
+package fetcher
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var outlinePageHTML = `<html><head><title>API Guide</title></head><body><article><h1>API Guide</h1>` +
+	strings.Repeat("<p>This paragraph is long enough for readability to treat the page as an article body.</p>", 3) +
+	`<h2 id="authentication">Authentication <a href="#authentication">¶</a></h2>
+<p>Send a bearer token with every request. This paragraph is long enough to be kept.</p>
+<h3><a name="api-keys"></a>API <em>keys</em></h3>
+<p>Keys are created in the dashboard. This paragraph is long enough to be kept too.</p>
+<section id="errors"><h2>Errors</h2>
+<p>Errors are returned as JSON objects. This paragraph is long enough to be kept.</p></section>
+<pre><code># not a heading
+</code></pre>
+<p>Error codes are stable across versions. This paragraph is long enough to be kept.</p>
+<h2>Rate limits</h2>
+<p>Requests are limited per minute. This paragraph is long enough to be kept around.</p>
+</article></body></html>`
+
+func TestParseMarkdownHeadings(t *testing.T) {
+	content := "# Title\n\nIntro\n\n## First\n\nText\n\n```\n# code\n```\n\n### Sub *one*\n\n## [Second](#second) ##\n\nEnd"
+	headings := parseMarkdownHeadings(content)
+	require.Len(t, headings, 4)
+
+	assert.Equal(t, "Title", headings[0].Text)
+	assert.Equal(t, 0, headings[0].Offset)
+	assert.Equal(t, len(content), headings[0].Length)
+
+	assert.Equal(t, "First", headings[1].Text)
+	assert.Equal(t, 2, headings[1].Level)
+	assert.Equal(t, strings.Index(content, "## First"), headings[1].Offset)
+	// The section includes its subsection and stops at the next h2
+	assert.Equal(t, "## First\n\nText\n\n```\n# code\n```\n\n### Sub *one*\n\n",
+		content[headings[1].Offset:headings[1].Offset+headings[1].Length])
+
+	assert.Equal(t, "Sub one", headings[2].Text)
+	assert.Equal(t, "Second", headings[3].Text)
+}
+
+func TestDocumentOutline_Anchors(t *testing.T) {
+	content := processHTMLContent(outlinePageHTML, "https://example.com/api")
+	headings := documentOutline(content, outlinePageHTML)
+
+	anchors := []string{}
+	for _, h := range headings {
+		anchors = append(anchors, h.Anchor)
+	}
+	// Title heading and article h1 share their text, so the second slug gets a suffix
+	assert.Equal(t, []string{"api-guide", "api-guide-1", "authentication", "api-keys", "errors", "rate-limits"}, anchors)
+	assert.Equal(t, "Authentication", headings[2].Text)
+	assert.Equal(t, "API keys", headings[3].Text)
+
+	tree := nestHeadings(headings)
+	require.Len(t, tree, 2)
+	require.Len(t, tree[1].Children, 3)
+	assert.Equal(t, "api-keys", tree[1].Children[0].Children[0].Anchor)
+}
+
+func TestFindSection(t *testing.T) {
+	headings := []*types.Heading{
+		{Level: 2, Text: "Getting Started", Anchor: "intro"},
+		{Level: 2, Text: "Rate limits", Anchor: "rate-limits"},
+	}
+
+	tests := []struct {
+		section  string
+		expected string
+	}{
+		{section: "intro", expected: "intro"},
+		{section: "#Rate-Limits", expected: "rate-limits"},
+		{section: "getting started", expected: "intro"},
+		{section: "Rate%20limits", expected: "rate-limits"},
+		{section: "missing", expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.section, func(t *testing.T) {
+			h := findSection(headings, tt.section)
+			if tt.expected == "" {
+				assert.Nil(t, h)
+				return
+			}
+			require.NotNil(t, h)
+			assert.Equal(t, tt.expected, h.Anchor)
+		})
+	}
+}
+
+func TestHTTPFetcher_FetchWithOptions_Section(t *testing.T) {
+	mockResponses := map[string]mockResponse{
+		"/api":    {Body: outlinePageHTML, ContentType: "text/html", StatusCode: http.StatusOK},
+		"/readme": {Body: "# Readme\n\n## Install\n\nrun make\n\n## Usage\n\nrun it", ContentType: "text/markdown", StatusCode: http.StatusOK},
+		"/plain":  {Body: "plain", ContentType: "text/plain", StatusCode: http.StatusOK},
+	}
+	server := startMockServer(t, mockResponses)
+	fetcher := newTestFetcher(t, server.URL)
+
+	// Section argument by anchor includes subsections only
+	resp, err := fetcher.FetchWithOptions(server.URL+"/api", FetchOptions{Section: "authentication"})
+	require.NoError(t, err)
+	assert.Equal(t, "authentication", resp.Section)
+	assert.True(t, strings.HasPrefix(resp.Content, "## Authentication"))
+	assert.Contains(t, resp.Content, "API *keys*")
+	assert.NotContains(t, resp.Content, "Errors are returned")
+
+	// URL fragment, with trimming relative to the section
+	resp, err = fetcher.FetchWithOptions(server.URL+"/api#errors", FetchOptions{MaxLength: 9})
+	require.NoError(t, err)
+	assert.Equal(t, "errors", resp.Section)
+	assert.Equal(t, "## Errors", resp.Content)
+
+	// Section by heading text
+	resp, err = fetcher.FetchWithOptions(server.URL+"/api", FetchOptions{Section: "rate limits"})
+	require.NoError(t, err)
+	assert.Equal(t, "rate-limits", resp.Section)
+
+	// Unknown fragments fall back to the full content; unknown sections fail
+	resp, err = fetcher.FetchWithOptions(server.URL+"/api#top", FetchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Section)
+	assert.True(t, strings.HasPrefix(resp.Content, "# API Guide"))
+
+	_, err = fetcher.FetchWithOptions(server.URL+"/api", FetchOptions{Section: "missing"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `section "missing" not found`)
+	assert.ErrorIs(t, err, ierrors.ErrInvalidArgument)
+
+	// Markdown documents are sectioned as-is
+	resp, err = fetcher.FetchWithOptions(server.URL+"/readme#install", FetchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "## Install\n\nrun make\n\n", resp.Content)
+
+	// Sections need Markdown output
+	_, err = fetcher.FetchWithOptions(server.URL+"/api", FetchOptions{Section: "errors", Format: FormatText})
+	require.Error(t, err)
+	_, err = fetcher.FetchWithOptions(server.URL+"/plain", FetchOptions{Section: "errors"})
+	require.Error(t, err)
+}
+
+func TestHTTPFetcher_FetchOutline(t *testing.T) {
+	mockResponses := map[string]mockResponse{
+		"/api":   {Body: outlinePageHTML, ContentType: "text/html", StatusCode: http.StatusOK},
+		"/plain": {Body: "plain", ContentType: "text/plain", StatusCode: http.StatusOK},
+	}
+	server := startMockServer(t, mockResponses)
+	fetcher := newTestFetcher(t, server.URL)
+
+	outline, err := fetcher.FetchOutline(server.URL + "/api")
+	require.NoError(t, err)
+	require.Len(t, outline.Headings, 2)
+
+	full, err := fetcher.FetchWithOptions(server.URL+"/api", FetchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, len(full.Content), outline.ContentLength)
+
+	// Offsets point into the content returned by fetch
+	errorsHeading := outline.Headings[1].Children[1]
+	assert.Equal(t, "errors", errorsHeading.Anchor)
+	section, err := fetcher.FetchWithOptions(server.URL+"/api", FetchOptions{
+		StartIndex: errorsHeading.Offset,
+		MaxLength:  errorsHeading.Length,
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(section.Content, "## Errors"))
+	assert.NotContains(t, section.Content, "Rate limits")
+
+	_, err = fetcher.FetchOutline(server.URL + "/plain")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not HTML or Markdown")
+	assert.ErrorIs(t, err, ierrors.ErrInvalidArgument)
+}
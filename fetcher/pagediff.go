@@ -0,0 +1,192 @@
+package fetcher
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"go.uber.org/zap"
+)
+
+// DiffMode is the output of a page diff.
+type DiffMode string
+
+const (
+	// DiffUnified returns a unified line diff (the default).
+	DiffUnified DiffMode = "unified"
+	// DiffWords returns hunks whose changed lines are compared word by word.
+	DiffWords DiffMode = "words"
+)
+
+// DiffModes lists every supported diff mode.
+var DiffModes = []string{string(DiffUnified), string(DiffWords)}
+
+// Default lines of context of each diff mode.
+const (
+	defaultUnifiedContext = 3
+	defaultWordsContext   = 1
+)
+
+// ParseDiffMode validates a diff mode name. An empty name selects DiffUnified.
+func ParseDiffMode(name string) (DiffMode, error) {
+	switch DiffMode(strings.ToLower(strings.TrimSpace(name))) {
+	case "", DiffUnified:
+		return DiffUnified, nil
+	case DiffWords:
+		return DiffWords, nil
+	}
+	return "", ierrors.Newf(ierrors.ErrInvalidArgument, "invalid diff mode %q: must be one of %s", name, strings.Join(DiffModes, ", "))
+}
+
+// DiffOptions controls what FetchDiff compares and how the diff is returned.
+type DiffOptions struct {
+	Mode DiffMode
+	// Since selects the latest snapshot fetched at or before it instead of
+	// the last one.
+	Since time.Time
+	// CompareURL compares against the current version of another URL instead
+	// of a snapshot.
+	CompareURL string
+	// Context is the number of unchanged lines around changes; negative
+	// selects the mode's default.
+	Context   int
+	MaxLength int // Maximum length of the diff, 0 for no limit
+}
+
+// Differ diffs the processed content of pages against earlier snapshots or
+// against other pages.
+type Differ struct {
+	fetcher   Fetcher
+	snapshots *Snapshots
+	now       func() time.Time
+}
+
+// NewDiffer creates a Differ fetching pages with f and storing their versions
+// in snapshots.
+func NewDiffer(f Fetcher, snapshots *Snapshots) *Differ {
+	return &Differ{fetcher: f, snapshots: snapshots, now: time.Now}
+}
+
+// WithGuard returns a Differ storing its versions in the same snapshots,
+// whose requests are checked with guard.
+func (d *Differ) WithGuard(guard URLGuard) *Differ {
+	return &Differ{fetcher: d.fetcher.WithGuard(guard), snapshots: d.snapshots, now: d.now}
+}
+
+// FetchDiff fetches urlStr and diffs its Markdown content against the
+// snapshot selected by opts, or against opts.CompareURL. The fetched versions
+// are stored as snapshots. Without an earlier snapshot, the response is a
+// baseline with no diff.
+func (d *Differ) FetchDiff(urlStr string, opts DiffOptions) (*types.PageDiffResponse, error) {
+	zap.S().Debugw("diffing URL",
+		"url", urlStr,
+		"mode", opts.Mode,
+		"since", opts.Since,
+		"compare_url", opts.CompareURL,
+		"context", opts.Context)
+
+	mode := opts.Mode
+	if mode == "" {
+		mode = DiffUnified
+	}
+
+	// Select the snapshot first, so the version fetched now is not picked
+	var previous snapshot
+	var hasPrevious bool
+	if opts.CompareURL == "" {
+		previous, hasPrevious = d.snapshots.latest(urlStr, opts.Since)
+		if !hasPrevious && !opts.Since.IsZero() {
+			return nil, ierrors.Newf(ierrors.ErrInvalidArgument, "no snapshot of %s fetched at or before %s", urlStr, opts.Since.UTC().Format(time.RFC3339))
+		}
+	}
+
+	current, err := d.fetchContent(urlStr)
+	if err != nil {
+		return nil, err
+	}
+	response := &types.PageDiffResponse{
+		URL:  urlStr,
+		Mode: string(mode),
+		To:   types.PageVersion{URL: urlStr, FetchedAt: formatSnapshotTime(current.fetchedAt)},
+	}
+
+	var from *types.PageVersion
+	if opts.CompareURL != "" {
+		other, err := d.fetchContent(opts.CompareURL)
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to fetch %s", opts.CompareURL)
+		}
+		previous, hasPrevious = other, true
+		from = &types.PageVersion{URL: opts.CompareURL, FetchedAt: formatSnapshotTime(other.fetchedAt)}
+	} else if hasPrevious {
+		from = &types.PageVersion{URL: urlStr, FetchedAt: formatSnapshotTime(previous.fetchedAt)}
+	}
+	response.Snapshots = d.snapshots.List(urlStr)
+	if !hasPrevious {
+		response.Baseline = true
+		response.Summary = "no earlier snapshot; stored the current version"
+		return response, nil
+	}
+	response.From = from
+
+	context := opts.Context
+	if context < 0 {
+		context = defaultUnifiedContext
+		if mode == DiffWords {
+			context = defaultWordsContext
+		}
+	}
+	edits := diffTokens(splitLines(previous.content), splitLines(current.content))
+	var unit string
+	switch mode {
+	case DiffWords:
+		response.Diff, response.Added, response.Removed = renderWords(edits, context)
+		unit = "words"
+	default:
+		header := fmt.Sprintf("--- %s\t%s\n+++ %s\t%s\n", from.URL, from.FetchedAt, urlStr, response.To.FetchedAt)
+		for _, edit := range edits {
+			switch edit.op {
+			case diffInsert:
+				response.Added++
+			case diffDelete:
+				response.Removed++
+			}
+		}
+		if response.Added > 0 || response.Removed > 0 {
+			response.Diff = header + renderUnified(edits, context)
+		}
+		unit = "lines"
+	}
+	response.Changed = response.Added > 0 || response.Removed > 0
+	response.Summary = fmt.Sprintf("%d %s added, %d removed", response.Added, unit, response.Removed)
+	if opts.MaxLength > 0 && len(response.Diff) > opts.MaxLength {
+		response.Diff = truncateText(response.Diff, opts.MaxLength)
+		response.Truncated = true
+	}
+
+	zap.S().Debugw("diffed URL",
+		"url", urlStr,
+		"added", response.Added,
+		"removed", response.Removed,
+		"truncated", response.Truncated)
+	return response, nil
+}
+
+// fetchContent fetches the whole processed content of urlStr and stores it as
+// a snapshot.
+func (d *Differ) fetchContent(urlStr string) (snapshot, error) {
+	resp, err := d.fetcher.FetchWithOptions(urlStr, FetchOptions{HTTPErrors: HTTPErrorsError})
+	if err != nil {
+		return snapshot{}, err
+	}
+	version := snapshot{content: resp.Content, fetchedAt: d.now()}
+	d.snapshots.record(urlStr, version.content, version.fetchedAt)
+	return version, nil
+}
+
+// formatSnapshotTime formats the fetch time of a snapshot as RFC 3339.
+func formatSnapshotTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,90 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDiffMode(t *testing.T) {
+	mode, err := ParseDiffMode("")
+	require.NoError(t, err)
+	assert.Equal(t, DiffUnified, mode)
+	mode, err = ParseDiffMode(" Words ")
+	require.NoError(t, err)
+	assert.Equal(t, DiffWords, mode)
+	_, err = ParseDiffMode("side-by-side")
+	assert.ErrorContains(t, err, "invalid diff mode")
+}
+
+func TestDiffer_FetchDiff(t *testing.T) {
+	page := &versionedPage{body: "# Terms\n\nYou may use the service.\n\nFees are $10 per month.\n", etag: `"v1"`}
+	server := httptest.NewServer(page)
+	t.Cleanup(server.Close)
+	urlStr := server.URL + "/page"
+
+	clock := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	differ := NewDiffer(newTestFetcherWithTokenizer(t, "heuristic"), NewSnapshots(10, 5))
+	differ.now = func() time.Time { return clock }
+
+	// The first fetch stores a baseline
+	resp, err := differ.FetchDiff(urlStr, DiffOptions{Context: -1})
+	require.NoError(t, err)
+	assert.True(t, resp.Baseline)
+	assert.Nil(t, resp.From)
+	assert.Empty(t, resp.Diff)
+	assert.Equal(t, []string{"2024-01-31T12:00:00Z"}, resp.Snapshots)
+
+	clock = clock.Add(time.Hour)
+	page.set("# Terms\n\nYou may use the service.\n\nFees are $12 per month.\n", `"v2"`)
+	resp, err = differ.FetchDiff(urlStr, DiffOptions{Context: -1})
+	require.NoError(t, err)
+	assert.False(t, resp.Baseline)
+	assert.True(t, resp.Changed)
+	assert.Equal(t, "2024-01-31T12:00:00Z", resp.From.FetchedAt)
+	assert.Equal(t, "2024-01-31T13:00:00Z", resp.To.FetchedAt)
+	assert.Equal(t, "1 lines added, 1 removed", resp.Summary)
+	assert.Equal(t, "--- "+urlStr+"\t2024-01-31T12:00:00Z\n"+
+		"+++ "+urlStr+"\t2024-01-31T13:00:00Z\n"+
+		"@@ -2,4 +2,4 @@\n"+
+		" \n"+
+		" You may use the service.\n"+
+		" \n"+
+		"-Fees are $10 per month.\n"+
+		"+Fees are $12 per month.\n", resp.Diff)
+
+	// Against the version of a given time, word by word
+	clock = clock.Add(time.Hour)
+	page.set("# Terms\n\nYou may not resell the service.\n\nFees are $12 per month.\n", `"v3"`)
+	resp, err = differ.FetchDiff(urlStr, DiffOptions{Mode: DiffWords, Since: clock.Add(-90 * time.Minute), Context: 0})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-31T12:00:00Z", resp.From.FetchedAt)
+	assert.Equal(t, "@@ -3,1 +3,1 @@\nYou may [-use-] {+not resell+} the service.\n@@ -5,1 +5,1 @@\nFees are [-$10-] {+$12+} per month.\n", resp.Diff)
+	assert.Equal(t, 3, resp.Added)
+	assert.Equal(t, 2, resp.Removed)
+	assert.Len(t, resp.Snapshots, 3)
+
+	// Unchanged since the last fetch
+	resp, err = differ.FetchDiff(urlStr, DiffOptions{Context: -1})
+	require.NoError(t, err)
+	assert.False(t, resp.Changed)
+	assert.Empty(t, resp.Diff)
+
+	_, err = differ.FetchDiff(urlStr, DiffOptions{Since: clock.Add(-24 * time.Hour)})
+	assert.ErrorContains(t, err, "no snapshot")
+
+	// Against another URL, truncated
+	other := startMockServer(t, map[string]mockResponse{
+		"/other": {Body: "# Terms\n\nSomething else entirely.\n", ContentType: "text/plain", StatusCode: http.StatusOK},
+	})
+	resp, err = differ.FetchDiff(urlStr, DiffOptions{CompareURL: other.URL + "/other", Context: -1, MaxLength: 20})
+	require.NoError(t, err)
+	assert.Equal(t, other.URL+"/other", resp.From.URL)
+	assert.True(t, resp.Changed)
+	assert.True(t, resp.Truncated)
+	assert.Len(t, resp.Diff, 20)
+}
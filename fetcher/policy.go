@@ -0,0 +1,274 @@
+package fetcher
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"go.uber.org/zap"
+)
+
+// Policy restricts the fetches of one client of the server: the domains it
+// may fetch, the URLs per FetchMultiple and its rate of fetches. Every fetch
+// allowed is logged with the client identity for audit.
+type Policy struct {
+	Client         string   // Identity logged with every fetch
+	AllowedDomains []string // Domains that may be fetched, with their subdomains; all if empty
+	MaxURLs        int      // Maximum URLs per FetchMultiple; unlimited if 0
+
+	limiter *rateLimiter // nil if unlimited
+}
+
+// NewPolicy creates the policy of client, allowing rateLimit fetches per
+// minute (unlimited if 0), each URL of a FetchMultiple counting as one.
+func NewPolicy(client string, allowedDomains []string, maxURLs int, rateLimit int) *Policy {
+	p := &Policy{
+		Client:  client,
+		MaxURLs: maxURLs,
+	}
+	for _, domain := range allowedDomains {
+		domain = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "*.")
+		if domain != "" {
+			p.AllowedDomains = append(p.AllowedDomains, domain)
+		}
+	}
+	if rateLimit > 0 {
+		p.limiter = newRateLimiter(rateLimit, time.Minute)
+	}
+	return p
+}
+
+// Allow checks that the client may fetch urls for operation: that their
+// hosts are allowed and that its rate limit has room for them. Allowed
+// fetches are logged with the client identity.
+func (p *Policy) Allow(operation string, urls ...string) error {
+	if err := p.CheckDomains(operation, urls...); err != nil {
+		return err
+	}
+	if p.limiter != nil && !p.limiter.take(len(urls)) {
+		zap.S().Warnw("client fetch rate limited",
+			"client", p.Client,
+			"operation", operation,
+			"urls", urls)
+		return ierrors.Newf(ierrors.ErrRateLimited, "rate limit of %d fetches per minute exceeded for client %q", p.limiter.capacity, p.Client)
+	}
+	zap.S().Infow("client fetch",
+		"client", p.Client,
+		"operation", operation,
+		"urls", urls)
+	return nil
+}
+
+// CheckDomains checks that the hosts of urls are allowed for the client,
+// without counting them against its rate limit.
+func (p *Policy) CheckDomains(operation string, urls ...string) error {
+	for _, urlStr := range urls {
+		if err := p.checkDomain(urlStr); err != nil {
+			zap.S().Warnw("client fetch denied",
+				"client", p.Client,
+				"operation", operation,
+				"url", urlStr,
+				"error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Guard returns the URLGuard enforcing the policy on the requests made for
+// operation: each fetch is checked with Allow, and each redirect is checked
+// against the allowed domains before it is followed.
+func (p *Policy) Guard(operation string) URLGuard {
+	return func(u *url.URL, redirect bool) error {
+		if !redirect {
+			return p.Allow(operation, u.String())
+		}
+		if err := p.CheckDomains(operation, u.String()); err != nil {
+			return ierrors.Wrapf(err, "refused redirect to %s", u.Redacted())
+		}
+		return nil
+	}
+}
+
+// checkDomain returns an ErrBlockedByPolicy error if the host of urlStr is
+// not one of the allowed domains or their subdomains.
+func (p *Policy) checkDomain(urlStr string) error {
+	if len(p.AllowedDomains) == 0 {
+		return nil
+	}
+	u, err := url.Parse(urlStr)
+	if err != nil || u.Hostname() == "" {
+		return ierrors.Newf(ierrors.ErrInvalidArgument, "invalid URL %q", urlStr)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, domain := range p.AllowedDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return nil
+		}
+	}
+	return ierrors.Newf(ierrors.ErrBlockedByPolicy, "domain %s is not allowed for client %q", host, p.Client)
+}
+
+// rateLimiter is a token bucket refilled continuously.
+type rateLimiter struct {
+	capacity int
+	interval time.Duration // Time to refill the whole bucket
+	now      func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter allows capacity takes per interval, in bursts of up to
+// capacity.
+func newRateLimiter(capacity int, interval time.Duration) *rateLimiter {
+	return &rateLimiter{
+		capacity: capacity,
+		interval: interval,
+		now:      time.Now,
+		tokens:   float64(capacity),
+		last:     time.Now(),
+	}
+}
+
+// take takes n tokens and reports whether there were enough.
+func (l *rateLimiter) take(n int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	elapsed := now.Sub(l.last)
+	l.last = now
+	l.tokens = min(float64(l.capacity), l.tokens+float64(l.capacity)*elapsed.Seconds()/l.interval.Seconds())
+	if l.tokens < float64(n) {
+		return false
+	}
+	l.tokens -= float64(n)
+	return true
+}
+
+// policyFetcher is a Fetcher restricted by a client's policy.
+type policyFetcher struct {
+	Fetcher
+	policy *Policy
+}
+
+// WithPolicy returns f restricted by policy, or f itself if policy is nil.
+// The URLs given are checked against the allowed domains first, then every
+// request sent on their behalf, such as the pages of a crawl, the files of a
+// sitemap or the URLs of a FetchMultiple, is checked with the policy's Guard
+// and counts against its rate limit. Redirects to a domain the policy does
+// not allow are refused before they are followed.
+func WithPolicy(f Fetcher, policy *Policy) Fetcher {
+	if policy == nil {
+		return f
+	}
+	return &policyFetcher{Fetcher: f, policy: policy}
+}
+
+// WithGuard implements Fetcher.
+func (f *policyFetcher) WithGuard(guard URLGuard) Fetcher {
+	return &policyFetcher{Fetcher: f.Fetcher.WithGuard(guard), policy: f.policy}
+}
+
+// guarded checks the domains of urls and returns the wrapped Fetcher guarded
+// by the policy for operation.
+func (f *policyFetcher) guarded(operation string, urls ...string) (Fetcher, error) {
+	if err := f.policy.CheckDomains(operation, urls...); err != nil {
+		return nil, err
+	}
+	return f.Fetcher.WithGuard(f.policy.Guard(operation)), nil
+}
+
+// Fetch implements Fetcher.
+func (f *policyFetcher) Fetch(urlStr string, maxLength int, startIndex int, raw bool) (*types.FetchResponse, error) {
+	return f.FetchWithOptions(urlStr, FetchOptions{MaxLength: maxLength, StartIndex: startIndex, Raw: raw})
+}
+
+// FetchWithOptions implements Fetcher.
+func (f *policyFetcher) FetchWithOptions(urlStr string, opts FetchOptions) (*types.FetchResponse, error) {
+	fetcher, err := f.guarded("fetch", urlStr)
+	if err != nil {
+		return nil, err
+	}
+	return fetcher.FetchWithOptions(urlStr, opts)
+}
+
+// FetchMultiple implements Fetcher.
+func (f *policyFetcher) FetchMultiple(urls []string, maxLength int, raw bool) (*types.MultipleFetchResponse, error) {
+	return f.FetchMultipleWithOptions(context.Background(), urls, MultipleFetchOptions{MaxLength: maxLength, Raw: raw})
+}
+
+// FetchMultipleWithOptions implements Fetcher. URLs beyond the rate limit or
+// redirected to a domain the policy does not allow are reported as errors in
+// their results.
+func (f *policyFetcher) FetchMultipleWithOptions(ctx context.Context, urls []string, opts MultipleFetchOptions) (*types.MultipleFetchResponse, error) {
+	if f.policy.MaxURLs > 0 && len(urls) > f.policy.MaxURLs {
+		return nil, ierrors.Newf(ierrors.ErrBlockedByPolicy, "too many URLs for client %q: %d (max %d)", f.policy.Client, len(urls), f.policy.MaxURLs)
+	}
+	fetcher, err := f.guarded("fetch_multiple", urls...)
+	if err != nil {
+		return nil, err
+	}
+	return fetcher.FetchMultipleWithOptions(ctx, urls, opts)
+}
+
+// FetchLinks implements Fetcher.
+func (f *policyFetcher) FetchLinks(urlStr string, opts LinkOptions) (*types.LinksResponse, error) {
+	fetcher, err := f.guarded("fetch_links", urlStr)
+	if err != nil {
+		return nil, err
+	}
+	return fetcher.FetchLinks(urlStr, opts)
+}
+
+// FetchOutline implements Fetcher.
+func (f *policyFetcher) FetchOutline(urlStr string) (*types.OutlineResponse, error) {
+	fetcher, err := f.guarded("fetch_outline", urlStr)
+	if err != nil {
+		return nil, err
+	}
+	return fetcher.FetchOutline(urlStr)
+}
+
+// FetchSearch implements Fetcher.
+func (f *policyFetcher) FetchSearch(urlStr string, opts SearchOptions) (*types.SearchResponse, error) {
+	fetcher, err := f.guarded("fetch_search", urlStr)
+	if err != nil {
+		return nil, err
+	}
+	return fetcher.FetchSearch(urlStr, opts)
+}
+
+// Crawl implements Fetcher. Each page crawled counts against the rate limit;
+// pages refused by the policy are reported with an error.
+func (f *policyFetcher) Crawl(ctx context.Context, startURL string, opts CrawlOptions) (*types.CrawlResponse, error) {
+	fetcher, err := f.guarded("crawl", startURL)
+	if err != nil {
+		return nil, err
+	}
+	return fetcher.Crawl(ctx, startURL, opts)
+}
+
+// FetchSitemap implements Fetcher. Each sitemap file fetched counts against
+// the rate limit.
+func (f *policyFetcher) FetchSitemap(urlStr string, opts SitemapOptions) (*types.SitemapResponse, error) {
+	fetcher, err := f.guarded("fetch_sitemap", urlStr)
+	if err != nil {
+		return nil, err
+	}
+	return fetcher.FetchSitemap(urlStr, opts)
+}
+
+// FetchSummary implements Fetcher.
+func (f *policyFetcher) FetchSummary(ctx context.Context, urlStr string, opts SummaryOptions) (*types.SummaryResponse, error) {
+	fetcher, err := f.guarded("fetch_summary", urlStr)
+	if err != nil {
+		return nil, err
+	}
+	return fetcher.FetchSummary(ctx, urlStr, opts)
+}
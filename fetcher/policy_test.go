@@ -0,0 +1,126 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_AllowDomains(t *testing.T) {
+	policy := NewPolicy("alice", []string{"Example.com", "*.docs.org", " "}, 0, 0)
+	assert.Equal(t, []string{"example.com", "docs.org"}, policy.AllowedDomains)
+
+	for _, urlStr := range []string{
+		"https://example.com/page",
+		"https://www.EXAMPLE.com./page",
+		"http://example.com:8080/",
+		"https://api.docs.org/v1",
+	} {
+		assert.NoError(t, policy.Allow("fetch", urlStr), urlStr)
+	}
+	for _, urlStr := range []string{
+		"https://notexample.com/",
+		"https://example.com.evil.net/",
+		"https://other.org/",
+	} {
+		err := policy.Allow("fetch", urlStr)
+		assert.True(t, errors.Is(err, ierrors.ErrBlockedByPolicy), urlStr)
+	}
+	assert.True(t, errors.Is(policy.Allow("fetch", "not a url"), ierrors.ErrInvalidArgument))
+
+	// No allowed domains allows every domain
+	assert.NoError(t, NewPolicy("bob", nil, 0, 0).Allow("fetch", "https://anything.example/"))
+}
+
+func TestPolicy_RateLimit(t *testing.T) {
+	policy := NewPolicy("alice", nil, 0, 3)
+	now := time.Now()
+	policy.limiter.now = func() time.Time { return now }
+	policy.limiter.last = now
+
+	assert.NoError(t, policy.Allow("fetch_multiple", "https://a.example/", "https://b.example/"))
+	assert.NoError(t, policy.Allow("fetch", "https://a.example/"))
+	err := policy.Allow("fetch", "https://a.example/")
+	require.Error(t, err)
+	assert.Equal(t, "rate_limited", ierrors.Code(err))
+	assert.True(t, ierrors.Classify(err).Retryable)
+
+	// One fetch is allowed again after a third of a minute
+	now = now.Add(20 * time.Second)
+	assert.NoError(t, policy.Allow("fetch", "https://a.example/"))
+	assert.Error(t, policy.Allow("fetch", "https://a.example/"))
+}
+
+func TestPolicyFetcher(t *testing.T) {
+	var server *httptest.Server
+	var redirected int32
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.Host, "localhost") {
+			atomic.AddInt32(&redirected, 1)
+		}
+		switch r.URL.Path {
+		case "/away":
+			// Same server, under a host name the policy does not allow
+			http.Redirect(w, r, strings.Replace(server.URL, "127.0.0.1", "localhost", 1)+"/page", http.StatusFound)
+		case "/page", "/other":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("Hello"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	f := WithPolicy(newTestFetcherWithTokenizer(t, "heuristic"), NewPolicy("alice", []string{"127.0.0.1"}, 2, 0))
+
+	response, err := f.Fetch(server.URL+"/page", 100, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", response.Content)
+
+	// The redirect is refused before it is followed
+	_, err = f.Fetch(server.URL+"/away", 100, 0, false)
+	assert.True(t, errors.Is(err, ierrors.ErrBlockedByPolicy))
+	_, err = f.FetchOutline("https://example.com/")
+	assert.True(t, errors.Is(err, ierrors.ErrBlockedByPolicy))
+
+	multiple, err := f.FetchMultiple([]string{server.URL + "/page", server.URL + "/away"}, 100, false)
+	require.NoError(t, err)
+	require.Len(t, multiple.Results, 2)
+	assert.Nil(t, multiple.Results[0].Error)
+	require.NotNil(t, multiple.Results[1].Error)
+	assert.Equal(t, "blocked_by_policy", multiple.Results[1].Error.Code)
+	assert.Empty(t, multiple.Results[1].Content)
+
+	_, err = f.FetchMultiple([]string{server.URL + "/page", server.URL + "/other", server.URL + "/away"}, 100, false)
+	assert.True(t, errors.Is(err, ierrors.ErrBlockedByPolicy))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&redirected))
+}
+
+func TestPolicyFetcher_RateLimit(t *testing.T) {
+	server := startMockServer(t, crawlSite())
+	f := WithPolicy(newTestFetcher(t, server.URL), NewPolicy("alice", nil, 0, 2))
+
+	// Every page of the crawl counts against the rate limit
+	resp, err := f.Crawl(context.Background(), server.URL+"/docs/", CrawlOptions{MaxDepth: 1, MaxLength: 10000})
+	require.NoError(t, err)
+	require.Len(t, resp.Pages, 4)
+	var limited int
+	for _, page := range resp.Pages {
+		if strings.Contains(page.Error, "rate limit") {
+			limited++
+		}
+	}
+	assert.Equal(t, 2, limited)
+
+	_, err = f.FetchSitemap(server.URL+"/sitemap.xml", SitemapOptions{})
+	assert.True(t, errors.Is(err, ierrors.ErrRateLimited))
+}
@@ -0,0 +1,43 @@
+package fetcher
+
+import (
+	"io"
+)
+
+// progressByteStep is the number of bytes between download progress updates,
+// so that only large downloads report them.
+const progressByteStep = 64 * 1024
+
+// ProgressFunc receives progress updates of a long-running call: the amount
+// done so far, the total amount or 0 if unknown, and a short description.
+// It may be called from multiple goroutines.
+type ProgressFunc func(progress, total float64, message string)
+
+// progressReader reports the bytes read from a response body every
+// progressByteStep bytes.
+type progressReader struct {
+	reader   io.Reader
+	total    int64 // Content-Length, or 0 if unknown
+	read     int64
+	next     int64 // Byte count of the next update
+	progress ProgressFunc
+}
+
+// newProgressReader wraps r to report download progress to progress.
+func newProgressReader(r io.Reader, total int64, progress ProgressFunc) *progressReader {
+	if total < 0 {
+		total = 0
+	}
+	return &progressReader{reader: r, total: total, next: progressByteStep, progress: progress}
+}
+
+// Read implements io.Reader.
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.read >= r.next {
+		r.progress(float64(r.read), float64(r.total), "downloading")
+		r.next = r.read + progressByteStep
+	}
+	return n, err
+}
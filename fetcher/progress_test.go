@@ -0,0 +1,82 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// progressRecorder collects progress updates.
+type progressRecorder struct {
+	mu      sync.Mutex
+	updates [][2]float64
+}
+
+func (r *progressRecorder) record(progress, total float64, message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.updates = append(r.updates, [2]float64{progress, total})
+}
+
+func TestHTTPFetcher_FetchWithOptions_Progress(t *testing.T) {
+	large := strings.Repeat("word ", progressByteStep) // 5 steps
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := "small"
+		if r.URL.Path == "/large" {
+			body = large
+		}
+		// The total is reported when the server sends Content-Length
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	fetcher := newTestFetcher(t, server.URL)
+
+	recorder := &progressRecorder{}
+	_, err := fetcher.FetchWithOptions(server.URL+"/large", FetchOptions{MaxLength: 10, Progress: recorder.record})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(recorder.updates), 2)
+	for i, u := range recorder.updates {
+		assert.Equal(t, float64(len(large)), u[1])
+		if i > 0 {
+			assert.Greater(t, u[0], recorder.updates[i-1][0])
+		}
+	}
+
+	// Small downloads report nothing
+	recorder = &progressRecorder{}
+	_, err = fetcher.FetchWithOptions(server.URL+"/small", FetchOptions{Progress: recorder.record})
+	require.NoError(t, err)
+	assert.Empty(t, recorder.updates)
+}
+
+func TestHTTPFetcher_FetchMultipleWithOptions_Progress(t *testing.T) {
+	mockResponses := map[string]mockResponse{
+		"/a": {Body: "A", ContentType: "text/plain", StatusCode: http.StatusOK},
+		"/b": {Body: "B", ContentType: "text/plain", StatusCode: http.StatusOK},
+	}
+	server := startMockServer(t, mockResponses)
+	fetcher := newTestFetcher(t, server.URL)
+
+	recorder := &progressRecorder{}
+	urls := []string{server.URL + "/a", server.URL + "/b", server.URL + "/missing"}
+	_, err := fetcher.FetchMultipleWithOptions(context.Background(), urls, MultipleFetchOptions{Progress: recorder.record})
+	require.NoError(t, err)
+
+	// One update per URL, failed or not, each counting completed URLs out of the total
+	require.Len(t, recorder.updates, 3)
+	seen := map[float64]bool{}
+	for _, u := range recorder.updates {
+		seen[u[0]] = true
+		assert.Equal(t, float64(3), u[1])
+	}
+	assert.Equal(t, map[float64]bool{1: true, 2: true, 3: true}, seen)
+}
@@ -0,0 +1,167 @@
+package fetcher
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cnosuke/mcp-fetch/types"
+)
+
+// responseInfoHeaders lists the response headers returned in
+// types.ResponseInfo: those that explain caching, encoding and which server
+// or CDN answered.
+var responseInfoHeaders = []string{
+	"Age",
+	"Cache-Control",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Length",
+	"Content-Type",
+	"Date",
+	"ETag",
+	"Expires",
+	"Last-Modified",
+	"Retry-After",
+	"Server",
+	"Vary",
+	"Via",
+	"X-Cache",
+	"CF-Cache-Status",
+	"CF-Ray",
+	"X-Served-By",
+}
+
+// responseTrace records the httptrace timings of a request. When a request is
+// redirected, the connection phases are those of the last connection made.
+type responseTrace struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	dns          time.Duration
+	connect      time.Duration
+	tls          time.Duration
+	ttfb         time.Duration
+	remoteAddr   string
+}
+
+// newResponseTrace starts timing a request.
+func newResponseTrace() *responseTrace {
+	return &responseTrace{start: time.Now()}
+}
+
+// clientTrace returns the hooks recording t. They may be called concurrently
+// by the transport, e.g. when dialing several addresses.
+func (t *responseTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dns = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.connectStart = time.Now()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.connect = time.Since(t.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tls = time.Since(t.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if info.Conn != nil {
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+			if info.Reused {
+				t.dns, t.connect, t.tls = 0, 0, 0
+			}
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.ttfb = time.Since(t.start)
+		},
+	}
+}
+
+// responseInfo builds the types.ResponseInfo of a response whose body has been
+// read: compressed is the number of bytes received for the body and body the
+// decompressed content.
+func (t *responseTrace) responseInfo(resp *http.Response, compressed int64, body []byte) *types.ResponseInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	headers := make(map[string]string)
+	for _, name := range responseInfoHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			headers[strings.ToLower(name)] = value
+		}
+	}
+	sum := sha256.Sum256(body)
+
+	info := &types.ResponseInfo{
+		RemoteAddr:        t.remoteAddr,
+		Protocol:          resp.Proto,
+		Headers:           headers,
+		CompressedBytes:   compressed,
+		DecompressedBytes: int64(len(body)),
+		SHA256:            hex.EncodeToString(sum[:]),
+		Timings: &types.ResponseTimings{
+			DNSMs:     durationMs(t.dns),
+			ConnectMs: durationMs(t.connect),
+			TLSMs:     durationMs(t.tls),
+			TTFBMs:    durationMs(t.ttfb),
+			TotalMs:   durationMs(time.Since(t.start)),
+		},
+	}
+	if resp.TLS != nil {
+		info.TLSVersion = tls.VersionName(resp.TLS.Version)
+	}
+	return info
+}
+
+// durationMs converts d to fractional milliseconds.
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// countingReader counts the bytes read from a reader.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+// Read implements io.Reader.
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
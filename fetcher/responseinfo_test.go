@@ -0,0 +1,78 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPFetcher_ResponseInfo(t *testing.T) {
+	page := "<html><body><h1>Compressed</h1><p>" + strings.Repeat("word ", 2000) + "</p></body></html>"
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, _ = zw.Write([]byte(page))
+	require.NoError(t, zw.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/page" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("X-Internal", "not returned")
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			_, _ = w.Write(compressed.Bytes())
+			return
+		}
+		_, _ = w.Write([]byte(page))
+	}))
+	t.Cleanup(server.Close)
+	fetcher := newTestFetcherWithTokenizer(t, "heuristic")
+
+	resp, err := fetcher.FetchWithOptions(server.URL+"/page", FetchOptions{MaxLength: 20, IncludeResponseInfo: true})
+	require.NoError(t, err)
+	assert.Equal(t, "# Compressed\n\nword w", resp.Content)
+
+	info := resp.ResponseInfo
+	require.NotNil(t, info)
+	assert.Equal(t, "HTTP/1.1", info.Protocol)
+	assert.Equal(t, strings.TrimPrefix(server.URL, "http://"), info.RemoteAddr)
+	assert.Empty(t, info.TLSVersion)
+	assert.Equal(t, `"v1"`, info.Headers["etag"])
+	assert.Equal(t, "gzip", info.Headers["content-encoding"])
+	assert.NotContains(t, info.Headers, "x-internal")
+
+	assert.Equal(t, int64(compressed.Len()), info.CompressedBytes)
+	assert.Equal(t, int64(len(page)), info.DecompressedBytes)
+	sum := sha256.Sum256([]byte(page))
+	assert.Equal(t, hex.EncodeToString(sum[:]), info.SHA256)
+
+	require.NotNil(t, info.Timings)
+	assert.Greater(t, info.Timings.TTFBMs, 0.0)
+	assert.GreaterOrEqual(t, info.Timings.TotalMs, info.Timings.TTFBMs)
+
+	// Response info is opt-in
+	resp, err = fetcher.FetchWithOptions(server.URL+"/page", FetchOptions{MaxLength: 20})
+	require.NoError(t, err)
+	assert.Nil(t, resp.ResponseInfo)
+	assert.Equal(t, "# Compressed\n\nword w", resp.Content)
+
+	multi, err := fetcher.FetchMultipleWithOptions(context.Background(), []string{server.URL + "/page", server.URL + "/missing"},
+		MultipleFetchOptions{MaxLength: 100, IncludeResponseInfo: true})
+	require.NoError(t, err)
+	require.NotNil(t, multi.Results[0].ResponseInfo)
+	assert.Equal(t, int64(len(page)), multi.Results[0].ResponseInfo.DecompressedBytes)
+	require.NotNil(t, multi.Results[1].ResponseInfo)
+	assert.Equal(t, int64(len("404 page not found\n")), multi.Results[1].ResponseInfo.DecompressedBytes)
+}
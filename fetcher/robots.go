@@ -0,0 +1,271 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/robots"
+	"go.uber.org/zap"
+)
+
+// Robots policy modes.
+const (
+	// RobotsEnforce refuses to fetch URLs disallowed by robots.txt (the default).
+	RobotsEnforce = "enforce"
+	// RobotsWarn fetches disallowed URLs but logs a warning.
+	RobotsWarn = "warn"
+	// RobotsOff ignores robots.txt.
+	RobotsOff = "off"
+)
+
+const (
+	// robotsCacheTTL is how long a fetched robots.txt is reused (RFC 9309 §2.4).
+	robotsCacheTTL = 24 * time.Hour
+	// robotsMaxSize is the number of robots.txt bytes parsed (RFC 9309 §2.5).
+	robotsMaxSize = 500 * 1024
+	// maxCrawlDelay caps the Crawl-delay honored between requests to one origin.
+	maxCrawlDelay = 10 * time.Second
+)
+
+// ErrRobotsDisallowed matches errors returned for URLs disallowed by robots.txt.
+// It is the ierrors.ErrRobots kind.
+var ErrRobotsDisallowed = ierrors.ErrRobots
+
+// RobotsError is returned when robots.txt disallows fetching a URL.
+// errors.Is(err, ErrRobotsDisallowed) reports true for it.
+type RobotsError struct {
+	URL       string
+	UserAgent string
+}
+
+// Error implements the error interface.
+func (e *RobotsError) Error() string {
+	return fmt.Sprintf("robots.txt disallows %s for user agent %q", e.URL, e.UserAgent)
+}
+
+// Is makes RobotsError match ErrRobotsDisallowed.
+func (e *RobotsError) Is(target error) bool {
+	return target == ErrRobotsDisallowed
+}
+
+// ParseRobotsMode validates a robots policy name. An empty name selects RobotsEnforce.
+func ParseRobotsMode(name string) (string, error) {
+	switch mode := strings.ToLower(strings.TrimSpace(name)); mode {
+	case "":
+		return RobotsEnforce, nil
+	case RobotsEnforce, RobotsWarn, RobotsOff:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unsupported robots mode %q (expected %s, %s or %s)", name, RobotsEnforce, RobotsWarn, RobotsOff)
+}
+
+// robotsAgentToken derives the product token matched against robots.txt
+// user-agent lines from a User-Agent header: "mcp-fetch/1.0 (+url)" -> "mcp-fetch".
+func robotsAgentToken(userAgent string) string {
+	token, _, _ := strings.Cut(strings.TrimSpace(userAgent), " ")
+	token, _, _ = strings.Cut(token, "/")
+	return token
+}
+
+// robotsPolicy fetches and caches robots.txt per origin and applies the
+// configured mode to each request.
+type robotsPolicy struct {
+	mode      string
+	agent     string // Product token matched against user-agent lines
+	userAgent string // User-Agent header sent when fetching robots.txt
+	client    *http.Client
+
+	mu      sync.Mutex
+	entries map[string]*robotsEntry
+	next    map[string]time.Time // Earliest time of the next request per origin
+}
+
+// robotsEntry is a cached robots.txt. ready is closed once rules or err is
+// set, so concurrent requests to a new origin share a single robots.txt fetch.
+type robotsEntry struct {
+	rules   *robots.Rules
+	err     error // Set if robots.txt could not be requested at all
+	expires time.Time
+	ready   chan struct{}
+}
+
+// newRobotsPolicy creates a robots policy. client is used for robots.txt
+// requests, which are not subject to the policy themselves.
+func newRobotsPolicy(mode, agent, userAgent string, client *http.Client) *robotsPolicy {
+	return &robotsPolicy{
+		mode:      mode,
+		agent:     agent,
+		userAgent: userAgent,
+		client:    client,
+		entries:   make(map[string]*robotsEntry),
+		next:      make(map[string]time.Time),
+	}
+}
+
+// check applies the policy to u. It returns a *RobotsError if u is disallowed
+// in enforce mode, and otherwise waits for the origin's Crawl-delay, if any.
+// If robots.txt cannot be requested (e.g. the host is unreachable), enforce
+// mode returns that error instead. The robots.txt request and the wait stop
+// with ctx.
+func (p *robotsPolicy) check(ctx context.Context, u *url.URL) error {
+	if p.mode == RobotsOff || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+
+	rules, err := p.rules(ctx, u)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ierrors.Wrap(ctx.Err(), "failed to fetch robots.txt")
+		}
+		if p.mode == RobotsEnforce {
+			return ierrors.Wrap(err, "failed to fetch robots.txt")
+		}
+		zap.S().Warnw("fetching URL without robots.txt", "url", u.String(), "error", err)
+		return nil
+	}
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if !rules.Allowed(p.agent, path) {
+		if p.mode == RobotsEnforce {
+			return &RobotsError{URL: u.String(), UserAgent: p.agent}
+		}
+		zap.S().Warnw("fetching URL disallowed by robots.txt", "url", u.String(), "user_agent", p.agent)
+	}
+
+	if err := p.wait(ctx, u, rules.CrawlDelay(p.agent)); err != nil {
+		return ierrors.Wrap(err, "interrupted while waiting for crawl delay")
+	}
+	return nil
+}
+
+// rules returns the cached robots.txt rules for the origin of u, fetching
+// them if needed. Request errors are not cached, and a fetch cancelled by
+// the context of another request is retried with ctx.
+func (p *robotsPolicy) rules(ctx context.Context, u *url.URL) (*robots.Rules, error) {
+	origin := u.Scheme + "://" + strings.ToLower(u.Host)
+	for {
+		rules, err := p.originRules(ctx, origin)
+		if err != nil && ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+			continue
+		}
+		return rules, err
+	}
+}
+
+// originRules returns the cached robots.txt rules for origin, fetching them
+// with ctx if needed, or waiting for the fetch in progress.
+func (p *robotsPolicy) originRules(ctx context.Context, origin string) (*robots.Rules, error) {
+	p.mu.Lock()
+	entry, ok := p.entries[origin]
+	if ok {
+		select {
+		case <-entry.ready:
+			// Fetched entries are refreshed once expired
+			ok = time.Now().Before(entry.expires)
+		default:
+			// Still being fetched
+		}
+	}
+	if !ok {
+		entry = &robotsEntry{ready: make(chan struct{})}
+		p.entries[origin] = entry
+		p.mu.Unlock()
+
+		entry.rules, entry.err = p.fetchRules(ctx, origin)
+		entry.expires = time.Now().Add(robotsCacheTTL)
+		if entry.err != nil {
+			p.mu.Lock()
+			if p.entries[origin] == entry {
+				delete(p.entries, origin)
+			}
+			p.mu.Unlock()
+		}
+		close(entry.ready)
+		return entry.rules, entry.err
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-entry.ready:
+		return entry.rules, entry.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetchRules downloads and parses robots.txt for an origin. A missing file
+// (4xx) allows everything; server errors disallow everything, as RFC 9309
+// requires. It returns an error only if the request itself failed.
+func (p *robotsPolicy) fetchRules(ctx context.Context, origin string) (*robots.Rules, error) {
+	robotsURL := origin + robots.Path
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("User-Agent", p.userAgent)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to execute request")
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		zap.S().Warnw("robots.txt unavailable, treating origin as disallowed", "url", robotsURL, "status", resp.StatusCode)
+		return robots.DisallowAll(), nil
+	case resp.StatusCode >= 400:
+		zap.S().Debugw("no robots.txt, allowing all", "url", robotsURL, "status", resp.StatusCode)
+		return robots.AllowAll(), nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, robotsMaxSize))
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to read robots.txt")
+	}
+
+	zap.S().Debugw("fetched robots.txt", "url", robotsURL, "bytes", len(body))
+	return robots.Parse(string(body)), nil
+}
+
+// wait blocks until a request to the origin of u respects delay since the
+// previous one. Slots are reserved under the lock so concurrent requests to
+// the same origin are spaced out. It returns ctx.Err() if ctx is done first.
+func (p *robotsPolicy) wait(ctx context.Context, u *url.URL, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	if delay > maxCrawlDelay {
+		delay = maxCrawlDelay
+	}
+	origin := u.Scheme + "://" + strings.ToLower(u.Host)
+
+	p.mu.Lock()
+	now := time.Now()
+	slot := p.next[origin]
+	if slot.Before(now) {
+		slot = now
+	}
+	p.next[origin] = slot.Add(delay)
+	p.mu.Unlock()
+
+	if d := time.Until(slot); d > 0 {
+		zap.S().Debugw("waiting for crawl delay", "url", u.String(), "wait", d)
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
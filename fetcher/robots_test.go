@@ -0,0 +1,179 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startRobotsServer serves robotsBody (or robotsStatus if non-zero) at
+// /robots.txt, a page at every other path and a redirect at /go-private.
+// It returns the server and a counter of robots.txt requests.
+func startRobotsServer(t *testing.T, robotsBody string, robotsStatus int) (*httptest.Server, *int32) {
+	t.Helper()
+	var robotsHits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			atomic.AddInt32(&robotsHits, 1)
+			if robotsStatus != 0 {
+				w.WriteHeader(robotsStatus)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(robotsBody))
+		case "/go-private":
+			http.Redirect(w, r, "/private/page", http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("page " + r.URL.Path))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &robotsHits
+}
+
+func newRobotsTestFetcher(t *testing.T, mode string) Fetcher {
+	t.Helper()
+	fetcher, err := NewHTTPFetcher(&Config{
+		Timeout:          5,
+		UserAgent:        "test-agent/1.0",
+		MaxURLs:          20,
+		MaxWorkers:       5,
+		DefaultMaxLength: 1000,
+		Robots:           mode,
+	})
+	require.NoError(t, err)
+	return fetcher
+}
+
+const testRobots = "User-agent: *\nDisallow: /\n\nUser-agent: test-agent\nDisallow: /private/\nAllow: /private/open\n"
+
+func TestHTTPFetcher_Robots_Enforce(t *testing.T) {
+	server, hits := startRobotsServer(t, testRobots, 0)
+	fetcher := newRobotsTestFetcher(t, RobotsEnforce)
+
+	resp, err := fetcher.Fetch(server.URL+"/docs", 100, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, "page /docs", resp.Content)
+
+	_, err = fetcher.Fetch(server.URL+"/private/secret", 100, 0, false)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrRobotsDisallowed))
+	assert.True(t, errors.Is(err, ierrors.ErrRobots))
+	assert.Equal(t, "robots", ierrors.Code(err))
+	var robotsErr *RobotsError
+	require.True(t, errors.As(err, &robotsErr))
+	assert.Equal(t, "test-agent", robotsErr.UserAgent)
+
+	_, err = fetcher.Fetch(server.URL+"/private/open", 100, 0, false)
+	require.NoError(t, err)
+
+	// Redirect targets are checked too
+	_, err = fetcher.Fetch(server.URL+"/go-private", 100, 0, false)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrRobotsDisallowed))
+
+	// Blocked URLs are reported per URL in fetch_multiple
+	multi, err := fetcher.FetchMultiple([]string{server.URL + "/docs", server.URL + "/private/x"}, 1000, false)
+	require.NoError(t, err)
+	require.Len(t, multi.Results, 2)
+	assert.Nil(t, multi.Results[0].Error)
+	require.NotNil(t, multi.Results[1].Error)
+	assert.Contains(t, multi.Results[1].Error.Message, "robots.txt disallows")
+
+	// robots.txt is fetched once per origin
+	assert.Equal(t, int32(1), atomic.LoadInt32(hits))
+}
+
+func TestHTTPFetcher_Robots_Modes(t *testing.T) {
+	server, hits := startRobotsServer(t, testRobots, 0)
+
+	_, err := newRobotsTestFetcher(t, RobotsWarn).Fetch(server.URL+"/private/secret", 100, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(hits))
+
+	_, err = newRobotsTestFetcher(t, RobotsOff).Fetch(server.URL+"/private/secret", 100, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(hits)) // Not requested when off
+
+	_, err = NewHTTPFetcher(&Config{Timeout: 5, Robots: "sometimes"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported robots mode")
+}
+
+func TestHTTPFetcher_Robots_Unavailable(t *testing.T) {
+	// A missing robots.txt allows everything
+	server, _ := startRobotsServer(t, "", http.StatusNotFound)
+	_, err := newRobotsTestFetcher(t, RobotsEnforce).Fetch(server.URL+"/private/secret", 100, 0, false)
+	require.NoError(t, err)
+
+	// Server errors disallow everything
+	server, _ = startRobotsServer(t, "", http.StatusServiceUnavailable)
+	_, err = newRobotsTestFetcher(t, RobotsEnforce).Fetch(server.URL+"/docs", 100, 0, false)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrRobotsDisallowed))
+}
+
+func TestHTTPFetcher_Robots_CrawlDelay(t *testing.T) {
+	server, _ := startRobotsServer(t, "User-agent: *\nCrawl-delay: 0.2\n", 0)
+	fetcher := newRobotsTestFetcher(t, RobotsEnforce)
+
+	start := time.Now()
+	for _, path := range []string{"/a", "/b", "/c"} {
+		_, err := fetcher.Fetch(server.URL+path, 100, 0, false)
+		require.NoError(t, err)
+	}
+	// The first request is immediate, the next two wait for the delay
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
+
+func TestRobotsPolicy_Cancel(t *testing.T) {
+	// The crawl delay wait stops with the context
+	server, _ := startRobotsServer(t, "User-agent: *\nCrawl-delay: 5\n", 0)
+	policy := newRobotsPolicy(RobotsEnforce, "test-agent", "test-agent/1.0", http.DefaultClient)
+	u, err := url.Parse(server.URL + "/a")
+	require.NoError(t, err)
+	require.NoError(t, policy.check(context.Background(), u))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = policy.check(ctx, u)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// So does the robots.txt request
+	release := make(chan struct{})
+	defer close(release)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	u, err = url.Parse(slow.URL + "/a")
+	require.NoError(t, err)
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	err = policy.check(ctx, u)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRobotsAgentToken(t *testing.T) {
+	assert.Equal(t, "mcp-fetch", robotsAgentToken("mcp-fetch/1.0"))
+	assert.Equal(t, "MyBot", robotsAgentToken(" MyBot/2.1 (+https://example.com/bot)"))
+	assert.Equal(t, "plain", robotsAgentToken("plain"))
+}
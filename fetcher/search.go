@@ -0,0 +1,249 @@
+package fetcher
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultSearchResults is the number of matches returned when MaxResults is not set.
+	defaultSearchResults = 10
+	// maxSearchContext caps the number of context paragraphs on each side of a match.
+	maxSearchContext = 5
+)
+
+// SearchOptions controls how FetchSearch matches and returns paragraphs.
+type SearchOptions struct {
+	Query      string // Keywords, or a regular expression if Regex is set
+	Regex      bool   // Treat Query as a regular expression
+	MaxResults int    // Maximum number of matches to return (default 10)
+	Context    int    // Number of paragraphs of context before and after each match
+}
+
+// paragraph is a blank-line separated block of the processed content.
+type paragraph struct {
+	text    string
+	offset  int
+	heading bool
+}
+
+// FetchSearch fetches a page, runs the regular Markdown pipeline and returns
+// the paragraphs matching the query, best matches first.
+func (f *httpFetcher) FetchSearch(urlStr string, opts SearchOptions) (*types.SearchResponse, error) {
+	zap.S().Debugw("searching URL",
+		"url", urlStr,
+		"query", opts.Query,
+		"regex", opts.Regex,
+		"max_results", opts.MaxResults,
+		"context", opts.Context)
+
+	match, err := newSearchMatcher(opts.Query, opts.Regex)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := f.fetch(urlStr)
+	if resp.err != nil {
+		return nil, resp.err
+	}
+	content, _, err := markdownContent(resp, urlStr)
+	if err != nil {
+		// Other text formats are searched as they are
+		if !strings.HasPrefix(resp.contentType, "text/") {
+			return nil, err
+		}
+		content = resp.body
+	}
+
+	matches := searchParagraphs(content, match, opts.Context)
+	total := len(matches)
+	maxResults := opts.MaxResults
+	if maxResults <= 0 {
+		maxResults = defaultSearchResults
+	}
+	if len(matches) > maxResults {
+		matches = matches[:maxResults]
+	}
+
+	zap.S().Debugw("searched content",
+		"url", urlStr,
+		"query", opts.Query,
+		"total", total,
+		"returned", len(matches))
+
+	return &types.SearchResponse{
+		URL:           urlStr,
+		StatusCode:    resp.status,
+		OriginalURL:   resp.originalURL,
+		Query:         opts.Query,
+		ContentLength: len(content),
+		Total:         total,
+		Matches:       matches,
+	}, nil
+}
+
+// searchMatcher scores a paragraph. It returns the number of distinct query
+// terms found (the primary ranking key) and the total number of matches.
+type searchMatcher func(text string) (terms int, count int)
+
+// newSearchMatcher builds a matcher for a keyword or regular expression query.
+// Keywords match case-insensitively anywhere in the text.
+func newSearchMatcher(query string, regex bool) (searchMatcher, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, ierrors.New(ierrors.ErrInvalidArgument, "query is required")
+	}
+
+	if regex {
+		re, err := regexp.Compile(query)
+		if err != nil {
+			return nil, ierrors.WithKind(ierrors.Wrap(err, "invalid query pattern"), ierrors.ErrInvalidArgument)
+		}
+		return func(text string) (int, int) {
+			count := len(re.FindAllStringIndex(text, -1))
+			if count == 0 {
+				return 0, 0
+			}
+			return 1, count
+		}, nil
+	}
+
+	keywords := strings.Fields(strings.ToLower(query))
+	return func(text string) (int, int) {
+		lower := strings.ToLower(text)
+		terms, count := 0, 0
+		for _, k := range keywords {
+			if n := strings.Count(lower, k); n > 0 {
+				terms++
+				count += n
+			}
+		}
+		return terms, count
+	}, nil
+}
+
+// searchParagraphs returns the matching paragraphs of Markdown content, ranked
+// by distinct terms matched, then by number of matches, then by position.
+// Headings are not returned themselves but make up each match's heading path.
+func searchParagraphs(content string, match searchMatcher, context int) []types.SearchMatch {
+	if context < 0 {
+		context = 0
+	}
+	if context > maxSearchContext {
+		context = maxSearchContext
+	}
+
+	paragraphs := splitParagraphs(content)
+	headings := parseMarkdownHeadings(content)
+
+	type ranked struct {
+		match types.SearchMatch
+		terms int
+	}
+	results := []ranked{}
+	for i, p := range paragraphs {
+		if p.heading {
+			continue
+		}
+		terms, count := match(p.text)
+		if count == 0 {
+			continue
+		}
+
+		m := types.SearchMatch{
+			Text:        p.text,
+			Offset:      p.offset,
+			Length:      len(p.text),
+			Score:       count,
+			HeadingPath: headingPath(headings, p.offset),
+		}
+		for j := max(0, i-context); j < i; j++ {
+			m.Before = append(m.Before, paragraphs[j].text)
+		}
+		for j := i + 1; j < len(paragraphs) && j <= i+context; j++ {
+			m.After = append(m.After, paragraphs[j].text)
+		}
+		results = append(results, ranked{match: m, terms: terms})
+	}
+
+	sort.SliceStable(results, func(a, b int) bool {
+		if results[a].terms != results[b].terms {
+			return results[a].terms > results[b].terms
+		}
+		return results[a].match.Score > results[b].match.Score
+	})
+
+	matches := make([]types.SearchMatch, 0, len(results))
+	for _, r := range results {
+		matches = append(matches, r.match)
+	}
+	return matches
+}
+
+// splitParagraphs splits Markdown content into blank-line separated blocks,
+// keeping fenced code blocks whole.
+func splitParagraphs(content string) []paragraph {
+	paragraphs := []paragraph{}
+	start := -1
+	end := 0
+	fence := ""
+	flush := func() {
+		if start >= 0 {
+			text := content[start:end]
+			paragraphs = append(paragraphs, paragraph{
+				text:    text,
+				offset:  start,
+				heading: markdownHeadingPattern.MatchString(text) && !strings.Contains(text, "\n"),
+			})
+		}
+		start = -1
+	}
+
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		lineStart := offset
+		offset += len(line)
+		trimmed := strings.TrimRight(line, "\r\n")
+		stripped := strings.TrimSpace(trimmed)
+
+		if fence != "" {
+			end = lineStart + len(trimmed)
+			if strings.HasPrefix(stripped, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if stripped == "" {
+			flush()
+			continue
+		}
+		if strings.HasPrefix(stripped, "```") || strings.HasPrefix(stripped, "~~~") {
+			fence = stripped[:3]
+		}
+		if start < 0 {
+			start = lineStart
+		}
+		end = lineStart + len(trimmed)
+	}
+	flush()
+	return paragraphs
+}
+
+// headingPath returns the texts of the headings whose sections contain offset,
+// outermost first.
+func headingPath(headings []*types.Heading, offset int) []string {
+	path := []string{}
+	for _, h := range headings {
+		if h.Offset <= offset && offset < h.Offset+h.Length {
+			path = append(path, h.Text)
+		}
+	}
+	if len(path) == 0 {
+		return nil
+	}
+	return path
+}
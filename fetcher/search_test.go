@@ -0,0 +1,100 @@
+package fetcher
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const searchDocument = "# Guide\n\nIntro about tokens.\n\n## Auth\n\nSend a bearer token.\n\nRotate the token and the key often. The token expires.\n\n```\nexport TOKEN=abc\n\necho done\n```\n\n## Limits\n\nNo match here."
+
+func TestSplitParagraphs(t *testing.T) {
+	paragraphs := splitParagraphs(searchDocument)
+	require.Len(t, paragraphs, 8)
+
+	assert.Equal(t, "# Guide", paragraphs[0].text)
+	assert.True(t, paragraphs[0].heading)
+	assert.Equal(t, "Send a bearer token.", paragraphs[3].text)
+	assert.Equal(t, strings.Index(searchDocument, "Send a bearer"), paragraphs[3].offset)
+	// Blank lines inside fenced code do not split the block
+	assert.Equal(t, "```\nexport TOKEN=abc\n\necho done\n```", paragraphs[5].text)
+	assert.False(t, paragraphs[5].heading)
+}
+
+func TestSearchParagraphs_Keywords(t *testing.T) {
+	match, err := newSearchMatcher("Token key", false)
+	require.NoError(t, err)
+
+	matches := searchParagraphs(searchDocument, match, 1)
+	require.Len(t, matches, 4)
+
+	// Both keywords rank first, then by number of matches, then by position
+	assert.Equal(t, "Rotate the token and the key often. The token expires.", matches[0].Text)
+	assert.Equal(t, 3, matches[0].Score)
+	assert.Equal(t, []string{"Guide", "Auth"}, matches[0].HeadingPath)
+	assert.Equal(t, []string{"Send a bearer token."}, matches[0].Before)
+	assert.Equal(t, []string{"```\nexport TOKEN=abc\n\necho done\n```"}, matches[0].After)
+	assert.Equal(t, searchDocument[matches[0].Offset:matches[0].Offset+matches[0].Length], matches[0].Text)
+
+	assert.Equal(t, "Intro about tokens.", matches[1].Text)
+	assert.Equal(t, []string{"Guide"}, matches[1].HeadingPath)
+	assert.Equal(t, "Send a bearer token.", matches[2].Text)
+	assert.True(t, strings.HasPrefix(matches[3].Text, "```"))
+}
+
+func TestSearchParagraphs_Regex(t *testing.T) {
+	match, err := newSearchMatcher(`(?i)token\b`, true)
+	require.NoError(t, err)
+
+	matches := searchParagraphs(searchDocument, match, 0)
+	require.Len(t, matches, 3)
+	assert.Equal(t, 2, matches[0].Score)
+	assert.Nil(t, matches[0].Before)
+	assert.Nil(t, matches[0].After)
+
+	_, err = newSearchMatcher("(", true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid query pattern")
+
+	_, err = newSearchMatcher("  ", false)
+	require.Error(t, err)
+}
+
+func TestHTTPFetcher_FetchSearch(t *testing.T) {
+	mockResponses := map[string]mockResponse{
+		"/api":   {Body: outlinePageHTML, ContentType: "text/html", StatusCode: http.StatusOK},
+		"/notes": {Body: "first note\n\nsecond note about limits", ContentType: "text/plain", StatusCode: http.StatusOK},
+		"/image": {Body: "binary", ContentType: "image/png", StatusCode: http.StatusOK},
+	}
+	server := startMockServer(t, mockResponses)
+	fetcher := newTestFetcher(t, server.URL)
+
+	resp, err := fetcher.FetchSearch(server.URL+"/api", SearchOptions{Query: "JSON", Context: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "JSON", resp.Query)
+	require.Equal(t, 1, resp.Total)
+	match := resp.Matches[0]
+	assert.Equal(t, []string{"API Guide", "Errors"}, match.HeadingPath)
+	assert.Equal(t, []string{"## Errors"}, match.Before)
+
+	// The offset can be used as start_index with fetch
+	page, err := fetcher.FetchWithOptions(server.URL+"/api", FetchOptions{StartIndex: match.Offset, MaxLength: match.Length})
+	require.NoError(t, err)
+	assert.Equal(t, match.Text, page.Content)
+
+	resp, err = fetcher.FetchSearch(server.URL+"/api", SearchOptions{Query: "long enough", MaxResults: 2})
+	require.NoError(t, err)
+	assert.Greater(t, resp.Total, 2)
+	assert.Len(t, resp.Matches, 2)
+
+	resp, err = fetcher.FetchSearch(server.URL+"/notes", SearchOptions{Query: "limits"})
+	require.NoError(t, err)
+	require.Len(t, resp.Matches, 1)
+	assert.Equal(t, "second note about limits", resp.Matches[0].Text)
+
+	_, err = fetcher.FetchSearch(server.URL+"/image", SearchOptions{Query: "x"})
+	require.Error(t, err)
+}
@@ -0,0 +1,131 @@
+package fetcher
+
+import (
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/mackee/go-readability"
+	"go.uber.org/zap"
+	"golang.org/x/net/html"
+)
+
+// selectionSeparator separates the output of individual selector matches.
+const selectionSeparator = "\n\n---\n\n"
+
+// selectHTMLContent scopes an HTML document to the elements matching selector,
+// after removing every element matching one of excludeSelectors.
+// Each match is converted to format (or kept as HTML when raw is set) and the
+// results are joined in document order. If selector is empty, the cleaned
+// document is passed through the regular readability pipeline instead.
+// It returns the processed content and the number of matched elements.
+func selectHTMLContent(body string, urlStr string, selector string, excludeSelectors []string, raw bool, format Format) (string, int, error) {
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return "", 0, ierrors.Wrap(err, "failed to parse HTML")
+	}
+
+	for _, exclude := range excludeSelectors {
+		if strings.TrimSpace(exclude) == "" {
+			continue
+		}
+		sel, err := cascadia.Compile(exclude)
+		if err != nil {
+			return "", 0, ierrors.WithKind(ierrors.Wrapf(err, "invalid exclude selector %q", exclude), ierrors.ErrInvalidArgument)
+		}
+		for _, n := range cascadia.QueryAll(doc, sel) {
+			if n.Parent != nil {
+				n.Parent.RemoveChild(n)
+			}
+		}
+	}
+
+	if selector == "" {
+		cleaned, err := renderHTML(doc)
+		if err != nil {
+			return "", 0, err
+		}
+		if raw {
+			return cleaned, 0, nil
+		}
+		return formatHTMLContent(cleaned, urlStr, format), 0, nil
+	}
+
+	sel, err := cascadia.Compile(selector)
+	if err != nil {
+		return "", 0, ierrors.WithKind(ierrors.Wrapf(err, "invalid selector %q", selector), ierrors.ErrInvalidArgument)
+	}
+	matches := outermostNodes(cascadia.QueryAll(doc, sel))
+	if len(matches) == 0 {
+		return "", 0, ierrors.Newf(ierrors.ErrInvalidArgument, "selector %q matched no elements", selector)
+	}
+
+	parts := make([]string, 0, len(matches))
+	for _, n := range matches {
+		fragment, err := renderHTML(n)
+		if err != nil {
+			return "", 0, err
+		}
+		if raw {
+			parts = append(parts, fragment)
+			continue
+		}
+		formatted, err := formatFragment(fragment, format)
+		if err != nil {
+			return "", 0, err
+		}
+		if formatted != "" {
+			parts = append(parts, formatted)
+		}
+	}
+
+	zap.S().Debugw("scoped HTML content with selector",
+		"url", urlStr,
+		"selector", selector,
+		"exclude_selectors", excludeSelectors,
+		"matches", len(matches))
+
+	return strings.Join(parts, selectionSeparator), len(matches), nil
+}
+
+// outermostNodes drops nodes that are descendants of another node in the list,
+// so nested matches are not emitted twice.
+func outermostNodes(nodes []*html.Node) []*html.Node {
+	set := make(map[*html.Node]bool, len(nodes))
+	for _, n := range nodes {
+		set[n] = true
+	}
+	result := make([]*html.Node, 0, len(nodes))
+	for _, n := range nodes {
+		nested := false
+		for p := n.Parent; p != nil; p = p.Parent {
+			if set[p] {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// renderHTML serializes a node back to an HTML string.
+func renderHTML(n *html.Node) (string, error) {
+	var sb strings.Builder
+	if err := html.Render(&sb, n); err != nil {
+		return "", ierrors.Wrap(err, "failed to render HTML")
+	}
+	return sb.String(), nil
+}
+
+// htmlFragmentToMarkdown converts an HTML fragment to Markdown without running
+// readability's main-content detection.
+func htmlFragmentToMarkdown(fragment string) (string, error) {
+	doc, err := readability.ParseHTML(fragment, "")
+	if err != nil {
+		return "", ierrors.Wrap(err, "failed to parse HTML fragment")
+	}
+	return readability.ToMarkdown(doc.Body), nil
+}
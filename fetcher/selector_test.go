@@ -0,0 +1,85 @@
+package fetcher
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const selectorPageHTML = `<html><head><title>App</title></head><body>
+<nav><a href="/">Home</a></nav>
+<main>
+  <article class="card"><h2>First</h2><p>Alpha text.</p><div class="ad">Buy now</div></article>
+  <article class="card"><h2>Second</h2><p>Beta text.</p></article>
+</main>
+</body></html>`
+
+func TestSelectHTMLContent(t *testing.T) {
+	content, matches, err := selectHTMLContent(selectorPageHTML, "https://example.com", "main .card", []string{".ad"}, false, FormatMarkdown)
+	require.NoError(t, err)
+	assert.Equal(t, 2, matches)
+	assert.Equal(t, "## First\n\nAlpha text.\n\n---\n\n## Second\n\nBeta text.", content)
+}
+
+func TestSelectHTMLContent_NestedMatchesAreNotDuplicated(t *testing.T) {
+	body := `<html><body><div class="x"><p>Outer</p><div class="x"><p>Inner</p></div></div></body></html>`
+	content, matches, err := selectHTMLContent(body, "https://example.com", ".x", nil, false, FormatMarkdown)
+	require.NoError(t, err)
+	assert.Equal(t, 1, matches)
+	assert.Equal(t, 1, strings.Count(content, "Inner"))
+}
+
+func TestSelectHTMLContent_Raw(t *testing.T) {
+	content, matches, err := selectHTMLContent(selectorPageHTML, "https://example.com", "article:last-child h2", nil, true, FormatMarkdown)
+	require.NoError(t, err)
+	assert.Equal(t, 1, matches)
+	assert.Equal(t, "<h2>Second</h2>", content)
+}
+
+func TestSelectHTMLContent_Errors(t *testing.T) {
+	_, _, err := selectHTMLContent(selectorPageHTML, "https://example.com", "section.missing", nil, false, FormatMarkdown)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "matched no elements")
+	assert.ErrorIs(t, err, ierrors.ErrInvalidArgument)
+
+	_, _, err = selectHTMLContent(selectorPageHTML, "https://example.com", "main[", nil, false, FormatMarkdown)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid selector")
+	assert.ErrorIs(t, err, ierrors.ErrInvalidArgument)
+
+	_, _, err = selectHTMLContent(selectorPageHTML, "https://example.com", "main", []string{"::"}, false, FormatMarkdown)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid exclude selector")
+	assert.ErrorIs(t, err, ierrors.ErrInvalidArgument)
+}
+
+func TestHTTPFetcher_FetchWithOptions_Selector(t *testing.T) {
+	mockResponses := map[string]mockResponse{
+		"/app":   {Body: selectorPageHTML, ContentType: "text/html", StatusCode: http.StatusOK},
+		"/plain": {Body: "plain text", ContentType: "text/plain", StatusCode: http.StatusOK},
+	}
+	server := startMockServer(t, mockResponses)
+	fetcher := newTestFetcher(t, server.URL)
+
+	resp, err := fetcher.FetchWithOptions(server.URL+"/app", FetchOptions{
+		MaxLength:        100,
+		Selector:         "article",
+		ExcludeSelectors: []string{".ad"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.SelectorMatches)
+	assert.Contains(t, resp.Content, "Alpha text.")
+	assert.Contains(t, resp.Content, "Beta text.")
+	assert.NotContains(t, resp.Content, "Buy now")
+	assert.NotContains(t, resp.Content, "Home")
+
+	// Selectors are ignored for non-HTML content
+	resp, err = fetcher.FetchWithOptions(server.URL+"/plain", FetchOptions{Selector: "article"})
+	require.NoError(t, err)
+	assert.Equal(t, "plain text", resp.Content)
+	assert.Zero(t, resp.SelectorMatches)
+}
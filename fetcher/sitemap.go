@@ -0,0 +1,315 @@
+package fetcher
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultSitemapLimit is the number of entries returned when SitemapOptions.Limit is not set.
+	DefaultSitemapLimit = 100
+	// MaxSitemapLimit is the upper bound for SitemapOptions.Limit.
+	MaxSitemapLimit = 1000
+	// maxSitemapFiles caps the number of sitemap files read per call.
+	maxSitemapFiles = 50
+	// maxSitemapDepth caps how deeply nested sitemap indexes are followed.
+	maxSitemapDepth = 3
+	// maxSitemapSize is the largest uncompressed sitemap accepted (sitemaps.org limit).
+	maxSitemapSize = 50 * 1024 * 1024
+	// defaultSitemapPath is tried when robots.txt declares no sitemaps.
+	defaultSitemapPath = "/sitemap.xml"
+)
+
+// SitemapOptions controls discovery and filtering for FetchSitemap.
+type SitemapOptions struct {
+	PathPrefix string // Only return URLs whose path starts with this prefix
+	Pattern    string // Regular expression URLs must match
+	// ModifiedSince only returns URLs with a lastmod at or after this time.
+	// URLs without a lastmod are excluded when it is set.
+	ModifiedSince time.Time
+	Limit         int // Maximum number of entries returned (default DefaultSitemapLimit)
+}
+
+// sitemapDocument is a <urlset> or <sitemapindex> document.
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapURL `xml:"url"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// sitemapURL is a <url> or <sitemap> element.
+type sitemapURL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod"`
+	ChangeFreq string `xml:"changefreq"`
+	Priority   string `xml:"priority"`
+}
+
+// w3cDateLayouts are the W3C Datetime formats allowed for lastmod.
+var w3cDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// ParseSitemapDate parses a W3C Datetime as used by sitemap lastmod values,
+// from a bare year up to a full timestamp with time zone.
+func ParseSitemapDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range w3cDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, ierrors.Newf(ierrors.ErrInvalidArgument, "invalid date %q (expected a W3C datetime such as 2024-01-31 or 2024-01-31T12:00:00Z)", s)
+}
+
+// FetchSitemap lists the URLs published in a site's sitemaps. urlStr may be a
+// sitemap itself or any page of the site, in which case sitemaps are
+// discovered through robots.txt, falling back to /sitemap.xml. Sitemap indexes
+// are expanded and gzipped sitemaps decompressed.
+func (f *httpFetcher) FetchSitemap(urlStr string, opts SitemapOptions) (*types.SitemapResponse, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultSitemapLimit
+	}
+	if opts.Limit > MaxSitemapLimit {
+		opts.Limit = MaxSitemapLimit
+	}
+
+	zap.S().Debugw("fetching sitemap",
+		"url", urlStr,
+		"path_prefix", opts.PathPrefix,
+		"pattern", opts.Pattern,
+		"modified_since", opts.ModifiedSince,
+		"limit", opts.Limit)
+
+	u, err := url.Parse(urlStr)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ierrors.Newf(ierrors.ErrInvalidArgument, "invalid URL %q", urlStr)
+	}
+	var pattern *regexp.Regexp
+	if opts.Pattern != "" {
+		if pattern, err = regexp.Compile(opts.Pattern); err != nil {
+			return nil, ierrors.WithKind(ierrors.Wrapf(err, "invalid pattern %q", opts.Pattern), ierrors.ErrInvalidArgument)
+		}
+	}
+
+	sitemaps := f.discoverSitemaps(u)
+	seen := make(map[string]bool)
+	for _, s := range sitemaps {
+		seen[s] = true
+	}
+
+	response := &types.SitemapResponse{
+		URL:      urlStr,
+		Sitemaps: []string{},
+		Entries:  []types.SitemapEntry{},
+		URLs:     []string{},
+	}
+	listed := make(map[string]bool)
+	read := 0
+	var firstErr error
+
+	// Sitemap indexes are expanded level by level, fetching each level in parallel
+	for depth := 0; len(sitemaps) > 0 && depth <= maxSitemapDepth; depth++ {
+		if slots := maxSitemapFiles - read; len(sitemaps) > slots {
+			zap.S().Warnw("too many sitemaps, skipping the rest", "url", urlStr, "skipped", len(sitemaps)-slots)
+			sitemaps = sitemaps[:slots]
+		}
+		read += len(sitemaps)
+
+		next := []string{}
+		for i, res := range f.fetchAll(context.Background(), sitemaps) {
+			doc, err := parseSitemap(res)
+			if err != nil {
+				if response.Errors == nil {
+					response.Errors = make(map[string]string)
+				}
+				response.Errors[sitemaps[i]] = err.Error()
+				if firstErr == nil {
+					firstErr = ierrors.Wrapf(err, "failed to read sitemap %s", sitemaps[i])
+				}
+				continue
+			}
+			response.Sitemaps = append(response.Sitemaps, sitemaps[i])
+
+			for _, child := range doc.Sitemaps {
+				loc := strings.TrimSpace(child.Loc)
+				if loc == "" || seen[loc] || !sitemapModifiedSince(child.LastMod, opts.ModifiedSince) {
+					continue
+				}
+				seen[loc] = true
+				next = append(next, loc)
+			}
+
+			for _, item := range doc.URLs {
+				entry, ok := sitemapEntry(item)
+				if !ok || listed[entry.URL] || !sitemapEntryMatches(entry, opts, pattern) {
+					continue
+				}
+				listed[entry.URL] = true
+				response.Total++
+				if len(response.Entries) < opts.Limit {
+					response.Entries = append(response.Entries, entry)
+					response.URLs = append(response.URLs, entry.URL)
+				}
+			}
+		}
+		sitemaps = next
+	}
+
+	if len(response.Sitemaps) == 0 {
+		return nil, firstErr
+	}
+
+	zap.S().Infow("fetched sitemap",
+		"url", urlStr,
+		"sitemaps", len(response.Sitemaps),
+		"errors", len(response.Errors),
+		"total", response.Total,
+		"returned", len(response.Entries))
+
+	return response, nil
+}
+
+// discoverSitemaps returns the sitemaps to read for u: u itself if it looks
+// like a sitemap, else those declared in robots.txt or /sitemap.xml.
+func (f *httpFetcher) discoverSitemaps(u *url.URL) []string {
+	path := strings.ToLower(u.Path)
+	if strings.HasSuffix(path, ".xml") || strings.HasSuffix(path, ".xml.gz") || strings.HasSuffix(path, ".txt") {
+		return []string{u.String()}
+	}
+
+	if rules, err := f.robots.rules(context.Background(), u); err != nil {
+		zap.S().Warnw("failed to read robots.txt for sitemap discovery", "url", u.String(), "error", err)
+	} else if declared := rules.Sitemaps(); len(declared) > 0 {
+		sitemaps := []string{}
+		seen := make(map[string]bool)
+		for _, s := range declared {
+			if !seen[s] {
+				seen[s] = true
+				sitemaps = append(sitemaps, s)
+			}
+		}
+		return sitemaps
+	}
+	return []string{u.Scheme + "://" + u.Host + defaultSitemapPath}
+}
+
+// parseSitemap decodes a fetched sitemap: an XML urlset or sitemap index,
+// optionally gzipped, or a plain text list of URLs.
+func parseSitemap(res *fetchResponse) (*sitemapDocument, error) {
+	if res.err != nil {
+		return nil, res.err
+	}
+	if res.status < 200 || res.status >= 300 {
+		return nil, ierrors.HTTPStatus(res.status)
+	}
+
+	body := []byte(res.body)
+	// Gzipped sitemaps are usually served as application/gzip rather than
+	// with a Content-Encoding the HTTP client would undo, so check the magic bytes
+	if len(body) >= 2 && body[0] == 0x1f && body[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, ierrors.Wrap(err, "failed to decompress sitemap")
+		}
+		body, err = io.ReadAll(io.LimitReader(zr, maxSitemapSize+1))
+		if err != nil {
+			return nil, ierrors.Wrap(err, "failed to decompress sitemap")
+		}
+		if len(body) > maxSitemapSize {
+			return nil, ierrors.Newf(ierrors.ErrTooLarge, "decompressed sitemap exceeds %d bytes", maxSitemapSize)
+		}
+	}
+
+	if strings.HasPrefix(res.contentType, "text/plain") {
+		doc := &sitemapDocument{}
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				doc.URLs = append(doc.URLs, sitemapURL{Loc: line})
+			}
+		}
+		return doc, nil
+	}
+
+	doc := &sitemapDocument{}
+	if err := xml.Unmarshal(body, doc); err != nil {
+		return nil, ierrors.Wrap(err, "failed to parse sitemap XML")
+	}
+	if name := doc.XMLName.Local; name != "urlset" && name != "sitemapindex" {
+		return nil, fmt.Errorf("not a sitemap: unexpected root element <%s>", name)
+	}
+	return doc, nil
+}
+
+// sitemapEntry converts a <url> element, reporting false for unusable locations.
+func sitemapEntry(item sitemapURL) (types.SitemapEntry, bool) {
+	loc := strings.TrimSpace(item.Loc)
+	if u, err := url.Parse(loc); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return types.SitemapEntry{}, false
+	}
+	entry := types.SitemapEntry{
+		URL:        loc,
+		LastMod:    strings.TrimSpace(item.LastMod),
+		ChangeFreq: strings.TrimSpace(item.ChangeFreq),
+	}
+	if p, err := strconv.ParseFloat(strings.TrimSpace(item.Priority), 64); err == nil {
+		entry.Priority = &p
+	}
+	return entry, true
+}
+
+// sitemapEntryMatches applies the filters of opts to entry.
+func sitemapEntryMatches(entry types.SitemapEntry, opts SitemapOptions, pattern *regexp.Regexp) bool {
+	if opts.PathPrefix != "" {
+		u, _ := url.Parse(entry.URL)
+		path := u.Path
+		if path == "" {
+			path = "/"
+		}
+		if !strings.HasPrefix(path, opts.PathPrefix) {
+			return false
+		}
+	}
+	if pattern != nil && !pattern.MatchString(entry.URL) {
+		return false
+	}
+	if !opts.ModifiedSince.IsZero() {
+		t, err := ParseSitemapDate(entry.LastMod)
+		if err != nil || t.Before(opts.ModifiedSince) {
+			return false
+		}
+	}
+	return true
+}
+
+// sitemapModifiedSince reports whether lastmod is not before since. Missing or
+// unparsable values pass, so that sitemap indexes without dates are still expanded.
+func sitemapModifiedSince(lastmod string, since time.Time) bool {
+	if since.IsZero() || lastmod == "" {
+		return true
+	}
+	t, err := ParseSitemapDate(lastmod)
+	if err != nil {
+		return true
+	}
+	return !t.Before(since)
+}
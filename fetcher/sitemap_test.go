@@ -0,0 +1,175 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startSitemapServer serves a site whose robots.txt declares a sitemap index
+// pointing to a plain and a gzipped sitemap. Bodies may use {{base}} for the
+// server URL.
+func startSitemapServer(t *testing.T, withRobots bool) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := server.URL
+		switch r.URL.Path {
+		case "/robots.txt":
+			if !withRobots {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("User-agent: *\nDisallow:\n\nSitemap: " + base + "/sitemap_index.xml\n"))
+		case "/sitemap_index.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>` + base + `/sitemap-docs.xml</loc><lastmod>2024-06-01</lastmod></sitemap>
+  <sitemap><loc>` + base + `/sitemap-blog.xml.gz</loc></sitemap>
+  <sitemap><loc>` + base + `/sitemap-old.xml</loc><lastmod>2020-01-01</lastmod></sitemap>
+  <sitemap><loc>` + base + `/sitemap-missing.xml</loc></sitemap>
+</sitemapindex>`))
+		case "/sitemap-docs.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>` + base + `/docs/intro</loc><lastmod>2024-05-01T10:00:00+00:00</lastmod><changefreq>weekly</changefreq><priority>0.8</priority></url>
+  <url><loc>` + base + `/docs/setup</loc><lastmod>2023-01-15</lastmod></url>
+  <url><loc>` + base + `/docs/intro</loc></url>
+  <url><loc>mailto:docs@example.com</loc></url>
+</urlset>`))
+		case "/sitemap-blog.xml.gz":
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			_, _ = zw.Write([]byte(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>` + base + `/blog/hello</loc><lastmod>2024-02</lastmod><priority>0.3</priority></url>
+</urlset>`))
+			_ = zw.Close()
+			w.Header().Set("Content-Type", "application/gzip")
+			_, _ = w.Write(buf.Bytes())
+		case "/sitemap-old.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<urlset><url><loc>` + base + `/old/page</loc><lastmod>2019-12-31</lastmod></url></urlset>`))
+		case "/sitemap.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<urlset><url><loc>` + base + `/</loc></url></urlset>`))
+		case "/urls.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(base + "/a\n\n" + base + "/b\n"))
+		case "/not-a-sitemap.xml":
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write([]byte(`<rss><channel></channel></rss>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPFetcher_FetchSitemap(t *testing.T) {
+	server := startSitemapServer(t, true)
+	fetcher := newTestFetcher(t, server.URL)
+
+	resp, err := fetcher.FetchSitemap(server.URL+"/docs/", SitemapOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		server.URL + "/sitemap_index.xml",
+		server.URL + "/sitemap-docs.xml",
+		server.URL + "/sitemap-blog.xml.gz",
+		server.URL + "/sitemap-old.xml",
+	}, resp.Sitemaps)
+	assert.Contains(t, resp.Errors[server.URL+"/sitemap-missing.xml"], "HTTP status 404")
+
+	// Duplicates and non-HTTP locations are dropped
+	assert.Equal(t, []string{
+		server.URL + "/docs/intro",
+		server.URL + "/docs/setup",
+		server.URL + "/blog/hello",
+		server.URL + "/old/page",
+	}, resp.URLs)
+	assert.Equal(t, 4, resp.Total)
+
+	intro := resp.Entries[0]
+	assert.Equal(t, "2024-05-01T10:00:00+00:00", intro.LastMod)
+	assert.Equal(t, "weekly", intro.ChangeFreq)
+	require.NotNil(t, intro.Priority)
+	assert.Equal(t, 0.8, *intro.Priority)
+	assert.Nil(t, resp.Entries[1].Priority)
+}
+
+func TestHTTPFetcher_FetchSitemap_Filters(t *testing.T) {
+	server := startSitemapServer(t, true)
+	fetcher := newTestFetcher(t, server.URL)
+
+	resp, err := fetcher.FetchSitemap(server.URL, SitemapOptions{PathPrefix: "/docs/"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{server.URL + "/docs/intro", server.URL + "/docs/setup"}, resp.URLs)
+
+	resp, err = fetcher.FetchSitemap(server.URL, SitemapOptions{Pattern: `/(blog|old)/`, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{server.URL + "/blog/hello"}, resp.URLs)
+	assert.Equal(t, 2, resp.Total)
+
+	// Child sitemaps modified before the date are not read at all
+	since, err := ParseSitemapDate("2024-01-01")
+	require.NoError(t, err)
+	resp, err = fetcher.FetchSitemap(server.URL, SitemapOptions{ModifiedSince: since})
+	require.NoError(t, err)
+	assert.Equal(t, []string{server.URL + "/docs/intro", server.URL + "/blog/hello"}, resp.URLs)
+	assert.NotContains(t, resp.Sitemaps, server.URL+"/sitemap-old.xml")
+
+	_, err = fetcher.FetchSitemap(server.URL, SitemapOptions{Pattern: "("})
+	require.Error(t, err)
+	_, err = fetcher.FetchSitemap("ftp://example.com/", SitemapOptions{})
+	require.Error(t, err)
+}
+
+func TestHTTPFetcher_FetchSitemap_Discovery(t *testing.T) {
+	server := startSitemapServer(t, false)
+	fetcher := newTestFetcher(t, server.URL)
+
+	// Without robots.txt, /sitemap.xml is used
+	resp, err := fetcher.FetchSitemap(server.URL+"/some/page", SitemapOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{server.URL + "/sitemap.xml"}, resp.Sitemaps)
+	assert.Equal(t, []string{server.URL + "/"}, resp.URLs)
+
+	// Sitemap URLs are read directly, including plain text sitemaps
+	resp, err = fetcher.FetchSitemap(server.URL+"/urls.txt", SitemapOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{server.URL + "/a", server.URL + "/b"}, resp.URLs)
+
+	_, err = fetcher.FetchSitemap(server.URL+"/not-a-sitemap.xml", SitemapOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a sitemap")
+
+	_, err = fetcher.FetchSitemap(server.URL+"/sitemap-missing.xml", SitemapOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP status 404")
+	assert.True(t, errors.Is(err, ierrors.ErrHTTPStatus))
+	assert.False(t, ierrors.IsRetryable(err))
+}
+
+func TestParseSitemapDate(t *testing.T) {
+	for _, s := range []string{"2024", "2024-05", "2024-05-01", "2024-05-01T10:00+02:00", "2024-05-01T10:00:00Z", "2024-05-01T10:00:00.5-05:00"} {
+		_, err := ParseSitemapDate(s)
+		assert.NoError(t, err, s)
+	}
+	_, err := ParseSitemapDate("yesterday")
+	assert.Error(t, err)
+
+	t1, _ := ParseSitemapDate("2024-05-01")
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), t1)
+}
@@ -0,0 +1,165 @@
+package fetcher
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cnosuke/mcp-fetch/types"
+)
+
+// Defaults of Snapshots.
+const (
+	DefaultSnapshotURLs    = 100
+	DefaultSnapshotsPerURL = 5
+)
+
+// Snapshots keeps the processed content of recently fetched URLs, a few
+// versions per URL, to diff later fetches against. URLs not fetched for the
+// longest time are dropped first. It is safe for concurrent use.
+type Snapshots struct {
+	mu      sync.Mutex
+	maxURLs int
+	perURL  int
+	byURL   map[string][]snapshot // Oldest first
+	order   []string              // URLs, least recently recorded first
+}
+
+// snapshot is one version of a document.
+type snapshot struct {
+	content   string
+	fetchedAt time.Time
+}
+
+// NewSnapshots creates a Snapshots keeping perURL versions of maxURLs URLs.
+// Settings that are not positive select DefaultSnapshotURLs and
+// DefaultSnapshotsPerURL.
+func NewSnapshots(maxURLs int, perURL int) *Snapshots {
+	if maxURLs <= 0 {
+		maxURLs = DefaultSnapshotURLs
+	}
+	if perURL <= 0 {
+		perURL = DefaultSnapshotsPerURL
+	}
+	return &Snapshots{
+		maxURLs: maxURLs,
+		perURL:  perURL,
+		byURL:   make(map[string][]snapshot),
+	}
+}
+
+// List returns the times the stored versions of urlStr were fetched, most
+// recent first.
+func (s *Snapshots) List(urlStr string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions := s.byURL[urlStr]
+	times := make([]string, 0, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		times = append(times, formatSnapshotTime(versions[i].fetchedAt))
+	}
+	return times
+}
+
+// latest returns the most recent version of urlStr fetched at or before t, or
+// the most recent version if t is zero.
+func (s *Snapshots) latest(urlStr string, t time.Time) (snapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions := s.byURL[urlStr]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if t.IsZero() || !versions[i].fetchedAt.After(t) {
+			return versions[i], true
+		}
+	}
+	return snapshot{}, false
+}
+
+// record stores a version of urlStr. A version identical to the most recent
+// one is not stored again, so that its fetch time is when it first appeared.
+func (s *Snapshots) record(urlStr string, content string, fetchedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := s.byURL[urlStr]
+	if len(versions) > 0 && versions[len(versions)-1].content == content {
+		return
+	}
+	versions = append(versions, snapshot{content: content, fetchedAt: fetchedAt})
+	if len(versions) > s.perURL {
+		versions = versions[len(versions)-s.perURL:]
+	}
+	s.byURL[urlStr] = versions
+
+	order := make([]string, 0, len(s.order)+1)
+	for _, u := range s.order {
+		if u != urlStr {
+			order = append(order, u)
+		}
+	}
+	order = append(order, urlStr)
+	for len(order) > s.maxURLs {
+		delete(s.byURL, order[0])
+		order = order[1:]
+	}
+	s.order = order
+}
+
+// snapshotFetcher records the complete documents returned by the Fetcher it
+// wraps as snapshots.
+type snapshotFetcher struct {
+	Fetcher
+	snapshots *Snapshots
+}
+
+// RecordSnapshots returns a Fetcher that behaves like f and records the
+// documents returned by Fetch and FetchWithOptions in snapshots, when they
+// are complete and processed the default way (no offset, selector, section,
+// raw mode or other format), so that fetch_diff can compare against them.
+func RecordSnapshots(f Fetcher, snapshots *Snapshots) Fetcher {
+	return &snapshotFetcher{Fetcher: f, snapshots: snapshots}
+}
+
+// WithGuard implements Fetcher.
+func (f *snapshotFetcher) WithGuard(guard URLGuard) Fetcher {
+	return &snapshotFetcher{Fetcher: f.Fetcher.WithGuard(guard), snapshots: f.snapshots}
+}
+
+// Fetch implements Fetcher.
+func (f *snapshotFetcher) Fetch(urlStr string, maxLength int, startIndex int, raw bool) (*types.FetchResponse, error) {
+	resp, err := f.Fetcher.Fetch(urlStr, maxLength, startIndex, raw)
+	if startIndex == 0 && !raw {
+		f.recordResponse(resp)
+	}
+	return resp, err
+}
+
+// FetchWithOptions implements Fetcher.
+func (f *snapshotFetcher) FetchWithOptions(urlStr string, opts FetchOptions) (*types.FetchResponse, error) {
+	resp, err := f.Fetcher.FetchWithOptions(urlStr, opts)
+	if isDefaultProcessing(opts) {
+		f.recordResponse(resp)
+	}
+	return resp, err
+}
+
+// isDefaultProcessing reports whether opts return the whole document as
+// fetch_diff processes it.
+func isDefaultProcessing(opts FetchOptions) bool {
+	return opts.StartIndex == 0 && opts.StartToken == 0 && !opts.Raw &&
+		(opts.Format == "" || opts.Format == FormatMarkdown) &&
+		opts.Selector == "" && len(opts.ExcludeSelectors) == 0 && opts.Section == ""
+}
+
+// recordResponse records the content of a fetch, if it succeeded and was not
+// truncated or scoped to a section.
+func (f *snapshotFetcher) recordResponse(resp *types.FetchResponse) {
+	if resp == nil || resp.NotModified || resp.Section != "" || resp.Tokens != resp.TotalTokens {
+		return
+	}
+	for _, flag := range resp.Flags {
+		if flag == FlagHTTPError {
+			return
+		}
+	}
+	f.snapshots.record(resp.URL, resp.Content, time.Now())
+}
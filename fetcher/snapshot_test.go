@@ -0,0 +1,67 @@
+package fetcher
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshots(t *testing.T) {
+	snapshots := NewSnapshots(2, 2)
+	base := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	snapshots.record("https://example.com/a", "v1", base)
+	snapshots.record("https://example.com/a", "v1", base.Add(time.Hour)) // Unchanged: not stored again
+	snapshots.record("https://example.com/a", "v2", base.Add(2*time.Hour))
+	snapshots.record("https://example.com/a", "v3", base.Add(3*time.Hour))
+	assert.Equal(t, []string{"2024-01-31T15:00:00Z", "2024-01-31T14:00:00Z"}, snapshots.List("https://example.com/a"))
+
+	latest, ok := snapshots.latest("https://example.com/a", time.Time{})
+	require.True(t, ok)
+	assert.Equal(t, "v3", latest.content)
+	latest, ok = snapshots.latest("https://example.com/a", base.Add(150*time.Minute))
+	require.True(t, ok)
+	assert.Equal(t, "v2", latest.content)
+	_, ok = snapshots.latest("https://example.com/a", base)
+	assert.False(t, ok, "v1 was dropped")
+
+	// The URL recorded least recently is dropped first
+	snapshots.record("https://example.com/b", "b", base)
+	snapshots.record("https://example.com/a", "v4", base.Add(4*time.Hour))
+	snapshots.record("https://example.com/c", "c", base)
+	assert.Empty(t, snapshots.List("https://example.com/b"))
+	assert.Len(t, snapshots.List("https://example.com/a"), 2)
+	assert.Len(t, snapshots.List("https://example.com/c"), 1)
+
+	assert.Equal(t, DefaultSnapshotURLs, NewSnapshots(0, 0).maxURLs)
+	assert.Equal(t, DefaultSnapshotsPerURL, NewSnapshots(0, 0).perURL)
+}
+
+func TestRecordSnapshots(t *testing.T) {
+	server := startMockServer(t, map[string]mockResponse{
+		"/a": {Body: "Page A has some words", ContentType: "text/plain", StatusCode: http.StatusOK},
+		"/b": {Body: "Page B has some words", ContentType: "text/plain", StatusCode: http.StatusOK},
+		"/c": {Body: "Page C has some words", ContentType: "text/plain", StatusCode: http.StatusOK},
+		"/d": {Body: "Gone", ContentType: "text/plain", StatusCode: http.StatusNotFound},
+	})
+	snapshots := NewSnapshots(10, 5)
+	fetcher := RecordSnapshots(newTestFetcherWithTokenizer(t, "heuristic"), snapshots)
+
+	_, err := fetcher.FetchWithOptions(server.URL+"/a", FetchOptions{})
+	require.NoError(t, err)
+	_, err = fetcher.FetchWithOptions(server.URL+"/b", FetchOptions{MaxLength: 6})
+	require.NoError(t, err)
+	_, err = fetcher.FetchWithOptions(server.URL+"/c", FetchOptions{Raw: true})
+	require.NoError(t, err)
+	_, err = fetcher.FetchWithOptions(server.URL+"/d", FetchOptions{})
+	require.NoError(t, err)
+
+	// Truncated, raw and error pages are not recorded
+	assert.Len(t, snapshots.List(server.URL+"/a"), 1)
+	assert.Empty(t, snapshots.List(server.URL+"/b"))
+	assert.Empty(t, snapshots.List(server.URL+"/c"))
+	assert.Empty(t, snapshots.List(server.URL+"/d"))
+}
@@ -0,0 +1,366 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"go.uber.org/zap"
+)
+
+// Defaults of SummaryOptions.
+const (
+	DefaultSummaryChunkLength = 12000
+	DefaultSummaryTokens      = 800
+)
+
+const (
+	// minSummaryChunkLength is the smallest chunk length accepted, so that a
+	// document is not split into a huge number of sampling requests.
+	minSummaryChunkLength = 1000
+	// maxSummaryChunks caps the chunks of a document; longer documents are
+	// summarized from their first maxSummaryChunks chunks.
+	maxSummaryChunks = 30
+	// maxReduceRounds bounds the rounds of combining partial summaries.
+	maxReduceRounds = 6
+)
+
+// summaryCitationPattern matches the section markers cited in summaries.
+var summaryCitationPattern = regexp.MustCompile(`\[S(\d+)\]`)
+
+// SampleRequest is a prompt sent to a language model.
+type SampleRequest struct {
+	SystemPrompt string
+	Prompt       string
+	MaxTokens    int // Maximum tokens of the completion
+}
+
+// SampleResult is the completion of a SampleRequest.
+type SampleResult struct {
+	Text  string
+	Model string // Name of the model that generated it, if known
+}
+
+// Sampler asks a language model, typically the MCP client's through
+// sampling/createMessage, to complete a prompt.
+type Sampler interface {
+	Sample(ctx context.Context, request SampleRequest) (*SampleResult, error)
+}
+
+// SummaryOptions controls how FetchSummary chunks and summarizes a page.
+type SummaryOptions struct {
+	Sampler     Sampler // Required
+	Focus       string  // Topic or question the summary should focus on
+	ChunkLength int     // Maximum characters of content per sampling request (default DefaultSummaryChunkLength)
+	MaxTokens   int     // Maximum tokens of each summary (default DefaultSummaryTokens)
+	// Progress, if set, receives the number of sampling requests completed.
+	Progress ProgressFunc
+}
+
+// summaryPart is a section of the document, labelled for citation.
+type summaryPart struct {
+	section types.SummarySection
+	text    string
+}
+
+// FetchSummary fetches a page, splits its Markdown content by section into
+// chunks and has opts.Sampler summarize them map-reduce style: each chunk is
+// summarized, then the partial summaries are combined. Summaries cite the
+// sections they draw from as [S1], [S2], ..., listed in the response.
+func (f *httpFetcher) FetchSummary(ctx context.Context, urlStr string, opts SummaryOptions) (*types.SummaryResponse, error) {
+	zap.S().Debugw("summarizing URL",
+		"url", urlStr,
+		"focus", opts.Focus,
+		"chunk_length", opts.ChunkLength,
+		"max_tokens", opts.MaxTokens)
+
+	if opts.Sampler == nil {
+		return nil, ierrors.New(ierrors.ErrInvalidArgument, "a sampler is required to summarize")
+	}
+	if opts.ChunkLength <= 0 {
+		opts.ChunkLength = DefaultSummaryChunkLength
+	}
+	if opts.ChunkLength < minSummaryChunkLength {
+		opts.ChunkLength = minSummaryChunkLength
+	}
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = DefaultSummaryTokens
+	}
+
+	resp := f.fetchWithContext(ctx, urlStr, requestOptions{})
+	if resp.err != nil {
+		return nil, resp.err
+	}
+	if isHTTPError(resp.status) {
+		return nil, ierrors.HTTPStatus(resp.status)
+	}
+	content, sourceHTML, err := markdownContent(resp, urlStr)
+	if err != nil {
+		// Other text formats are summarized as they are
+		if !strings.HasPrefix(resp.contentType, "text/") {
+			return nil, err
+		}
+		content = resp.body
+	}
+
+	parts := splitSummaryParts(content, documentOutline(content, sourceHTML))
+	chunks := packSummaryChunks(parts, opts.ChunkLength)
+	truncated := false
+	if len(chunks) > maxSummaryChunks {
+		chunks = chunks[:maxSummaryChunks]
+		truncated = true
+	}
+
+	s := &summarizer{sampler: opts.Sampler, url: urlStr, opts: opts}
+	s.total = float64(len(chunks))
+	if len(chunks) > 1 {
+		s.total++ // At least one combining request
+	}
+
+	summaries := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		summary, err := s.sample(ctx, summaryChunkPrompt(urlStr, chunk, i, len(chunks), opts.Focus))
+		if err != nil {
+			return nil, ierrors.Wrapf(err, "failed to summarize chunk %d of %d", i+1, len(chunks))
+		}
+		summaries = append(summaries, summary)
+	}
+	for round := 0; len(summaries) > 1; round++ {
+		if round == maxReduceRounds {
+			return nil, ierrors.Newf(ierrors.ErrTooLarge, "partial summaries still too long after %d rounds", maxReduceRounds)
+		}
+		summaries, err = s.reduce(ctx, summaries)
+		if err != nil {
+			return nil, err
+		}
+	}
+	summary := strings.TrimSpace(summaries[0])
+
+	zap.S().Debugw("summarized URL",
+		"url", urlStr,
+		"chunks", len(chunks),
+		"sampling_requests", s.requests,
+		"summary_length", len(summary))
+
+	return &types.SummaryResponse{
+		URL:              urlStr,
+		StatusCode:       resp.status,
+		OriginalURL:      resp.originalURL,
+		Summary:          summary,
+		Sections:         citedSections(summary, parts),
+		ContentLength:    len(content),
+		Chunks:           len(chunks),
+		SamplingRequests: s.requests,
+		Truncated:        truncated,
+		Model:            s.model,
+	}, nil
+}
+
+// summarizer runs the sampling requests of one FetchSummary call.
+type summarizer struct {
+	sampler  Sampler
+	url      string
+	opts     SummaryOptions
+	requests int
+	total    float64 // Expected requests, for progress
+	model    string
+}
+
+// sample sends one prompt and returns the completion.
+func (s *summarizer) sample(ctx context.Context, prompt string) (string, error) {
+	result, err := s.sampler.Sample(ctx, SampleRequest{
+		SystemPrompt: "You summarize web pages accurately and concisely. Only state what the given text says, and keep the [S#] section citations.",
+		Prompt:       prompt,
+		MaxTokens:    s.opts.MaxTokens,
+	})
+	if err != nil {
+		return "", err
+	}
+	s.requests++
+	if result.Model != "" {
+		s.model = result.Model
+	}
+	if s.opts.Progress != nil {
+		if float64(s.requests) > s.total {
+			s.total = float64(s.requests)
+		}
+		s.opts.Progress(float64(s.requests), s.total, fmt.Sprintf("sampled %d summaries", s.requests))
+	}
+	return result.Text, nil
+}
+
+// reduce combines partial summaries, as many at a time as fit in a chunk.
+func (s *summarizer) reduce(ctx context.Context, summaries []string) ([]string, error) {
+	var groups [][]string
+	length := 0
+	for _, summary := range summaries {
+		if n := len(groups); n > 0 && length+len(summary) <= s.opts.ChunkLength {
+			groups[n-1] = append(groups[n-1], summary)
+			length += len(summary)
+			continue
+		}
+		groups = append(groups, []string{summary})
+		length = len(summary)
+	}
+	if len(groups) == len(summaries) {
+		// No two summaries fit together: combine them in pairs anyway
+		groups = groups[:0]
+		for i := 0; i < len(summaries); i += 2 {
+			groups = append(groups, summaries[i:min(i+2, len(summaries))])
+		}
+	}
+
+	if len(groups) > 1 {
+		s.total += float64(len(groups))
+	}
+	combined := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if len(group) == 1 {
+			combined = append(combined, group[0])
+			continue
+		}
+		summary, err := s.sample(ctx, summaryReducePrompt(s.url, group, s.opts.Focus))
+		if err != nil {
+			return nil, ierrors.Wrap(err, "failed to combine summaries")
+		}
+		combined = append(combined, summary)
+	}
+	return combined, nil
+}
+
+// splitSummaryParts splits content at its headings into parts labelled S1,
+// S2, ... in order. Content before the first heading is a part of its own.
+func splitSummaryParts(content string, headings []*types.Heading) []summaryPart {
+	var parts []summaryPart
+	add := func(section types.SummarySection, text string) {
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		section.ID = fmt.Sprintf("S%d", len(parts)+1)
+		parts = append(parts, summaryPart{section: section, text: text})
+	}
+
+	start := 0
+	if len(headings) > 0 {
+		start = headings[0].Offset
+	}
+	add(types.SummarySection{Heading: "(introduction)"}, content[:start])
+	for i, heading := range headings {
+		end := len(content)
+		if i+1 < len(headings) {
+			end = headings[i+1].Offset
+		}
+		add(types.SummarySection{
+			Heading: heading.Text,
+			Anchor:  heading.Anchor,
+			Level:   heading.Level,
+			Offset:  heading.Offset,
+		}, content[heading.Offset:end])
+	}
+	return parts
+}
+
+// packSummaryChunks groups consecutive parts into chunks of at most
+// chunkLength characters, each part prefixed with its [S#] marker. Parts
+// longer than a chunk are split at paragraph boundaries.
+func packSummaryChunks(parts []summaryPart, chunkLength int) []string {
+	var chunks []string
+	var sb strings.Builder
+	flush := func() {
+		if sb.Len() > 0 {
+			chunks = append(chunks, sb.String())
+			sb.Reset()
+		}
+	}
+	for _, part := range parts {
+		for _, piece := range splitChunkPieces(part.text, chunkLength) {
+			block := fmt.Sprintf("[%s] %s\n\n", part.section.ID, strings.TrimSpace(piece))
+			if sb.Len() > 0 && sb.Len()+len(block) > chunkLength {
+				flush()
+			}
+			sb.WriteString(block)
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitChunkPieces splits text into pieces of at most maxLength characters,
+// at blank lines when possible.
+func splitChunkPieces(text string, maxLength int) []string {
+	if len(text) <= maxLength {
+		return []string{text}
+	}
+	var pieces []string
+	var current strings.Builder
+	for _, paragraph := range strings.SplitAfter(text, "\n\n") {
+		for len(paragraph) > maxLength {
+			// A single paragraph longer than a chunk is cut
+			if current.Len() > 0 {
+				pieces = append(pieces, current.String())
+				current.Reset()
+			}
+			head := truncateText(paragraph, maxLength)
+			pieces = append(pieces, head)
+			paragraph = paragraph[len(head):]
+		}
+		if current.Len()+len(paragraph) > maxLength {
+			pieces = append(pieces, current.String())
+			current.Reset()
+		}
+		current.WriteString(paragraph)
+	}
+	if current.Len() > 0 {
+		pieces = append(pieces, current.String())
+	}
+	return pieces
+}
+
+// summaryChunkPrompt returns the prompt summarizing chunk index of total.
+func summaryChunkPrompt(urlStr string, chunk string, index int, total int, focus string) string {
+	var sb strings.Builder
+	if total == 1 {
+		fmt.Fprintf(&sb, "Summarize the web page %s below.", urlStr)
+	} else {
+		fmt.Fprintf(&sb, "Summarize part %d of %d of the web page %s below.", index+1, total, urlStr)
+	}
+	sb.WriteString(" Each section starts with a marker such as [S3]. Cite the markers of the sections each point comes from, e.g. \"Prices rise in May [S3].\"")
+	if focus != "" {
+		fmt.Fprintf(&sb, " Focus on: %s.", focus)
+	}
+	sb.WriteString("\n\n")
+	sb.WriteString(chunk)
+	return sb.String()
+}
+
+// summaryReducePrompt returns the prompt combining partial summaries.
+func summaryReducePrompt(urlStr string, summaries []string, focus string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Combine the following summaries of consecutive parts of the web page %s into one summary. Remove repetition, keep the key facts and keep the [S#] citations.", urlStr)
+	if focus != "" {
+		fmt.Fprintf(&sb, " Focus on: %s.", focus)
+	}
+	for i, summary := range summaries {
+		fmt.Fprintf(&sb, "\n\n--- Part %d ---\n%s", i+1, strings.TrimSpace(summary))
+	}
+	return sb.String()
+}
+
+// citedSections returns the sections of parts cited in summary, in document
+// order.
+func citedSections(summary string, parts []summaryPart) []types.SummarySection {
+	cited := make(map[string]bool)
+	for _, m := range summaryCitationPattern.FindAllStringSubmatch(summary, -1) {
+		cited["S"+m[1]] = true
+	}
+	sections := []types.SummarySection{}
+	for _, part := range parts {
+		if cited[part.section.ID] {
+			sections = append(sections, part.section)
+		}
+	}
+	return sections
+}
@@ -0,0 +1,94 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSampler answers sampling requests by listing the section markers of the
+// prompt, and records the prompts.
+type stubSampler struct {
+	prompts []string
+	err     error
+}
+
+func (s *stubSampler) Sample(ctx context.Context, request SampleRequest) (*SampleResult, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.prompts = append(s.prompts, request.Prompt)
+	markers := summaryCitationPattern.FindAllString(request.Prompt, -1)
+	return &SampleResult{Text: "Summary citing " + strings.Join(markers, " "), Model: "stub-model"}, nil
+}
+
+func TestHTTPFetcher_FetchSummary(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("# Guide\n\nIntroduction paragraph.\n\n")
+	for i := 1; i <= 3; i++ {
+		fmt.Fprintf(&sb, "## Part %d\n\n%s\n\n", i, strings.Repeat(fmt.Sprintf("Sentence of part %d. ", i), 40))
+	}
+	server := startMockServer(t, map[string]mockResponse{
+		"/guide.md": {Body: sb.String(), ContentType: "text/markdown", StatusCode: http.StatusOK},
+		"/missing":  {Body: "Not found", ContentType: "text/plain", StatusCode: http.StatusNotFound},
+	})
+	fetcher := newTestFetcherWithTokenizer(t, "heuristic")
+
+	sampler := &stubSampler{}
+	var progress []float64
+	resp, err := fetcher.FetchSummary(context.Background(), server.URL+"/guide.md", SummaryOptions{
+		Sampler:     sampler,
+		Focus:       "part 2",
+		ChunkLength: minSummaryChunkLength,
+		Progress:    func(done, total float64, message string) { progress = append(progress, done) },
+	})
+	require.NoError(t, err)
+
+	// Each part fills a chunk; the chunk summaries are then combined
+	assert.Equal(t, 3, resp.Chunks)
+	assert.Equal(t, 4, resp.SamplingRequests)
+	assert.Equal(t, []float64{1, 2, 3, 4}, progress)
+	assert.Contains(t, sampler.prompts[0], "part 1 of 3")
+	assert.Contains(t, sampler.prompts[0], "Focus on: part 2.")
+	assert.Contains(t, sampler.prompts[3], "Combine the following summaries")
+	assert.Equal(t, "stub-model", resp.Model)
+
+	require.Len(t, resp.Sections, 4)
+	assert.Equal(t, "S1", resp.Sections[0].ID)
+	assert.Equal(t, "Guide", resp.Sections[0].Heading)
+	assert.Equal(t, "S3", resp.Sections[2].ID)
+	assert.Equal(t, "Part 2", resp.Sections[2].Heading)
+	assert.Equal(t, "part-2", resp.Sections[2].Anchor)
+	assert.Equal(t, strings.Index(sb.String(), "## Part 2"), resp.Sections[2].Offset)
+
+	_, err = fetcher.FetchSummary(context.Background(), server.URL+"/missing", SummaryOptions{Sampler: sampler})
+	assert.Error(t, err)
+	_, err = fetcher.FetchSummary(context.Background(), server.URL+"/guide.md", SummaryOptions{})
+	assert.ErrorContains(t, err, "sampler is required")
+	_, err = fetcher.FetchSummary(context.Background(), server.URL+"/guide.md", SummaryOptions{Sampler: &stubSampler{err: errors.New("rejected")}})
+	assert.ErrorContains(t, err, "rejected")
+}
+
+func TestPackSummaryChunks(t *testing.T) {
+	paragraph := strings.TrimSpace(strings.Repeat("Long paragraph. ", 6))
+	content := "Intro\n\n# A\n\nShort.\n\n# B\n\n" + paragraph + "\n\nEnd.\n"
+	parts := splitSummaryParts(content, parseMarkdownHeadings(content))
+	require.Len(t, parts, 3)
+	assert.Equal(t, "(introduction)", parts[0].section.Heading)
+	assert.Equal(t, "S3", parts[2].section.ID)
+
+	// Section B does not fit in a chunk and is split at blank lines, each
+	// piece keeping its marker
+	chunks := packSummaryChunks(parts, 100)
+	assert.Equal(t, []string{
+		"[S1] Intro\n\n[S2] # A\n\nShort.\n\n[S3] # B\n\n",
+		"[S3] " + paragraph + "\n\n",
+		"[S3] End.\n\n",
+	}, chunks)
+}
@@ -0,0 +1,259 @@
+package fetcher
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"go.uber.org/zap"
+)
+
+// Defaults of WatcherConfig.
+const (
+	DefaultWatchInterval    = 5 * time.Minute
+	DefaultWatchMinInterval = 30 * time.Second
+	DefaultMaxWatches       = 20
+)
+
+// WatcherConfig holds the settings of a Watcher.
+type WatcherConfig struct {
+	Interval    time.Duration // Polling interval used when Watch is given none
+	MinInterval time.Duration // Shortest polling interval allowed
+	MaxWatches  int           // Maximum number of URLs watched at once
+}
+
+// ChangeFunc receives the meaningful changes detected by a Watcher.
+type ChangeFunc func(change types.WatchChange)
+
+// Watcher polls URLs for meaningful changes to their processed content. Polls
+// are conditional requests, so unchanged pages are not downloaded again, and
+// changed pages are compared line by line after normalizing whitespace. It is
+// safe for concurrent use.
+type Watcher struct {
+	fetcher  Fetcher
+	cfg      WatcherConfig
+	onChange ChangeFunc
+
+	mu      sync.Mutex
+	watches map[string]*watch
+}
+
+// watch is the state of one watched URL.
+type watch struct {
+	status types.WatchStatus
+	lines  []string // Normalized content of the last version seen
+	stop   chan struct{}
+}
+
+// NewWatcher creates a Watcher polling with f and reporting changes to
+// onChange. Zero settings in cfg select the defaults.
+func NewWatcher(f Fetcher, cfg WatcherConfig, onChange ChangeFunc) *Watcher {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultWatchInterval
+	}
+	if cfg.MinInterval <= 0 {
+		cfg.MinInterval = DefaultWatchMinInterval
+	}
+	if cfg.MaxWatches <= 0 {
+		cfg.MaxWatches = DefaultMaxWatches
+	}
+	return &Watcher{
+		fetcher:  f,
+		cfg:      cfg,
+		onChange: onChange,
+		watches:  make(map[string]*watch),
+	}
+}
+
+// Watch starts polling urlStr every interval (the configured default if zero,
+// and no less than the configured minimum). The page is fetched once first,
+// as the baseline changes are detected against. Watching a URL again only
+// changes its interval.
+func (w *Watcher) Watch(urlStr string, interval time.Duration) (*types.WatchStatus, error) {
+	if interval <= 0 {
+		interval = w.cfg.Interval
+	}
+	if interval < w.cfg.MinInterval {
+		interval = w.cfg.MinInterval
+	}
+
+	w.mu.Lock()
+	if existing, ok := w.watches[urlStr]; ok {
+		close(existing.stop)
+		existing.stop = make(chan struct{})
+		existing.status.IntervalSeconds = int(interval / time.Second)
+		status := existing.status
+		go w.poll(urlStr, interval, existing.stop)
+		w.mu.Unlock()
+		zap.S().Infow("watch interval updated", "url", urlStr, "interval", interval)
+		return &status, nil
+	}
+	if len(w.watches) >= w.cfg.MaxWatches {
+		w.mu.Unlock()
+		return nil, ierrors.Newf(ierrors.ErrBlockedByPolicy, "too many watched URLs (max %d)", w.cfg.MaxWatches)
+	}
+	w.mu.Unlock()
+
+	resp, err := w.fetcher.FetchWithOptions(urlStr, FetchOptions{HTTPErrors: HTTPErrorsError})
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to fetch the baseline")
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	wt := &watch{
+		status: types.WatchStatus{
+			URL:             urlStr,
+			IntervalSeconds: int(interval / time.Second),
+			ETag:            resp.ETag,
+			LastModified:    resp.LastModified,
+			WatchedAt:       now,
+			CheckedAt:       now,
+		},
+		lines: normalizeLines(resp.Content),
+		stop:  make(chan struct{}),
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if existing, ok := w.watches[urlStr]; ok {
+		// Watched concurrently: keep the first watch
+		status := existing.status
+		return &status, nil
+	}
+	if len(w.watches) >= w.cfg.MaxWatches {
+		return nil, ierrors.Newf(ierrors.ErrBlockedByPolicy, "too many watched URLs (max %d)", w.cfg.MaxWatches)
+	}
+	w.watches[urlStr] = wt
+	go w.poll(urlStr, interval, wt.stop)
+
+	zap.S().Infow("watching URL", "url", urlStr, "interval", interval)
+	status := wt.status
+	return &status, nil
+}
+
+// Unwatch stops polling urlStr. It reports whether the URL was watched.
+func (w *Watcher) Unwatch(urlStr string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	wt, ok := w.watches[urlStr]
+	if !ok {
+		return false
+	}
+	close(wt.stop)
+	delete(w.watches, urlStr)
+	zap.S().Infow("stopped watching URL", "url", urlStr)
+	return true
+}
+
+// Status returns the state of a watched URL, or nil if it is not watched.
+func (w *Watcher) Status(urlStr string) *types.WatchStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	wt, ok := w.watches[urlStr]
+	if !ok {
+		return nil
+	}
+	status := wt.status
+	return &status
+}
+
+// Watches returns the state of every watched URL, sorted by URL.
+func (w *Watcher) Watches() []types.WatchStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	watches := make([]types.WatchStatus, 0, len(w.watches))
+	for _, wt := range w.watches {
+		watches = append(watches, wt.status)
+	}
+	sort.Slice(watches, func(i, j int) bool { return watches[i].URL < watches[j].URL })
+	return watches
+}
+
+// Close stops polling every watched URL.
+func (w *Watcher) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for urlStr, wt := range w.watches {
+		close(wt.stop)
+		delete(w.watches, urlStr)
+	}
+}
+
+// poll checks urlStr every interval until stop is closed.
+func (w *Watcher) poll(urlStr string, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			w.check(urlStr)
+		}
+	}
+}
+
+// check polls urlStr once and reports a meaningful change to onChange.
+func (w *Watcher) check(urlStr string) {
+	w.mu.Lock()
+	wt, ok := w.watches[urlStr]
+	if !ok {
+		w.mu.Unlock()
+		return
+	}
+	opts := FetchOptions{
+		HTTPErrors:      HTTPErrorsError,
+		IfNoneMatch:     wt.status.ETag,
+		IfModifiedSince: wt.status.LastModified,
+	}
+	w.mu.Unlock()
+
+	resp, err := w.fetcher.FetchWithOptions(urlStr, opts)
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	w.mu.Lock()
+	if w.watches[urlStr] != wt {
+		// Unwatched while polling
+		w.mu.Unlock()
+		return
+	}
+	wt.status.Checks++
+	wt.status.CheckedAt = now
+	if err != nil {
+		zap.S().Warnw("failed to poll watched URL", "url", urlStr, "error", err)
+		wt.status.LastError = newFetchError(fetchStageRequest, err)
+		w.mu.Unlock()
+		return
+	}
+	wt.status.LastError = nil
+	if resp.NotModified {
+		w.mu.Unlock()
+		zap.S().Debugw("watched URL not modified", "url", urlStr)
+		return
+	}
+
+	wt.status.ETag = resp.ETag
+	wt.status.LastModified = resp.LastModified
+	lines := normalizeLines(resp.Content)
+	diff := summarizeDiff(diffTokens(wt.lines, lines))
+	wt.lines = lines
+	if diff.AddedLines == 0 && diff.RemovedLines == 0 {
+		w.mu.Unlock()
+		zap.S().Debugw("watched URL unchanged", "url", urlStr)
+		return
+	}
+	wt.status.Changes++
+	wt.status.ChangedAt = now
+	wt.status.LastChange = diff
+	w.mu.Unlock()
+
+	zap.S().Infow("watched URL changed",
+		"url", urlStr,
+		"added_lines", diff.AddedLines,
+		"removed_lines", diff.RemovedLines)
+	if w.onChange != nil {
+		w.onChange(types.WatchChange{URL: urlStr, Diff: diff, DetectedAt: now})
+	}
+}
@@ -0,0 +1,111 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cnosuke/mcp-fetch/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// versionedPage serves a page with an ETag, answering conditional requests.
+type versionedPage struct {
+	mu          sync.Mutex
+	body        string
+	etag        string
+	notModified int
+}
+
+func (p *versionedPage) set(body string, etag string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.body, p.etag = body, etag
+}
+
+func (p *versionedPage) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if r.URL.Path != "/page" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("ETag", p.etag)
+	if r.Header.Get("If-None-Match") == p.etag {
+		p.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte(p.body))
+}
+
+func TestHTTPFetcher_FetchWithOptions_Conditional(t *testing.T) {
+	page := &versionedPage{body: "Version 1", etag: `"v1"`}
+	server := httptest.NewServer(page)
+	t.Cleanup(server.Close)
+	fetcher := newTestFetcherWithTokenizer(t, "heuristic")
+
+	resp, err := fetcher.FetchWithOptions(server.URL+"/page", FetchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, `"v1"`, resp.ETag)
+	assert.False(t, resp.NotModified)
+
+	resp, err = fetcher.FetchWithOptions(server.URL+"/page", FetchOptions{IfNoneMatch: `"v1"`})
+	require.NoError(t, err)
+	assert.True(t, resp.NotModified)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	assert.Empty(t, resp.Content)
+}
+
+func TestWatcher(t *testing.T) {
+	page := &versionedPage{body: "# Status\n\nAll systems operational\n", etag: `"v1"`}
+	server := httptest.NewServer(page)
+	t.Cleanup(server.Close)
+	urlStr := server.URL + "/page"
+
+	var changes []types.WatchChange
+	watcher := NewWatcher(newTestFetcherWithTokenizer(t, "heuristic"), WatcherConfig{MaxWatches: 1}, func(change types.WatchChange) {
+		changes = append(changes, change)
+	})
+	t.Cleanup(watcher.Close)
+
+	// The interval is raised to the minimum; the test polls by hand
+	status, err := watcher.Watch(urlStr, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int(DefaultWatchMinInterval/time.Second), status.IntervalSeconds)
+	assert.Equal(t, `"v1"`, status.ETag)
+
+	_, err = watcher.Watch(server.URL+"/other", 0)
+	assert.ErrorContains(t, err, "too many watched URLs")
+
+	// Unchanged: answered with 304
+	watcher.check(urlStr)
+	assert.Equal(t, 1, page.notModified)
+	assert.Empty(t, changes)
+
+	// Cosmetic change: whitespace only
+	page.set("# Status\n\n\nAll  systems operational\n", `"v2"`)
+	watcher.check(urlStr)
+	assert.Empty(t, changes)
+
+	page.set("# Status\n\nDegraded performance\n", `"v3"`)
+	watcher.check(urlStr)
+	require.Len(t, changes, 1)
+	assert.Equal(t, urlStr, changes[0].URL)
+	assert.Equal(t, []string{"- All systems operational", "+ Degraded performance"}, changes[0].Diff.Changes)
+
+	status = watcher.Status(urlStr)
+	require.NotNil(t, status)
+	assert.Equal(t, 3, status.Checks)
+	assert.Equal(t, 1, status.Changes)
+	assert.Equal(t, `"v3"`, status.ETag)
+	assert.NotEmpty(t, status.ChangedAt)
+
+	assert.True(t, watcher.Unwatch(urlStr))
+	assert.False(t, watcher.Unwatch(urlStr))
+	assert.Empty(t, watcher.Watches())
+}
@@ -0,0 +1,175 @@
+package errors
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// Kind is a category of failure with a stable, machine-readable code.
+// Kinds are errors themselves, so errors.Is(err, ErrTimeout) reports whether
+// err is of that kind.
+type Kind struct {
+	code      string
+	message   string
+	retryable bool
+}
+
+// Error implements the error interface.
+func (k *Kind) Error() string { return k.message }
+
+// Code returns the machine-readable code of the kind.
+func (k *Kind) Code() string { return k.code }
+
+// Error kinds.
+var (
+	// ErrDNS reports a failed host name lookup.
+	ErrDNS = &Kind{code: "dns", message: "DNS lookup failed"}
+	// ErrTimeout reports a request or deadline that timed out.
+	ErrTimeout = &Kind{code: "timeout", message: "timed out", retryable: true}
+	// ErrTLS reports a TLS handshake or certificate failure.
+	ErrTLS = &Kind{code: "tls", message: "TLS error"}
+	// ErrConnection reports a refused, reset or otherwise failed connection.
+	ErrConnection = &Kind{code: "connection", message: "connection failed", retryable: true}
+	// ErrBlockedByPolicy reports a request refused by the server's own policy.
+	ErrBlockedByPolicy = &Kind{code: "blocked_by_policy", message: "blocked by policy"}
+	// ErrRateLimited reports a client that exceeded its rate limit.
+	ErrRateLimited = &Kind{code: "rate_limited", message: "rate limited", retryable: true}
+	// ErrTooLarge reports content exceeding a size limit.
+	ErrTooLarge = &Kind{code: "too_large", message: "content too large"}
+	// ErrHTTPStatus reports an unsuccessful HTTP status code.
+	ErrHTTPStatus = &Kind{code: "http_status", message: "unsuccessful HTTP status"}
+	// ErrRobots reports a URL disallowed by robots.txt.
+	ErrRobots = &Kind{code: "robots", message: "disallowed by robots.txt"}
+	// ErrInvalidArgument reports an invalid tool argument or option.
+	ErrInvalidArgument = &Kind{code: "invalid_argument", message: "invalid argument"}
+	// ErrSampling reports a client that does not support or rejected an MCP
+	// sampling request.
+	ErrSampling = &Kind{code: "sampling", message: "sampling failed"}
+	// ErrUnknown is the kind of errors that match no other kind.
+	ErrUnknown = &Kind{code: "unknown", message: "unknown error"}
+)
+
+// kinds lists the kinds Classify matches with errors.Is.
+var kinds = []*Kind{
+	ErrDNS, ErrTimeout, ErrTLS, ErrConnection, ErrBlockedByPolicy, ErrRateLimited,
+	ErrTooLarge, ErrHTTPStatus, ErrRobots, ErrInvalidArgument, ErrSampling,
+}
+
+// Error is an error of a known kind. It wraps the underlying error, whose
+// message it keeps.
+type Error struct {
+	Kind      *Kind
+	Err       error
+	Retryable bool // Whether retrying the same request may succeed
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string { return e.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is makes errors.Is match the kind of e.
+func (e *Error) Is(target error) bool { return target == e.Kind }
+
+// New returns an error of kind with a message.
+func New(kind *Kind, message string) error {
+	return &Error{Kind: kind, Err: errors.New(message), Retryable: kind.retryable}
+}
+
+// Newf returns an error of kind with a formatted message.
+func Newf(kind *Kind, format string, args ...any) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, args...), Retryable: kind.retryable}
+}
+
+// WithKind marks err as being of kind. If err is nil, WithKind returns nil.
+func WithKind(err error, kind *Kind) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err, Retryable: kind.retryable}
+}
+
+// HTTPStatus returns an ErrHTTPStatus error for status. Rate limiting (429)
+// and server errors (5xx) are retryable.
+func HTTPStatus(status int) error {
+	return &Error{
+		Kind:      ErrHTTPStatus,
+		Err:       fmt.Errorf("HTTP status %d %s", status, http.StatusText(status)),
+		Retryable: status == http.StatusTooManyRequests || status >= 500,
+	}
+}
+
+// Classify returns err as an *Error. Errors marked with a kind keep it;
+// others are classified from the network, TLS and context errors they wrap,
+// or reported as ErrUnknown. Classify returns nil if err is nil.
+func Classify(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var kindErr *Error
+	if errors.As(err, &kindErr) {
+		if kindErr.Err == err {
+			return kindErr
+		}
+		// Keep the full message of err
+		return &Error{Kind: kindErr.Kind, Err: err, Retryable: kindErr.Retryable}
+	}
+	for _, kind := range kinds {
+		if errors.Is(err, kind) {
+			return &Error{Kind: kind, Err: err, Retryable: kind.retryable}
+		}
+	}
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var unknownAuthErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCertErr x509.CertificateInvalidError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Kind: ErrTimeout, Err: err, Retryable: true}
+	case errors.As(err, &dnsErr):
+		if dnsErr.IsTimeout {
+			return &Error{Kind: ErrTimeout, Err: err, Retryable: true}
+		}
+		return &Error{Kind: ErrDNS, Err: err, Retryable: dnsErr.IsTemporary}
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &unknownAuthErr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidCertErr):
+		return &Error{Kind: ErrTLS, Err: err}
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return &Error{Kind: ErrTimeout, Err: err, Retryable: true}
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return &Error{Kind: ErrConnection, Err: err, Retryable: true}
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return &Error{Kind: ErrConnection, Err: err, Retryable: true}
+	}
+	return &Error{Kind: ErrUnknown, Err: err}
+}
+
+// Code returns the code of the kind of err, or "" if err is nil.
+func Code(err error) string {
+	if e := Classify(err); e != nil {
+		return e.Kind.code
+	}
+	return ""
+}
+
+// IsRetryable reports whether retrying the request that failed with err may succeed.
+func IsRetryable(err error) bool {
+	if e := Classify(err); e != nil {
+		return e.Retryable
+	}
+	return false
+}
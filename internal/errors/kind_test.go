@@ -0,0 +1,130 @@
+package errors
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	err := Newf(ErrTooLarge, "body exceeds %d bytes", 10)
+	assert.Equal(t, "body exceeds 10 bytes", err.Error())
+	assert.True(t, errors.Is(err, ErrTooLarge))
+	assert.False(t, errors.Is(err, ErrTimeout))
+
+	// The kind survives wrapping
+	wrapped := Wrap(err, "failed to read body")
+	assert.True(t, errors.Is(wrapped, ErrTooLarge))
+	var kindErr *Error
+	require.True(t, errors.As(wrapped, &kindErr))
+	assert.Equal(t, ErrTooLarge, kindErr.Kind)
+}
+
+func TestWithKind(t *testing.T) {
+	assert.Nil(t, WithKind(nil, ErrTimeout))
+
+	baseErr := errors.New("base error")
+	err := WithKind(baseErr, ErrTimeout)
+	assert.Equal(t, "base error", err.Error())
+	assert.True(t, errors.Is(err, ErrTimeout))
+	assert.True(t, errors.Is(err, baseErr))
+	assert.True(t, IsRetryable(err))
+}
+
+func TestHTTPStatus(t *testing.T) {
+	err := HTTPStatus(404)
+	assert.Equal(t, "HTTP status 404 Not Found", err.Error())
+	assert.True(t, errors.Is(err, ErrHTTPStatus))
+	assert.False(t, IsRetryable(err))
+
+	assert.True(t, IsRetryable(HTTPStatus(429)))
+	assert.True(t, IsRetryable(HTTPStatus(503)))
+}
+
+func TestClassify(t *testing.T) {
+	assert.Nil(t, Classify(nil))
+	assert.Equal(t, "", Code(nil))
+
+	tests := []struct {
+		name      string
+		err       error
+		code      string
+		retryable bool
+	}{
+		{
+			name: "unknown host",
+			err:  &url.Error{Op: "Get", URL: "http://nx.invalid", Err: &net.DNSError{Err: "no such host", Name: "nx.invalid", IsNotFound: true}},
+			code: "dns",
+		},
+		{
+			name:      "temporary DNS failure",
+			err:       &net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true},
+			code:      "dns",
+			retryable: true,
+		},
+		{
+			name:      "DNS timeout",
+			err:       &net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true},
+			code:      "timeout",
+			retryable: true,
+		},
+		{
+			name:      "context deadline",
+			err:       Wrap(context.DeadlineExceeded, "failed to execute request"),
+			code:      "timeout",
+			retryable: true,
+		},
+		{
+			name:      "client timeout",
+			err:       &url.Error{Op: "Get", URL: "http://example.com", Err: os.ErrDeadlineExceeded},
+			code:      "timeout",
+			retryable: true,
+		},
+		{
+			name: "unknown certificate authority",
+			err:  &url.Error{Op: "Get", URL: "https://example.com", Err: x509.UnknownAuthorityError{}},
+			code: "tls",
+		},
+		{
+			name:      "connection refused",
+			err:       &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)},
+			code:      "connection",
+			retryable: true,
+		},
+		{
+			name: "kind sentinel",
+			err:  Wrap(ErrRobots, "failed to fetch URL"),
+			code: "robots",
+		},
+		{
+			name: "unclassified",
+			err:  errors.New("something else"),
+			code: "unknown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kindErr := Classify(tt.err)
+			require.NotNil(t, kindErr)
+			assert.Equal(t, tt.code, kindErr.Kind.Code())
+			assert.Equal(t, tt.retryable, kindErr.Retryable)
+			assert.Equal(t, tt.err.Error(), kindErr.Error())
+			assert.True(t, errors.Is(kindErr, kindErr.Kind))
+		})
+	}
+}
+
+func TestClassify_KeepsMessage(t *testing.T) {
+	err := Wrap(New(ErrInvalidArgument, "query is required"), "failed to search URL")
+	kindErr := Classify(err)
+	assert.Equal(t, ErrInvalidArgument, kindErr.Kind)
+	assert.Equal(t, "failed to search URL: query is required", kindErr.Error())
+}
@@ -39,6 +39,16 @@ func main() {
 					Value:   "config.yml",
 					Usage:   "path to the configuration file",
 				},
+				&cli.StringFlag{
+					Name:    "transport",
+					Aliases: []string{"t"},
+					Usage:   "MCP transport: stdio, sse or http (streamable HTTP), overriding the configuration file",
+				},
+				&cli.StringFlag{
+					Name:    "addr",
+					Aliases: []string{"a"},
+					Usage:   "listen address of the sse and http transports, overriding the configuration file",
+				},
 			},
 			Action: func(c *cli.Context) error {
 				configPath := c.String("config")
@@ -48,6 +58,12 @@ func main() {
 				if err != nil {
 					return ierrors.Wrap(err, "failed to load configuration file")
 				}
+				if c.IsSet("transport") {
+					cfg.Transport.Type = c.String("transport")
+				}
+				if c.IsSet("addr") {
+					cfg.Transport.Addr = c.String("addr")
+				}
 
 				// Initialize logger
 				if err := logger.InitLogger(cfg.Debug, cfg.Log); err != nil {
@@ -59,9 +75,81 @@ func main() {
 				return server.Run(cfg, Name, Version, Revision)
 			},
 		},
+		{
+			Name:      "fetch",
+			Aliases:   []string{"f"},
+			Usage:     "Fetch URLs once and print what the fetch tool would return",
+			ArgsUsage: "<url...>",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "config",
+					Aliases: []string{"c"},
+					Value:   "config.yml",
+					Usage:   "path to the configuration file",
+				},
+				&cli.IntFlag{
+					Name:  "max-length",
+					Usage: "maximum number of characters to return per URL (default: default_max_length of the configuration)",
+				},
+				&cli.IntFlag{
+					Name:  "start-index",
+					Usage: "start content from this character index",
+				},
+				&cli.BoolFlag{
+					Name:  "raw",
+					Usage: "get raw content without markdown conversion",
+				},
+				&cli.StringFlag{
+					Name:    "format",
+					Aliases: []string{"f"},
+					Usage:   "output format for HTML content: markdown, text, html or json",
+				},
+				&cli.StringFlag{
+					Name:  "selector",
+					Usage: "CSS selector limiting processing to matching elements",
+				},
+				&cli.StringFlag{
+					Name:  "section",
+					Usage: "return only this section, by heading anchor or text",
+				},
+				&cli.BoolFlag{
+					Name:    "json",
+					Aliases: []string{"j"},
+					Usage:   "print the JSON responses of the fetch tool instead of the content",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				if c.NArg() == 0 {
+					return cli.ShowSubcommandHelp(c)
+				}
+
+				// Read the configuration file
+				cfg, err := config.LoadConfig(c.String("config"))
+				if err != nil {
+					return ierrors.Wrap(err, "failed to load configuration file")
+				}
+
+				// Initialize logger
+				if err := logger.InitLogger(cfg.Debug, cfg.Log); err != nil {
+					return ierrors.Wrap(err, "failed to initialize logger")
+				}
+				defer logger.Sync()
+
+				return server.RunFetch(cfg, c.Args().Slice(), server.FetchCommandOptions{
+					MaxLength:  c.Int("max-length"),
+					StartIndex: c.Int("start-index"),
+					Raw:        c.Bool("raw"),
+					Format:     c.String("format"),
+					Selector:   c.String("selector"),
+					Section:    c.String("section"),
+					JSON:       c.Bool("json"),
+				}, os.Stdout, os.Stderr)
+			},
+		},
 	}
 
 	if err := app.Run(os.Args); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
 }
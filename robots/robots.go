@@ -0,0 +1,197 @@
+// Package robots parses robots.txt files and evaluates their rules
+// following RFC 9309, including the common Crawl-delay and Sitemap extensions.
+package robots
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Path is the location of robots.txt on every origin.
+const Path = "/robots.txt"
+
+// Rules is a parsed robots.txt file.
+type Rules struct {
+	groups   []*group
+	sitemaps []string
+	// disallowAll makes every path except robots.txt disallowed, for
+	// robots.txt files that could not be retrieved.
+	disallowAll bool
+}
+
+// group is a set of rules shared by one or more user agents.
+type group struct {
+	agents     []string
+	rules      []rule
+	crawlDelay time.Duration
+}
+
+// rule is a single Allow or Disallow line.
+type rule struct {
+	allow   bool
+	pattern string
+}
+
+// AllowAll returns rules allowing every path, used when a site has no robots.txt.
+func AllowAll() *Rules {
+	return &Rules{}
+}
+
+// DisallowAll returns rules disallowing every path, used when robots.txt
+// is unreachable because of a server error.
+func DisallowAll() *Rules {
+	return &Rules{disallowAll: true}
+}
+
+// Parse parses the contents of a robots.txt file. Invalid lines are ignored.
+func Parse(body string) *Rules {
+	r := &Rules{}
+	var current *group
+	inAgents := false // Whether the previous record was a user-agent line
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents || current == nil {
+				current = &group{}
+				r.groups = append(r.groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+			continue
+		case "allow", "disallow":
+			// An empty Disallow allows everything and adds no rule
+			if current != nil && value != "" {
+				current.rules = append(current.rules, rule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if current != nil {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		case "sitemap":
+			// Sitemap lines are independent of groups
+			if value != "" {
+				r.sitemaps = append(r.sitemaps, value)
+			}
+		}
+		inAgents = false
+	}
+	return r
+}
+
+// Allowed reports whether agent may fetch path, which should include the
+// query string. The longest matching rule wins; Allow wins ties.
+func (r *Rules) Allowed(agent, path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if path == Path {
+		return true
+	}
+	if r.disallowAll {
+		return false
+	}
+
+	allowed := true
+	matchLength := -1
+	for _, g := range r.groupsFor(agent) {
+		for _, rl := range g.rules {
+			if !matchPattern(rl.pattern, path) {
+				continue
+			}
+			length := len(rl.pattern)
+			if length > matchLength || (length == matchLength && rl.allow) {
+				matchLength = length
+				allowed = rl.allow
+			}
+		}
+	}
+	return allowed
+}
+
+// CrawlDelay returns the Crawl-delay declared for agent, or zero.
+func (r *Rules) CrawlDelay(agent string) time.Duration {
+	var delay time.Duration
+	for _, g := range r.groupsFor(agent) {
+		if g.crawlDelay > delay {
+			delay = g.crawlDelay
+		}
+	}
+	return delay
+}
+
+// Sitemaps returns the sitemap URLs declared in the file.
+func (r *Rules) Sitemaps() []string {
+	return r.sitemaps
+}
+
+// groupsFor returns the groups that apply to agent: every group naming its
+// product token, or else the groups for "*".
+func (r *Rules) groupsFor(agent string) []*group {
+	agent = strings.ToLower(agent)
+	var matched, wildcard []*group
+	for _, g := range r.groups {
+		for _, a := range g.agents {
+			if a == agent {
+				matched = append(matched, g)
+				break
+			}
+			if a == "*" {
+				wildcard = append(wildcard, g)
+				break
+			}
+		}
+	}
+	if len(matched) > 0 {
+		return matched
+	}
+	return wildcard
+}
+
+// matchPattern reports whether a rule pattern matches path. Patterns match
+// path prefixes; "*" matches any sequence of characters and a trailing "$"
+// anchors the pattern at the end of the path.
+func matchPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for i, part := range parts[1:] {
+		if i == len(parts)-2 && anchored {
+			// The last part must match at the very end
+			return strings.HasSuffix(path[pos:], part)
+		}
+		idx := strings.Index(path[pos:], part)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(part)
+	}
+	if anchored {
+		return pos == len(path)
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package robots
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleRobots = `# Example robots.txt
+User-agent: *
+Disallow: /private/
+Allow: /private/public
+Disallow: /*.pdf$
+Crawl-delay: 2
+
+User-agent: mcp-fetch
+User-agent: OtherBot
+Disallow: /search
+Allow: /search/about
+Crawl-delay: 0.5
+
+User-agent: BlockedBot
+Disallow: /
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+func TestRules_Allowed(t *testing.T) {
+	rules := Parse(sampleRobots)
+
+	tests := []struct {
+		name     string
+		agent    string
+		path     string
+		expected bool
+	}{
+		{name: "wildcard group disallow", agent: "somebot", path: "/private/data", expected: false},
+		{name: "longer allow wins", agent: "somebot", path: "/private/public/page", expected: true},
+		{name: "anchored wildcard", agent: "somebot", path: "/docs/file.pdf", expected: false},
+		{name: "anchor requires end", agent: "somebot", path: "/docs/file.pdf?x=1", expected: true},
+		{name: "no matching rule", agent: "somebot", path: "/index.html", expected: true},
+		{name: "specific group replaces wildcard", agent: "mcp-fetch", path: "/private/data", expected: true},
+		{name: "specific group case-insensitive", agent: "MCP-Fetch", path: "/search?q=go", expected: false},
+		{name: "shared group", agent: "otherbot", path: "/search/about", expected: true},
+		{name: "disallow all", agent: "blockedbot", path: "/anything", expected: false},
+		{name: "robots.txt always allowed", agent: "blockedbot", path: "/robots.txt", expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, rules.Allowed(tt.agent, tt.path))
+		})
+	}
+}
+
+func TestRules_CrawlDelayAndSitemaps(t *testing.T) {
+	rules := Parse(sampleRobots)
+	assert.Equal(t, 2*time.Second, rules.CrawlDelay("somebot"))
+	assert.Equal(t, 500*time.Millisecond, rules.CrawlDelay("mcp-fetch"))
+	assert.Equal(t, time.Duration(0), rules.CrawlDelay("blockedbot"))
+	assert.Equal(t, []string{"https://example.com/sitemap.xml"}, rules.Sitemaps())
+}
+
+func TestAllowAllAndDisallowAll(t *testing.T) {
+	assert.True(t, AllowAll().Allowed("bot", "/x"))
+	assert.False(t, DisallowAll().Allowed("bot", "/x"))
+	assert.True(t, DisallowAll().Allowed("bot", "/robots.txt"))
+	// Empty Disallow allows everything
+	assert.True(t, Parse("User-agent: *\nDisallow:\n").Allowed("bot", "/x"))
+}
+
+func TestMatchPattern(t *testing.T) {
+	assert.True(t, matchPattern("/a*/c", "/ab/c/d"))
+	assert.False(t, matchPattern("/a*/c", "/ab/d"))
+	assert.True(t, matchPattern("/*/end$", "/x/y/end"))
+	assert.False(t, matchPattern("/*/end$", "/x/end/more"))
+	assert.True(t, matchPattern("/exact$", "/exact"))
+	assert.False(t, matchPattern("/exact$", "/exactly"))
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"strings"
+
+	"github.com/cnosuke/mcp-fetch/config"
+	"github.com/cnosuke/mcp-fetch/fetcher"
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.uber.org/zap"
+)
+
+// headerAPIKey carries an API key, as an alternative to a bearer token.
+const headerAPIKey = "X-API-Key"
+
+// Client is an authenticated client of the network transports.
+type Client struct {
+	Name             string
+	DefaultMaxLength int // Default maximum length of returned content
+	policy           *fetcher.Policy
+}
+
+// Authenticator authenticates the requests of the network transports with
+// the tokens of the configured clients.
+type Authenticator struct {
+	byToken map[[sha256.Size]byte]*Client // By token hash, so lookups take constant time
+}
+
+// NewAuthenticator creates the authenticator of the clients of cfg, or
+// returns nil if none is configured.
+func NewAuthenticator(cfg *config.Config) (*Authenticator, error) {
+	if len(cfg.Auth.Clients) == 0 {
+		return nil, nil
+	}
+	a := &Authenticator{byToken: make(map[[sha256.Size]byte]*Client)}
+	names := make(map[string]bool)
+	for i, clientCfg := range cfg.Auth.Clients {
+		if clientCfg.Name == "" {
+			return nil, ierrors.Newf(ierrors.ErrInvalidArgument, "auth client %d has no name", i+1)
+		}
+		if names[clientCfg.Name] {
+			return nil, ierrors.Newf(ierrors.ErrInvalidArgument, "duplicate auth client %q", clientCfg.Name)
+		}
+		names[clientCfg.Name] = true
+		if clientCfg.Token == "" {
+			return nil, ierrors.Newf(ierrors.ErrInvalidArgument, "auth client %q has no token", clientCfg.Name)
+		}
+		hash := sha256.Sum256([]byte(clientCfg.Token))
+		if _, ok := a.byToken[hash]; ok {
+			return nil, ierrors.Newf(ierrors.ErrInvalidArgument, "auth client %q reuses the token of another client", clientCfg.Name)
+		}
+
+		client := &Client{
+			Name:             clientCfg.Name,
+			DefaultMaxLength: clientCfg.DefaultMaxLength,
+			policy:           fetcher.NewPolicy(clientCfg.Name, clientCfg.AllowedDomains, clientCfg.MaxURLs, clientCfg.RateLimit),
+		}
+		if client.DefaultMaxLength <= 0 {
+			client.DefaultMaxLength = cfg.Fetch.DefaultMaxLength
+		}
+		a.byToken[hash] = client
+		zap.S().Debugw("configured auth client",
+			"client", client.Name,
+			"allowed_domains", client.policy.AllowedDomains,
+			"max_urls", clientCfg.MaxURLs,
+			"default_max_length", client.DefaultMaxLength,
+			"rate_limit", clientCfg.RateLimit)
+	}
+	return a, nil
+}
+
+// authenticate returns the client whose token r carries, as a bearer token
+// or an API key, or nil.
+func (a *Authenticator) authenticate(r *http.Request) *Client {
+	token := r.Header.Get(headerAPIKey)
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		scheme, credentials, _ := strings.Cut(authorization, " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			return nil
+		}
+		token = strings.TrimSpace(credentials)
+	}
+	if token == "" {
+		return nil
+	}
+	return a.byToken[sha256.Sum256([]byte(token))]
+}
+
+// Middleware rejects the requests without a valid token with 401 and adds
+// the client of the others to their context.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := a.authenticate(r)
+		if client == nil {
+			zap.S().Warnw("rejected unauthenticated request",
+				"remote_addr", r.RemoteAddr,
+				"method", r.Method,
+				"path", r.URL.Path)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp-fetch"`)
+			writeJSONRPCError(w, http.StatusUnauthorized, mcp.INVALID_REQUEST, "missing or invalid bearer token or API key")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withClient(r.Context(), client)))
+	})
+}
+
+// clientKey is the context key of the authenticated client.
+type clientKey struct{}
+
+// withClient returns ctx with the authenticated client.
+func withClient(ctx context.Context, client *Client) context.Context {
+	if client == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// clientFromContext returns the authenticated client of ctx, or nil, e.g. on
+// stdio.
+func clientFromContext(ctx context.Context) *Client {
+	client, _ := ctx.Value(clientKey{}).(*Client)
+	return client
+}
+
+// clientName returns the name of the authenticated client of ctx, or "".
+func clientName(ctx context.Context) string {
+	if client := clientFromContext(ctx); client != nil {
+		return client.Name
+	}
+	return ""
+}
+
+// clientFetcher returns f restricted by the policy of the client of ctx, if
+// any.
+func clientFetcher(ctx context.Context, f fetcher.Fetcher) fetcher.Fetcher {
+	if client := clientFromContext(ctx); client != nil {
+		return fetcher.WithPolicy(f, client.policy)
+	}
+	return f
+}
+
+// clientDiffer returns differ restricted by the policy of the client of ctx,
+// if any, for operation.
+func clientDiffer(ctx context.Context, differ *fetcher.Differ, operation string) *fetcher.Differ {
+	if client := clientFromContext(ctx); client != nil {
+		return differ.WithGuard(client.policy.Guard(operation))
+	}
+	return differ
+}
+
+// checkDomains checks urls against the allowed domains of the client of ctx,
+// if any, for operation.
+func checkDomains(ctx context.Context, operation string, urls ...string) error {
+	if client := clientFromContext(ctx); client != nil {
+		return client.policy.CheckDomains(operation, urls...)
+	}
+	return nil
+}
+
+// clientMaxLength returns the default maximum length of the client of ctx,
+// or defaultMaxLength.
+func clientMaxLength(ctx context.Context, defaultMaxLength int) int {
+	if client := clientFromContext(ctx); client != nil {
+		return client.DefaultMaxLength
+	}
+	return defaultMaxLength
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cnosuke/mcp-fetch/config"
+	"github.com/cnosuke/mcp-fetch/fetcher"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// CrawlArgs - Arguments for crawl tool
+type CrawlArgs struct {
+	URL            string   `json:"url" jsonschema:"description=URL to start crawling from,required=true"`
+	MaxDepth       int      `json:"max_depth,omitempty" jsonschema:"description=Number of links to follow from the start URL"`
+	MaxPages       int      `json:"max_pages,omitempty" jsonschema:"description=Maximum number of pages to fetch"`
+	Scope          string   `json:"scope,omitempty" jsonschema:"description=Links to follow,enum=host,enum=prefix"`
+	PathPrefix     string   `json:"path_prefix,omitempty" jsonschema:"description=Only follow links under this path on the start host"`
+	Include        []string `json:"include,omitempty" jsonschema:"description=Regular expressions a URL must match to be followed"`
+	Exclude        []string `json:"exclude,omitempty" jsonschema:"description=Regular expressions of URLs not to follow"`
+	MaxLength      int      `json:"max_length,omitempty" jsonschema:"description=Total number of characters of summaries or content to return"`
+	IncludeContent bool     `json:"include_content,omitempty" jsonschema:"description=Return page content instead of summaries"`
+}
+
+// RegisterCrawlTool - Register the crawl tool
+func RegisterCrawlTool(mcpServer *server.MCPServer, f fetcher.Fetcher, cfg *config.Config) error {
+	zap.S().Debugw("registering crawl tool")
+
+	// Define the tool
+	tool := mcp.NewTool("crawl",
+		mcp.WithDescription(fmt.Sprintf("Crawls a site breadth-first from a URL and returns each visited page with its title and a summary (or its markdown content) plus the in-scope links found on it. The crawl stops at max_depth, max_pages or when max_length characters have been returned. Default max_length is %d.", cfg.Fetch.DefaultMaxLength)),
+		mcp.WithString("url",
+			mcp.Description("URL to start crawling from"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("max_depth",
+			mcp.Description(fmt.Sprintf("Number of links to follow from the start URL; 0 fetches only the start page (default: %d)", fetcher.DefaultCrawlDepth)),
+		),
+		mcp.WithNumber("max_pages",
+			mcp.Description(fmt.Sprintf("Maximum number of pages to fetch (default: %d, maximum: %d)", fetcher.DefaultCrawlPages, fetcher.MaxCrawlPages)),
+		),
+		mcp.WithString("scope",
+			mcp.Description("Links to follow: host (any page on the start host, default) or prefix (pages under the start URL's directory)"),
+			mcp.Enum(fetcher.CrawlScopeHost, fetcher.CrawlScopePrefix),
+		),
+		mcp.WithString("path_prefix",
+			mcp.Description("Only follow links under this path on the start host (e.g. \"/docs/\"). Overrides scope"),
+		),
+		mcp.WithArray("include",
+			mcp.Description("Regular expressions; only URLs matching at least one are followed"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithArray("exclude",
+			mcp.Description("Regular expressions of URLs not to follow"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithNumber("max_length",
+			mcp.Description("Total number of characters of summaries (or content) to return across all pages"),
+		),
+		mcp.WithBoolean("include_content",
+			mcp.Description("Return each page's markdown content instead of a summary"),
+		),
+	)
+
+	// Register the tool handler
+	mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract parameters
+		url, _ := request.Params.Arguments["url"].(string)
+
+		maxDepth := fetcher.DefaultCrawlDepth
+		if maxDepthVal, ok := request.Params.Arguments["max_depth"].(float64); ok {
+			maxDepth = int(maxDepthVal)
+		}
+
+		var maxPages int
+		if maxPagesVal, ok := request.Params.Arguments["max_pages"].(float64); ok {
+			maxPages = int(maxPagesVal)
+		}
+
+		scope, _ := request.Params.Arguments["scope"].(string)
+		pathPrefix, _ := request.Params.Arguments["path_prefix"].(string)
+		include := stringArrayArgument(request.Params.Arguments["include"])
+		exclude := stringArrayArgument(request.Params.Arguments["exclude"])
+
+		var maxLength int
+		if maxLengthVal, ok := request.Params.Arguments["max_length"].(float64); ok {
+			maxLength = int(maxLengthVal)
+		}
+
+		includeContent, _ := request.Params.Arguments["include_content"].(bool)
+
+		zap.S().Infow("executing crawl",
+			"url", url,
+			"max_depth", maxDepth,
+			"max_pages", maxPages,
+			"scope", scope,
+			"path_prefix", pathPrefix,
+			"include", include,
+			"exclude", exclude,
+			"max_length", maxLength,
+			"include_content", includeContent)
+
+		// Validate URL
+		if url == "" {
+			return invalidArgument("URL is required"), nil
+		}
+
+		// Set default values
+		if maxLength <= 0 {
+			maxLength = clientMaxLength(ctx, cfg.Fetch.DefaultMaxLength)
+		}
+
+		response, err := clientFetcher(ctx, f).Crawl(ctx, url, fetcher.CrawlOptions{
+			MaxDepth:       maxDepth,
+			MaxPages:       maxPages,
+			Scope:          scope,
+			PathPrefix:     pathPrefix,
+			Include:        include,
+			Exclude:        exclude,
+			MaxLength:      maxLength,
+			IncludeContent: includeContent,
+			Progress:       progressNotifier(ctx, request),
+		})
+		if err != nil {
+			zap.S().Errorw("failed to crawl",
+				"url", url,
+				"error", err)
+			return toolError("failed to crawl", err), nil
+		}
+
+		// Convert response to JSON
+		jsonResponse, err := json.Marshal(response)
+		if err != nil {
+			zap.S().Errorw("failed to marshal response to JSON",
+				"error", err)
+			return toolError("failed to marshal response to JSON", err), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	})
+
+	return nil
+}
+
+// stringArrayArgument converts an array tool argument to a string slice,
+// skipping non-string items.
+func stringArrayArgument(arg interface{}) []string {
+	var values []string
+	if array, ok := arg.([]interface{}); ok {
+		for _, item := range array {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+	}
+	return values
+}
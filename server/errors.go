@@ -0,0 +1,35 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// toolError returns a tool error result whose text is the JSON object
+// {code, message, retryable} for err, classified by ierrors.Classify. The
+// message is err prefixed with prefix, if any.
+func toolError(prefix string, err error) *mcp.CallToolResult {
+	kindErr := ierrors.Classify(err)
+	message := err.Error()
+	if prefix != "" {
+		message = fmt.Sprintf("%s: %s", prefix, message)
+	}
+	jsonError, marshalErr := json.Marshal(&types.FetchError{
+		Code:      kindErr.Kind.Code(),
+		Message:   message,
+		Retryable: kindErr.Retryable,
+	})
+	if marshalErr != nil {
+		return mcp.NewToolResultError(message)
+	}
+	return mcp.NewToolResultError(string(jsonError))
+}
+
+// invalidArgument returns a tool error result for an invalid tool argument.
+func invalidArgument(message string) *mcp.CallToolResult {
+	return toolError("", ierrors.New(ierrors.ErrInvalidArgument, message))
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/cnosuke/mcp-fetch/config"
+	"github.com/cnosuke/mcp-fetch/fetcher"
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"go.uber.org/zap"
+)
+
+// fetchCommandSeparator separates the contents of several URLs.
+const fetchCommandSeparator = "\n---\n\n"
+
+// FetchCommandOptions - Options of the fetch command, mirroring the arguments
+// of the fetch tool
+type FetchCommandOptions struct {
+	MaxLength  int // Default max length of the configuration if 0
+	StartIndex int
+	Raw        bool
+	Format     string
+	Selector   string
+	Section    string
+	JSON       bool // Print the JSON responses of the fetch tool instead of the content
+}
+
+// RunFetch - Fetch urls once, as the fetch tool would, and write their
+// content or JSON responses to stdout. Failed URLs are reported on stderr, or
+// on stdout as the JSON errors of the fetch tool.
+func RunFetch(cfg *config.Config, urls []string, opts FetchCommandOptions, stdout io.Writer, stderr io.Writer) error {
+	if len(urls) == 0 {
+		return ierrors.New(ierrors.ErrInvalidArgument, "URL is required")
+	}
+	format, err := fetcher.ParseFormat(opts.Format)
+	if err != nil {
+		return err
+	}
+	if opts.MaxLength <= 0 {
+		opts.MaxLength = cfg.Fetch.DefaultMaxLength
+	}
+
+	f, err := NewFetcher(cfg)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to create fetcher")
+	}
+
+	var printed, failed int
+	var lastErr error
+	for _, url := range urls {
+		zap.S().Infow("executing fetch command",
+			"url", url,
+			"max_length", opts.MaxLength,
+			"start_index", opts.StartIndex,
+			"raw", opts.Raw,
+			"format", format,
+			"selector", opts.Selector,
+			"section", opts.Section)
+
+		response, err := f.FetchWithOptions(url, fetcher.FetchOptions{
+			MaxLength:  opts.MaxLength,
+			StartIndex: opts.StartIndex,
+			Raw:        opts.Raw,
+			Format:     format,
+			Selector:   opts.Selector,
+			Section:    opts.Section,
+		})
+		if err != nil {
+			zap.S().Errorw("failed to fetch URL",
+				"url", url,
+				"error", err)
+			failed++
+			lastErr = ierrors.Wrapf(err, "failed to fetch %s", url)
+		}
+
+		switch {
+		case opts.JSON:
+			var value any = response
+			if err != nil {
+				kindErr := ierrors.Classify(err)
+				value = &types.FetchError{
+					Code:      kindErr.Kind.Code(),
+					Message:   lastErr.Error(),
+					Retryable: kindErr.Retryable,
+				}
+			}
+			jsonResponse, err := json.MarshalIndent(value, "", "  ")
+			if err != nil {
+				return ierrors.Wrap(err, "failed to marshal response to JSON")
+			}
+			fmt.Fprintln(stdout, string(jsonResponse))
+		case err != nil:
+			if len(urls) > 1 {
+				fmt.Fprintf(stderr, "Error: %v\n", lastErr)
+			}
+		default:
+			if printed > 0 {
+				fmt.Fprint(stdout, fetchCommandSeparator)
+			}
+			fmt.Fprintln(stdout, response.Content)
+			printed++
+		}
+	}
+
+	switch {
+	case failed == 0:
+		return nil
+	case len(urls) == 1:
+		return lastErr
+	default:
+		return ierrors.Newf(ierrors.ErrUnknown, "failed to fetch %d of %d URLs", failed, len(urls))
+	}
+}
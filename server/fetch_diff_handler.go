@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cnosuke/mcp-fetch/config"
+	"github.com/cnosuke/mcp-fetch/fetcher"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// FetchDiffArgs - Arguments for fetch_diff tool
+type FetchDiffArgs struct {
+	URL        string `json:"url" jsonschema:"description=URL to fetch and diff,required=true"`
+	Mode       string `json:"mode,omitempty" jsonschema:"description=Diff output: unified or words"`
+	Since      string `json:"since,omitempty" jsonschema:"description=Compare against the latest snapshot fetched at or before this RFC 3339 time"`
+	CompareURL string `json:"compare_url,omitempty" jsonschema:"description=Compare against the current version of this URL instead of a snapshot"`
+	Context    int    `json:"context,omitempty" jsonschema:"description=Number of unchanged lines around each change"`
+	MaxLength  int    `json:"max_length,omitempty" jsonschema:"description=Maximum length of the diff"`
+}
+
+// RegisterFetchDiffTool - Register the fetch_diff tool
+func RegisterFetchDiffTool(mcpServer *server.MCPServer, differ *fetcher.Differ, cfg *config.Config) error {
+	zap.S().Debugw("registering fetch_diff tool")
+
+	// Define the tool
+	tool := mcp.NewTool("fetch_diff",
+		mcp.WithDescription("Fetches a URL and returns what changed in its markdown content since an earlier fetch, or compared to another URL. Processed snapshots of fetched pages are kept, a few per URL; by default the current version is compared to the last snapshot. The first fetch of a URL only stores a baseline. Returns the compared versions, counts of added and removed lines (or words) and the diff."),
+		mcp.WithString("url",
+			mcp.Description("URL to fetch and diff"),
+			mcp.Required(),
+		),
+		mcp.WithString("mode",
+			mcp.Description("Diff output: unified (line diff with @@ hunks) or words (changed lines compared word by word, removed words as [-...-] and added words as {+...+}) (default: unified)"),
+			mcp.Enum(fetcher.DiffModes...),
+		),
+		mcp.WithString("since",
+			mcp.Description("Compare against the latest snapshot fetched at or before this time (RFC 3339, e.g. 2024-01-31T12:00:00Z) instead of the last one"),
+		),
+		mcp.WithString("compare_url",
+			mcp.Description("Compare against the current version of this URL instead of a snapshot"),
+		),
+		mcp.WithNumber("context",
+			mcp.Description("Number of unchanged lines around each change (default: 3 for unified, 1 for words)"),
+		),
+		mcp.WithNumber("max_length",
+			mcp.Description(fmt.Sprintf("Maximum length of the diff (default: %d)", cfg.Fetch.DefaultMaxLength)),
+		),
+	)
+
+	// Register the tool handler
+	mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract parameters
+		url, _ := request.Params.Arguments["url"].(string)
+		modeName, _ := request.Params.Arguments["mode"].(string)
+		sinceStr, _ := request.Params.Arguments["since"].(string)
+		compareURL, _ := request.Params.Arguments["compare_url"].(string)
+
+		contextLines := -1
+		if contextVal, ok := request.Params.Arguments["context"].(float64); ok {
+			contextLines = int(contextVal)
+		}
+
+		maxLength := clientMaxLength(ctx, cfg.Fetch.DefaultMaxLength)
+		if maxLengthVal, ok := request.Params.Arguments["max_length"].(float64); ok {
+			maxLength = int(maxLengthVal)
+		}
+
+		zap.S().Infow("executing fetch_diff",
+			"url", url,
+			"mode", modeName,
+			"since", sinceStr,
+			"compare_url", compareURL,
+			"context", contextLines,
+			"max_length", maxLength)
+
+		// Validate parameters
+		if url == "" {
+			return invalidArgument("URL is required"), nil
+		}
+		mode, err := fetcher.ParseDiffMode(modeName)
+		if err != nil {
+			return toolError("", err), nil
+		}
+		var since time.Time
+		if sinceStr != "" {
+			if compareURL != "" {
+				return invalidArgument("since and compare_url cannot be used together"), nil
+			}
+			since, err = time.Parse(time.RFC3339, strings.TrimSpace(sinceStr))
+			if err != nil {
+				return invalidArgument(fmt.Sprintf("invalid since %q: expected an RFC 3339 time", sinceStr)), nil
+			}
+		}
+
+		urls := []string{url}
+		if compareURL != "" {
+			urls = append(urls, compareURL)
+		}
+		if err := checkDomains(ctx, "fetch_diff", urls...); err != nil {
+			return toolError("failed to diff URL", err), nil
+		}
+
+		response, err := clientDiffer(ctx, differ, "fetch_diff").FetchDiff(url, fetcher.DiffOptions{
+			Mode:       mode,
+			Since:      since,
+			CompareURL: compareURL,
+			Context:    contextLines,
+			MaxLength:  maxLength,
+		})
+		if err != nil {
+			zap.S().Errorw("failed to diff URL",
+				"url", url,
+				"error", err)
+			return toolError("failed to diff URL", err), nil
+		}
+
+		// Convert response to JSON
+		jsonResponse, err := json.Marshal(response)
+		if err != nil {
+			zap.S().Errorw("failed to marshal response to JSON",
+				"error", err)
+			return toolError("failed to marshal response to JSON", err), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	})
+
+	return nil
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/cnosuke/mcp-fetch/config"
@@ -17,7 +18,18 @@ type FetchArgs struct {
 	URL        string `json:"url" jsonschema:"description=URL to fetch,required=true"`
 	MaxLength  int    `json:"max_length,omitempty" jsonschema:"description=Maximum number of characters to return"`
 	StartIndex int    `json:"start_index,omitempty" jsonschema:"description=Start content from this character index"`
+	MaxTokens  int    `json:"max_tokens,omitempty" jsonschema:"description=Maximum number of tokens to return (used instead of max_length)"`
+	StartToken int    `json:"start_token,omitempty" jsonschema:"description=Start content from this token index"`
 	Raw        bool   `json:"raw,omitempty" jsonschema:"description=Get raw content without markdown conversion"`
+	Format     string `json:"format,omitempty" jsonschema:"description=Output format for HTML content,enum=markdown,enum=text,enum=html,enum=json"`
+	// Selector and ExcludeSelectors scope HTML processing to specific elements
+	Selector         string   `json:"selector,omitempty" jsonschema:"description=CSS selector limiting processing to matching elements"`
+	ExcludeSelectors []string `json:"exclude_selectors,omitempty" jsonschema:"description=CSS selectors of elements to remove before processing"`
+	IncludeMetadata  bool     `json:"include_metadata,omitempty" jsonschema:"description=Include page metadata (OpenGraph, Twitter card, JSON-LD, canonical URL, dates, feeds)"`
+	Section          string   `json:"section,omitempty" jsonschema:"description=Heading anchor or text of the section to return"`
+	HTTPErrors       string   `json:"http_errors,omitempty" jsonschema:"description=Handling of 4xx/5xx responses,enum=content,enum=excerpt,enum=error"`
+	// IncludeResponseInfo adds headers, timings and transfer stats for debugging
+	IncludeResponseInfo bool `json:"include_response_info,omitempty" jsonschema:"description=Include response headers, timings and transfer stats"`
 }
 
 // RegisterFetchTool - Register the fetch tool
@@ -37,9 +49,39 @@ func RegisterFetchTool(mcpServer *server.MCPServer, f fetcher.Fetcher, cfg *conf
 		mcp.WithNumber("start_index",
 			mcp.Description("Start content from this character index"),
 		),
+		mcp.WithNumber("max_tokens",
+			mcp.Description("Maximum number of tokens to return. When set, the default max_length is not applied"),
+		),
+		mcp.WithNumber("start_token",
+			mcp.Description("Start content from this token index (use with max_tokens to page through content)"),
+		),
 		mcp.WithBoolean("raw",
 			mcp.Description("Get raw content without markdown conversion"),
 		),
+		mcp.WithString("format",
+			mcp.Description("Output format for HTML content: markdown (default), text (no markup), html (readability-cleaned HTML) or json (block tree of headings, paragraphs, lists, code and tables)"),
+			mcp.Enum(fetcher.Formats...),
+		),
+		mcp.WithString("selector",
+			mcp.Description("CSS selector limiting processing to matching elements (e.g. \"main article .content\"). Each match is returned in document order, bypassing readability"),
+		),
+		mcp.WithArray("exclude_selectors",
+			mcp.Description("CSS selectors of elements to remove before processing"),
+			mcp.Items(map[string]any{"type": "string"}),
+		),
+		mcp.WithBoolean("include_metadata",
+			mcp.Description("Include page metadata (description, canonical URL, language, OpenGraph, Twitter card, JSON-LD, dates, feeds)"),
+		),
+		mcp.WithString("section",
+			mcp.Description("Return only this section and its subsections, by heading anchor (e.g. \"authentication\") or heading text. A URL fragment is used the same way when it matches a heading. Use fetch_outline to list sections"),
+		),
+		mcp.WithString("http_errors",
+			mcp.Description(httpErrorsDescription(cfg.Fetch.HTTPErrors)),
+			mcp.Enum(fetcher.HTTPErrorPolicies...),
+		),
+		mcp.WithBoolean("include_response_info",
+			mcp.Description("Include response_info with selected response headers, timings (DNS, connect, TLS, time to first byte, total, processing), remote address, protocol, compressed and decompressed sizes and the SHA-256 of the body. Useful for debugging slow or flaky sites"),
+		),
 	)
 
 	// Register the tool handler
@@ -57,34 +99,105 @@ func RegisterFetchTool(mcpServer *server.MCPServer, f fetcher.Fetcher, cfg *conf
 			startIndex = int(startIndexVal)
 		}
 		
+		var maxTokens int
+		if maxTokensVal, ok := request.Params.Arguments["max_tokens"].(float64); ok {
+			maxTokens = int(maxTokensVal)
+		}
+
+		var startToken int
+		if startTokenVal, ok := request.Params.Arguments["start_token"].(float64); ok {
+			startToken = int(startTokenVal)
+		}
+
 		var raw bool
 		if rawVal, ok := request.Params.Arguments["raw"].(bool); ok {
 			raw = rawVal
 		}
 
+		formatName, _ := request.Params.Arguments["format"].(string)
+
+		selector, _ := request.Params.Arguments["selector"].(string)
+
+		var excludeSelectors []string
+		if excludeArray, ok := request.Params.Arguments["exclude_selectors"].([]interface{}); ok {
+			for _, e := range excludeArray {
+				if excludeStr, ok := e.(string); ok {
+					excludeSelectors = append(excludeSelectors, excludeStr)
+				}
+			}
+		}
+
+		includeMetadata, _ := request.Params.Arguments["include_metadata"].(bool)
+
+		section, _ := request.Params.Arguments["section"].(string)
+
+		httpErrors, _ := request.Params.Arguments["http_errors"].(string)
+
+		includeResponseInfo, _ := request.Params.Arguments["include_response_info"].(bool)
+
 		zap.S().Infow("executing fetch",
 			"url", url,
 			"max_length", maxLength,
 			"start_index", startIndex,
-			"raw", raw)
+			"max_tokens", maxTokens,
+			"start_token", startToken,
+			"raw", raw,
+			"format", formatName,
+			"selector", selector,
+			"exclude_selectors", excludeSelectors,
+			"include_metadata", includeMetadata,
+			"section", section,
+			"http_errors", httpErrors,
+			"include_response_info", includeResponseInfo)
 
 		// Validate URL
 		if url == "" {
-			return mcp.NewToolResultError("URL is required"), nil
+			return invalidArgument("URL is required"), nil
 		}
 
-		// Set default values
-		if maxLength <= 0 {
-			maxLength = cfg.Fetch.DefaultMaxLength
+		format, err := fetcher.ParseFormat(formatName)
+		if err != nil {
+			return toolError("", err), nil
+		}
+		if httpErrors != "" {
+			if httpErrors, err = fetcher.ParseHTTPErrorPolicy(httpErrors); err != nil {
+				return toolError("", err), nil
+			}
+		}
+
+		// Set default values; a token budget replaces the default character
+		// limit, which still caps content requested from a start_token alone
+		if maxLength <= 0 && maxTokens <= 0 {
+			maxLength = clientMaxLength(ctx, cfg.Fetch.DefaultMaxLength)
 		}
 
 		// Fetch URL with parameters using the Fetcher interface
-		response, err := f.Fetch(url, maxLength, startIndex, raw)
+		response, err := clientFetcher(ctx, f).FetchWithOptions(url, fetcher.FetchOptions{
+			MaxLength:           maxLength,
+			StartIndex:          startIndex,
+			MaxTokens:           maxTokens,
+			StartToken:          startToken,
+			Raw:                 raw,
+			Format:              format,
+			Selector:            selector,
+			ExcludeSelectors:    excludeSelectors,
+			IncludeMetadata:     includeMetadata,
+			Section:             section,
+			Progress:            progressNotifier(ctx, request),
+			HTTPErrors:          httpErrors,
+			IncludeResponseInfo: includeResponseInfo,
+		})
+		if errors.Is(err, fetcher.ErrRobotsDisallowed) {
+			zap.S().Warnw("URL blocked by robots.txt",
+				"url", url,
+				"error", err)
+			return toolError("blocked by robots.txt", err), nil
+		}
 		if err != nil {
 			zap.S().Errorw("failed to fetch URL",
 				"url", url,
 				"error", err)
-			return mcp.NewToolResultError(fmt.Sprintf("failed to fetch URL: %s", err.Error())), nil
+			return toolError("failed to fetch URL", err), nil
 		}
 
 		// Convert response to JSON
@@ -92,7 +205,7 @@ func RegisterFetchTool(mcpServer *server.MCPServer, f fetcher.Fetcher, cfg *conf
 		if err != nil {
 			zap.S().Errorw("failed to marshal response to JSON",
 				"error", err)
-			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal response to JSON: %s", err.Error())), nil
+			return toolError("failed to marshal response to JSON", err), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonResponse)), nil
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/cnosuke/mcp-fetch/fetcher"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// FetchLinksArgs - Arguments for fetch_links tool
+type FetchLinksArgs struct {
+	URL      string `json:"url" jsonschema:"description=URL to extract links from,required=true"`
+	Pattern  string `json:"pattern,omitempty" jsonschema:"description=Regular expression the absolute link URL must match"`
+	SameHost bool   `json:"same_host,omitempty" jsonschema:"description=Only return links to the same host as the page"`
+	Unique   bool   `json:"unique,omitempty" jsonschema:"description=Remove duplicate links (ignoring URL fragments)"`
+}
+
+// RegisterFetchLinksTool - Register the fetch_links tool
+func RegisterFetchLinksTool(mcpServer *server.MCPServer, f fetcher.Fetcher) error {
+	zap.S().Debugw("registering fetch_links tool")
+
+	// Define the tool
+	tool := mcp.NewTool("fetch_links",
+		mcp.WithDescription("Fetches a URL and returns every link on the page as structured data (absolute URL, anchor text, rel, same-host flag and page section)."),
+		mcp.WithString("url",
+			mcp.Description("URL to extract links from"),
+			mcp.Required(),
+		),
+		mcp.WithString("pattern",
+			mcp.Description("Regular expression the absolute link URL must match"),
+		),
+		mcp.WithBoolean("same_host",
+			mcp.Description("Only return links to the same host as the page"),
+		),
+		mcp.WithBoolean("unique",
+			mcp.Description("Remove duplicate links (ignoring URL fragments)"),
+		),
+	)
+
+	// Register the tool handler
+	mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract parameters
+		url, _ := request.Params.Arguments["url"].(string)
+		pattern, _ := request.Params.Arguments["pattern"].(string)
+		sameHost, _ := request.Params.Arguments["same_host"].(bool)
+		unique, _ := request.Params.Arguments["unique"].(bool)
+
+		zap.S().Infow("executing fetch_links",
+			"url", url,
+			"pattern", pattern,
+			"same_host", sameHost,
+			"unique", unique)
+
+		// Validate URL
+		if url == "" {
+			return invalidArgument("URL is required"), nil
+		}
+
+		response, err := clientFetcher(ctx, f).FetchLinks(url, fetcher.LinkOptions{
+			Pattern:  pattern,
+			SameHost: sameHost,
+			Unique:   unique,
+		})
+		if err != nil {
+			zap.S().Errorw("failed to fetch links",
+				"url", url,
+				"error", err)
+			return toolError("failed to fetch links", err), nil
+		}
+
+		// Convert response to JSON
+		jsonResponse, err := json.Marshal(response)
+		if err != nil {
+			zap.S().Errorw("failed to marshal response to JSON",
+				"error", err)
+			return toolError("failed to marshal response to JSON", err), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	})
+
+	return nil
+}
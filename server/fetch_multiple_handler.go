@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/cnosuke/mcp-fetch/config"
 	"github.com/cnosuke/mcp-fetch/fetcher"
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"go.uber.org/zap"
@@ -14,9 +16,37 @@ import (
 
 // FetchMultipleArgs - Arguments for fetch_multiple tool (kept for test compatibility)
 type FetchMultipleArgs struct {
-	URLs      []string `json:"urls" jsonschema:"description=URLs to fetch (maximum depends on config),maxItems=100"`
-	MaxLength int      `json:"max_length,omitempty" jsonschema:"description=Maximum total number of characters to return across all URLs combined"`
-	Raw       bool     `json:"raw,omitempty" jsonschema:"description=Get raw content without markdown conversion"`
+	URLs         []interface{} `json:"urls,omitempty" jsonschema:"description=URLs to fetch as strings or objects with per-URL settings (maximum depends on config),maxItems=100"`
+	MaxLength    int           `json:"max_length,omitempty" jsonschema:"description=Maximum total number of characters to return across all URLs combined"`
+	MaxTokens    int           `json:"max_tokens,omitempty" jsonschema:"description=Maximum total number of tokens to return across all URLs combined (used instead of max_length)"`
+	Raw          bool          `json:"raw,omitempty" jsonschema:"description=Get raw content without markdown conversion"`
+	Format       string        `json:"format,omitempty" jsonschema:"description=Output format for HTML content,enum=markdown,enum=text,enum=html,enum=json"`
+	Continuation string        `json:"continuation,omitempty" jsonschema:"description=Continuation token from a previous fetch_multiple response"`
+	DeadlineMs   int           `json:"deadline_ms,omitempty" jsonschema:"description=Overall deadline for the batch in milliseconds"`
+	HTTPErrors   string        `json:"http_errors,omitempty" jsonschema:"description=Handling of 4xx/5xx responses,enum=content,enum=excerpt,enum=error"`
+	// IncludeResponseInfo adds headers, timings and transfer stats to each result
+	IncludeResponseInfo bool `json:"include_response_info,omitempty" jsonschema:"description=Include response headers, timings and transfer stats"`
+}
+
+// fetchMultipleItemSchema is the JSON schema of a urls entry: a URL string or
+// an object with per-URL settings.
+var fetchMultipleItemSchema = map[string]any{
+	"anyOf": []any{
+		map[string]any{"type": "string"},
+		map[string]any{
+			"type":     "object",
+			"required": []string{"url"},
+			"properties": map[string]any{
+				"url":         map[string]any{"type": "string", "description": "URL to fetch"},
+				"weight":      map[string]any{"type": "number", "description": "Share of the budget relative to the other URLs (default: 1)"},
+				"max_length":  map[string]any{"type": "number", "description": "Cap on the content returned for this URL, in characters (or tokens with max_tokens)"},
+				"start_index": map[string]any{"type": "number", "description": "Offset into the processed content, in characters (or tokens with max_tokens)"},
+				"raw":         map[string]any{"type": "boolean", "description": "Get raw content without markdown conversion"},
+				"format":      map[string]any{"type": "string", "enum": fetcher.Formats, "description": "Output format for HTML content"},
+				"selector":    map[string]any{"type": "string", "description": "CSS selector limiting processing to matching elements"},
+			},
+		},
+	},
 }
 
 // RegisterFetchMultipleTool - Register the fetch_multiple tool
@@ -25,30 +55,46 @@ func RegisterFetchMultipleTool(mcpServer *server.MCPServer, f fetcher.Fetcher, m
 
 	// Define the tool
 	tool := mcp.NewTool("fetch_multiple",
-		mcp.WithDescription(fmt.Sprintf("Fetch content from multiple URLs (max %d). Default max_length is %d.", maxURLs, cfg.Fetch.DefaultMaxLength)),
+		mcp.WithDescription(fmt.Sprintf("Fetch content from multiple URLs (max %d). The length budget is shared by all URLs according to their weights; results are returned in request order. When content is truncated, the response includes a continuation token that fetches the next slice of every truncated document. Default max_length is %d.", maxURLs, cfg.Fetch.DefaultMaxLength)),
 		mcp.WithArray("urls",
-			mcp.Description(fmt.Sprintf("URLs to fetch (maximum %d)", maxURLs)),
-			mcp.Required(),
+			mcp.Description(fmt.Sprintf("URLs to fetch (maximum %d). Each entry is a URL string or an object with url and optional weight, max_length, start_index, raw, format and selector. Required unless continuation is given", maxURLs)),
+			mcp.Items(fetchMultipleItemSchema),
 			mcp.MaxItems(100),
 		),
 		mcp.WithNumber("max_length",
 			mcp.Description("Maximum total number of characters to return across all URLs combined"),
 		),
+		mcp.WithNumber("max_tokens",
+			mcp.Description("Maximum total number of tokens to return across all URLs combined. When set, the budget is distributed in tokens instead of characters"),
+		),
 		mcp.WithBoolean("raw",
 			mcp.Description("Get raw content without markdown conversion"),
 		),
+		mcp.WithString("format",
+			mcp.Description("Output format for HTML content: markdown (default), text (no markup), html (readability-cleaned HTML) or json (block tree of headings, paragraphs, lists, code and tables)"),
+			mcp.Enum(fetcher.Formats...),
+		),
+		mcp.WithNumber("deadline_ms",
+			mcp.Description(deadlineDescription(cfg.Fetch.DeadlineMs)),
+		),
+		mcp.WithString("http_errors",
+			mcp.Description(httpErrorsDescription(cfg.Fetch.HTTPErrors)),
+			mcp.Enum(fetcher.HTTPErrorPolicies...),
+		),
+		mcp.WithBoolean("include_response_info",
+			mcp.Description("Include response_info in each result with selected response headers, timings (DNS, connect, TLS, time to first byte, total, processing), remote address, protocol, compressed and decompressed sizes and the SHA-256 of the body. Useful for debugging slow or flaky sites"),
+		),
+		mcp.WithString("continuation",
+			mcp.Description("Continuation token from a previous response. Fetches the next slice of each truncated document with the same settings; urls, raw, format, deadline_ms, http_errors and include_response_info are ignored, and max_length or max_tokens (matching the original unit) replace the budget"),
+		),
 	)
 
 	// Register the tool handler
 	mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Extract parameters
-		var urls []string
-		if urlsArray, ok := request.Params.Arguments["urls"].([]interface{}); ok {
-			for _, u := range urlsArray {
-				if urlStr, ok := u.(string); ok {
-					urls = append(urls, urlStr)
-				}
-			}
+		urls, items, err := fetchMultipleItems(request.Params.Arguments["urls"])
+		if err != nil {
+			return toolError("", err), nil
 		}
 
 		var maxLength int
@@ -56,37 +102,95 @@ func RegisterFetchMultipleTool(mcpServer *server.MCPServer, f fetcher.Fetcher, m
 			maxLength = int(maxLengthVal)
 		}
 
+		var maxTokens int
+		if maxTokensVal, ok := request.Params.Arguments["max_tokens"].(float64); ok {
+			maxTokens = int(maxTokensVal)
+		}
+
 		var raw bool
 		if rawVal, ok := request.Params.Arguments["raw"].(bool); ok {
 			raw = rawVal
 		}
 
+		formatName, _ := request.Params.Arguments["format"].(string)
+		continuation, _ := request.Params.Arguments["continuation"].(string)
+		httpErrors, _ := request.Params.Arguments["http_errors"].(string)
+		includeResponseInfo, _ := request.Params.Arguments["include_response_info"].(bool)
+
+		deadlineMs := cfg.Fetch.DeadlineMs
+		if deadlineVal, ok := request.Params.Arguments["deadline_ms"].(float64); ok {
+			deadlineMs = int(deadlineVal)
+		}
+
 		// Log the request
 		zap.S().Debugw("executing fetch_multiple",
 			"urls_count", len(urls),
 			"max_length", maxLength,
-			"raw", raw)
+			"max_tokens", maxTokens,
+			"raw", raw,
+			"format", formatName,
+			"deadline_ms", deadlineMs,
+			"http_errors", httpErrors,
+			"include_response_info", includeResponseInfo,
+			"continuation", continuation != "")
+
+		var opts fetcher.MultipleFetchOptions
+		if continuation != "" {
+			// Resume truncated documents, optionally with a new budget in the same unit
+			urls, opts, err = fetcher.ParseContinuation(continuation)
+			if err != nil {
+				return toolError("", err), nil
+			}
+			if opts.MaxTokens > 0 && maxTokens > 0 {
+				opts.MaxTokens = maxTokens
+			} else if opts.MaxTokens == 0 && maxLength > 0 {
+				opts.MaxLength = maxLength
+			}
+		} else {
+			format, err := fetcher.ParseFormat(formatName)
+			if err != nil {
+				return toolError("", err), nil
+			}
+
+			// Set default values
+			if maxLength <= 0 {
+				maxLength = clientMaxLength(ctx, cfg.Fetch.DefaultMaxLength)
+			}
+
+			opts = fetcher.MultipleFetchOptions{
+				MaxLength:           maxLength,
+				MaxTokens:           maxTokens,
+				Raw:                 raw,
+				Format:              format,
+				Items:               items,
+				IncludeResponseInfo: includeResponseInfo,
+			}
+			if httpErrors != "" {
+				if opts.HTTPErrors, err = fetcher.ParseHTTPErrorPolicy(httpErrors); err != nil {
+					return toolError("", err), nil
+				}
+			}
+			if deadlineMs > 0 {
+				opts.Deadline = time.Duration(deadlineMs) * time.Millisecond
+			}
+		}
 
 		// Validate URLs count
 		if len(urls) == 0 {
-			return mcp.NewToolResultError("at least one URL is required"), nil
+			return invalidArgument("at least one URL is required"), nil
 		}
 
 		if len(urls) > maxURLs {
-			return mcp.NewToolResultError(fmt.Sprintf("too many URLs: maximum allowed is %d", maxURLs)), nil
-		}
-
-		// Set default values
-		if maxLength <= 0 {
-			maxLength = cfg.Fetch.DefaultMaxLength
+			return invalidArgument(fmt.Sprintf("too many URLs: maximum allowed is %d", maxURLs)), nil
 		}
 
 		// Fetch URLs with parameters using the Fetcher interface
-		response, err := f.FetchMultiple(urls, maxLength, raw)
+		opts.Progress = progressNotifier(ctx, request)
+		response, err := clientFetcher(ctx, f).FetchMultipleWithOptions(ctx, urls, opts)
 		if err != nil {
 			zap.S().Errorw("failed to fetch multiple URLs",
 				"error", err)
-			return mcp.NewToolResultError(fmt.Sprintf("failed to fetch multiple URLs: %s", err.Error())), nil
+			return toolError("failed to fetch multiple URLs", err), nil
 		}
 
 		// Convert response to JSON
@@ -94,7 +198,7 @@ func RegisterFetchMultipleTool(mcpServer *server.MCPServer, f fetcher.Fetcher, m
 		if err != nil {
 			zap.S().Errorw("failed to marshal response to JSON",
 				"error", err)
-			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal response to JSON: %s", err.Error())), nil
+			return toolError("failed to marshal response to JSON", err), nil
 		}
 
 		return mcp.NewToolResultText(string(jsonResponse)), nil
@@ -102,3 +206,70 @@ func RegisterFetchMultipleTool(mcpServer *server.MCPServer, f fetcher.Fetcher, m
 
 	return nil
 }
+
+// fetchMultipleItems converts the urls argument of fetch_multiple, whose
+// entries are URL strings or objects with per-URL settings. Items are nil if
+// every entry is a plain string.
+func fetchMultipleItems(arg interface{}) ([]string, []fetcher.MultipleFetchItem, error) {
+	array, _ := arg.([]interface{})
+	urls := []string{}
+	items := []fetcher.MultipleFetchItem{}
+	hasSettings := false
+	for i, entry := range array {
+		switch v := entry.(type) {
+		case string:
+			urls = append(urls, v)
+			items = append(items, fetcher.MultipleFetchItem{})
+		case map[string]interface{}:
+			urlStr, _ := v["url"].(string)
+			if urlStr == "" {
+				return nil, nil, ierrors.Newf(ierrors.ErrInvalidArgument, "urls[%d]: url is required", i)
+			}
+			item := fetcher.MultipleFetchItem{}
+			if weight, ok := v["weight"].(float64); ok {
+				item.Weight = weight
+			}
+			if maxLength, ok := v["max_length"].(float64); ok {
+				item.MaxLength = int(maxLength)
+			}
+			if startIndex, ok := v["start_index"].(float64); ok {
+				item.StartIndex = int(startIndex)
+			}
+			if raw, ok := v["raw"].(bool); ok {
+				item.Raw = &raw
+			}
+			if formatName, ok := v["format"].(string); ok {
+				format, err := fetcher.ParseFormat(formatName)
+				if err != nil {
+					return nil, nil, ierrors.Wrapf(err, "urls[%d]", i)
+				}
+				item.Format = format
+			}
+			item.Selector, _ = v["selector"].(string)
+			urls = append(urls, urlStr)
+			items = append(items, item)
+			hasSettings = true
+		}
+	}
+	if !hasSettings {
+		items = nil
+	}
+	return urls, items, nil
+}
+
+// deadlineDescription describes the deadline_ms argument with its configured default.
+func deadlineDescription(defaultMs int) string {
+	description := "Overall deadline for the batch in milliseconds. URLs not fetched in time are returned with timed_out set and the budget is shared by the others"
+	if defaultMs > 0 {
+		return fmt.Sprintf("%s (default: %d)", description, defaultMs)
+	}
+	return description + " (default: none)"
+}
+
+// httpErrorsDescription describes the http_errors argument with its configured default.
+func httpErrorsDescription(defaultPolicy string) string {
+	if defaultPolicy == "" {
+		defaultPolicy = fetcher.HTTPErrorsContent
+	}
+	return fmt.Sprintf("Handling of 4xx/5xx responses: content returns the page with an http_error flag, excerpt returns only its first %d characters, error fails with an http_status error (default: %s)", fetcher.HTTPErrorExcerptLength, defaultPolicy)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/cnosuke/mcp-fetch/fetcher"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// FetchOutlineArgs - Arguments for fetch_outline tool
+type FetchOutlineArgs struct {
+	URL string `json:"url" jsonschema:"description=URL to build the outline of,required=true"`
+}
+
+// RegisterFetchOutlineTool - Register the fetch_outline tool
+func RegisterFetchOutlineTool(mcpServer *server.MCPServer, f fetcher.Fetcher) error {
+	zap.S().Debugw("registering fetch_outline tool")
+
+	// Define the tool
+	tool := mcp.NewTool("fetch_outline",
+		mcp.WithDescription("Fetches a URL and returns its heading tree. Each heading has its anchor (usable as the fetch section argument) and the character offset and length of its section in the markdown returned by fetch (usable as start_index and max_length)."),
+		mcp.WithString("url",
+			mcp.Description("URL to build the outline of"),
+			mcp.Required(),
+		),
+	)
+
+	// Register the tool handler
+	mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract parameters
+		url, _ := request.Params.Arguments["url"].(string)
+
+		zap.S().Infow("executing fetch_outline", "url", url)
+
+		// Validate URL
+		if url == "" {
+			return invalidArgument("URL is required"), nil
+		}
+
+		response, err := clientFetcher(ctx, f).FetchOutline(url)
+		if err != nil {
+			zap.S().Errorw("failed to fetch outline",
+				"url", url,
+				"error", err)
+			return toolError("failed to fetch outline", err), nil
+		}
+
+		// Convert response to JSON
+		jsonResponse, err := json.Marshal(response)
+		if err != nil {
+			zap.S().Errorw("failed to marshal response to JSON",
+				"error", err)
+			return toolError("failed to marshal response to JSON", err), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	})
+
+	return nil
+}
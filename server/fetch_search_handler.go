@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/cnosuke/mcp-fetch/fetcher"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// FetchSearchArgs - Arguments for fetch_search tool
+type FetchSearchArgs struct {
+	URL        string `json:"url" jsonschema:"description=URL to search,required=true"`
+	Query      string `json:"query" jsonschema:"description=Keywords or regular expression to search for,required=true"`
+	Regex      bool   `json:"regex,omitempty" jsonschema:"description=Treat query as a regular expression"`
+	MaxResults int    `json:"max_results,omitempty" jsonschema:"description=Maximum number of matches to return"`
+	Context    int    `json:"context,omitempty" jsonschema:"description=Number of paragraphs of context before and after each match"`
+}
+
+// RegisterFetchSearchTool - Register the fetch_search tool
+func RegisterFetchSearchTool(mcpServer *server.MCPServer, f fetcher.Fetcher) error {
+	zap.S().Debugw("registering fetch_search tool")
+
+	// Define the tool
+	tool := mcp.NewTool("fetch_search",
+		mcp.WithDescription("Fetches a URL, extracts its contents as markdown and returns the paragraphs matching a query, best matches first. Each match has surrounding context, its heading path and its character offset, usable as start_index in a follow-up fetch."),
+		mcp.WithString("url",
+			mcp.Description("URL to search"),
+			mcp.Required(),
+		),
+		mcp.WithString("query",
+			mcp.Description("Keywords (matched case-insensitively; paragraphs matching more keywords rank higher) or a regular expression when regex is set"),
+			mcp.Required(),
+		),
+		mcp.WithBoolean("regex",
+			mcp.Description("Treat query as a regular expression (Go RE2 syntax, use (?i) for case-insensitive matching)"),
+		),
+		mcp.WithNumber("max_results",
+			mcp.Description("Maximum number of matches to return (default: 10)"),
+		),
+		mcp.WithNumber("context",
+			mcp.Description("Number of paragraphs of context before and after each match (default: 1, maximum: 5)"),
+		),
+	)
+
+	// Register the tool handler
+	mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract parameters
+		url, _ := request.Params.Arguments["url"].(string)
+		query, _ := request.Params.Arguments["query"].(string)
+		regex, _ := request.Params.Arguments["regex"].(bool)
+
+		var maxResults int
+		if maxResultsVal, ok := request.Params.Arguments["max_results"].(float64); ok {
+			maxResults = int(maxResultsVal)
+		}
+
+		contextParagraphs := 1
+		if contextVal, ok := request.Params.Arguments["context"].(float64); ok {
+			contextParagraphs = int(contextVal)
+		}
+
+		zap.S().Infow("executing fetch_search",
+			"url", url,
+			"query", query,
+			"regex", regex,
+			"max_results", maxResults,
+			"context", contextParagraphs)
+
+		// Validate parameters
+		if url == "" {
+			return invalidArgument("URL is required"), nil
+		}
+		if query == "" {
+			return invalidArgument("query is required"), nil
+		}
+
+		response, err := clientFetcher(ctx, f).FetchSearch(url, fetcher.SearchOptions{
+			Query:      query,
+			Regex:      regex,
+			MaxResults: maxResults,
+			Context:    contextParagraphs,
+		})
+		if err != nil {
+			zap.S().Errorw("failed to search URL",
+				"url", url,
+				"error", err)
+			return toolError("failed to search URL", err), nil
+		}
+
+		// Convert response to JSON
+		jsonResponse, err := json.Marshal(response)
+		if err != nil {
+			zap.S().Errorw("failed to marshal response to JSON",
+				"error", err)
+			return toolError("failed to marshal response to JSON", err), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	})
+
+	return nil
+}
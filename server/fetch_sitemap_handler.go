@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cnosuke/mcp-fetch/fetcher"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// FetchSitemapArgs - Arguments for fetch_sitemap tool
+type FetchSitemapArgs struct {
+	URL           string `json:"url" jsonschema:"description=Site URL or sitemap URL,required=true"`
+	PathPrefix    string `json:"path_prefix,omitempty" jsonschema:"description=Only return URLs under this path"`
+	Pattern       string `json:"pattern,omitempty" jsonschema:"description=Regular expression URLs must match"`
+	ModifiedSince string `json:"modified_since,omitempty" jsonschema:"description=Only return URLs modified at or after this date"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"description=Maximum number of URLs to return"`
+}
+
+// RegisterFetchSitemapTool - Register the fetch_sitemap tool
+func RegisterFetchSitemapTool(mcpServer *server.MCPServer, f fetcher.Fetcher, maxURLs int) error {
+	zap.S().Debugw("registering fetch_sitemap tool", "max_urls", maxURLs)
+
+	// Define the tool
+	tool := mcp.NewTool("fetch_sitemap",
+		mcp.WithDescription(fmt.Sprintf("Lists the URLs of a site from its sitemaps, with lastmod, changefreq and priority. Sitemaps are discovered through robots.txt and /sitemap.xml (or read from a sitemap URL directly); sitemap indexes and gzipped sitemaps are expanded. The returned urls array can be passed as-is to fetch_multiple (default limit %d).", maxURLs)),
+		mcp.WithString("url",
+			mcp.Description("Site URL, or the URL of a sitemap or sitemap index"),
+			mcp.Required(),
+		),
+		mcp.WithString("path_prefix",
+			mcp.Description("Only return URLs whose path starts with this prefix (e.g. \"/docs/\")"),
+		),
+		mcp.WithString("pattern",
+			mcp.Description("Regular expression (Go RE2 syntax) the URLs must match"),
+		),
+		mcp.WithString("modified_since",
+			mcp.Description("Only return URLs with a lastmod at or after this date (e.g. \"2024-01-31\" or \"2024-01-31T12:00:00Z\"); URLs without lastmod are excluded"),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description(fmt.Sprintf("Maximum number of URLs to return (default: %d, maximum: %d)", maxURLs, fetcher.MaxSitemapLimit)),
+		),
+	)
+
+	// Register the tool handler
+	mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract parameters
+		url, _ := request.Params.Arguments["url"].(string)
+		pathPrefix, _ := request.Params.Arguments["path_prefix"].(string)
+		pattern, _ := request.Params.Arguments["pattern"].(string)
+		modifiedSinceStr, _ := request.Params.Arguments["modified_since"].(string)
+
+		var limit int
+		if limitVal, ok := request.Params.Arguments["limit"].(float64); ok {
+			limit = int(limitVal)
+		}
+
+		zap.S().Infow("executing fetch_sitemap",
+			"url", url,
+			"path_prefix", pathPrefix,
+			"pattern", pattern,
+			"modified_since", modifiedSinceStr,
+			"limit", limit)
+
+		// Validate URL
+		if url == "" {
+			return invalidArgument("URL is required"), nil
+		}
+
+		var modifiedSince time.Time
+		if modifiedSinceStr != "" {
+			var err error
+			if modifiedSince, err = fetcher.ParseSitemapDate(modifiedSinceStr); err != nil {
+				return toolError("invalid modified_since", err), nil
+			}
+		}
+
+		// Set default values so the result fits in a single fetch_multiple call
+		if limit <= 0 {
+			limit = maxURLs
+		}
+
+		response, err := clientFetcher(ctx, f).FetchSitemap(url, fetcher.SitemapOptions{
+			PathPrefix:    pathPrefix,
+			Pattern:       pattern,
+			ModifiedSince: modifiedSince,
+			Limit:         limit,
+		})
+		if err != nil {
+			zap.S().Errorw("failed to fetch sitemap",
+				"url", url,
+				"error", err)
+			return toolError("failed to fetch sitemap", err), nil
+		}
+
+		// Convert response to JSON
+		jsonResponse, err := json.Marshal(response)
+		if err != nil {
+			zap.S().Errorw("failed to marshal response to JSON",
+				"error", err)
+			return toolError("failed to marshal response to JSON", err), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	})
+
+	return nil
+}
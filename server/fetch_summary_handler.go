@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cnosuke/mcp-fetch/fetcher"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// FetchSummaryArgs - Arguments for fetch_summary tool
+type FetchSummaryArgs struct {
+	URL         string `json:"url" jsonschema:"description=URL to summarize,required=true"`
+	Focus       string `json:"focus,omitempty" jsonschema:"description=Topic or question the summary should focus on"`
+	MaxTokens   int    `json:"max_tokens,omitempty" jsonschema:"description=Maximum tokens of the summary"`
+	ChunkLength int    `json:"chunk_length,omitempty" jsonschema:"description=Maximum characters of content per sampling request"`
+}
+
+// RegisterFetchSummaryTool - Register the fetch_summary tool
+func RegisterFetchSummaryTool(mcpServer *server.MCPServer, f fetcher.Fetcher, sampling *Sampling) error {
+	zap.S().Debugw("registering fetch_summary tool")
+
+	// Define the tool
+	tool := mcp.NewTool("fetch_summary",
+		mcp.WithDescription("Fetches a URL and summarizes it without returning the full content. The markdown content is split by section into chunks, which the client's own model summarizes through MCP sampling (sampling/createMessage), then the partial summaries are combined. The summary cites sections as [S1], [S2], ..., listed with their heading, anchor and character offset, usable as start_index in a follow-up fetch. Requires a client that supports sampling."),
+		mcp.WithString("url",
+			mcp.Description("URL to summarize"),
+			mcp.Required(),
+		),
+		mcp.WithString("focus",
+			mcp.Description("Topic or question the summary should focus on"),
+		),
+		mcp.WithNumber("max_tokens",
+			mcp.Description(fmt.Sprintf("Maximum tokens of the summary, and of each partial summary (default: %d)", fetcher.DefaultSummaryTokens)),
+		),
+		mcp.WithNumber("chunk_length",
+			mcp.Description(fmt.Sprintf("Maximum characters of content per sampling request (default: %d, minimum: 1000)", fetcher.DefaultSummaryChunkLength)),
+		),
+	)
+
+	// Register the tool handler
+	mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract parameters
+		url, _ := request.Params.Arguments["url"].(string)
+		focus, _ := request.Params.Arguments["focus"].(string)
+
+		var maxTokens int
+		if maxTokensVal, ok := request.Params.Arguments["max_tokens"].(float64); ok {
+			maxTokens = int(maxTokensVal)
+		}
+
+		var chunkLength int
+		if chunkLengthVal, ok := request.Params.Arguments["chunk_length"].(float64); ok {
+			chunkLength = int(chunkLengthVal)
+		}
+
+		zap.S().Infow("executing fetch_summary",
+			"url", url,
+			"focus", focus,
+			"max_tokens", maxTokens,
+			"chunk_length", chunkLength)
+
+		// Validate parameters
+		if url == "" {
+			return invalidArgument("URL is required"), nil
+		}
+		if maxTokens < 0 {
+			return invalidArgument("max_tokens must be positive"), nil
+		}
+
+		sampler, err := sampling.Sampler(server.ClientSessionFromContext(ctx))
+		if err != nil {
+			return toolError("cannot summarize", err), nil
+		}
+
+		response, err := clientFetcher(ctx, f).FetchSummary(ctx, url, fetcher.SummaryOptions{
+			Sampler:     sampler,
+			Focus:       focus,
+			ChunkLength: chunkLength,
+			MaxTokens:   maxTokens,
+			Progress:    progressNotifier(ctx, request),
+		})
+		if err != nil {
+			zap.S().Errorw("failed to summarize URL",
+				"url", url,
+				"error", err)
+			return toolError("failed to summarize URL", err), nil
+		}
+
+		// Convert response to JSON
+		jsonResponse, err := json.Marshal(response)
+		if err != nil {
+			zap.S().Errorw("failed to marshal response to JSON",
+				"error", err)
+			return toolError("failed to marshal response to JSON", err), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	})
+
+	return nil
+}
@@ -1,19 +1,50 @@
 package server
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/cnosuke/mcp-fetch/config"
+	"github.com/cnosuke/mcp-fetch/fetcher"
 	"github.com/cnosuke/mcp-fetch/types"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockFetcher for testing
 type MockFetcher struct {
 	defaultResponse *types.FetchResponse
+	guard           fetcher.URLGuard
+}
+
+// WithGuard - Mock implementation, checking the guard in Fetch
+func (f *MockFetcher) WithGuard(guard fetcher.URLGuard) fetcher.Fetcher {
+	guarded := *f
+	guarded.guard = guard
+	return &guarded
 }
 
 // Fetch - Mock implementation
 func (f *MockFetcher) Fetch(urlStr string, maxLength int, startIndex int, raw bool) (*types.FetchResponse, error) {
+	if f.guard != nil {
+		u, err := url.Parse(urlStr)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.guard(u, false); err != nil {
+			return nil, err
+		}
+	}
+
 	// Clone the default response
 	response := &types.FetchResponse{
 		URL:         f.defaultResponse.URL,
@@ -36,28 +67,49 @@ func (f *MockFetcher) Fetch(urlStr string, maxLength int, startIndex int, raw bo
 	return response, nil
 }
 
+// FetchWithOptions - Mock implementation
+func (f *MockFetcher) FetchWithOptions(urlStr string, opts fetcher.FetchOptions) (*types.FetchResponse, error) {
+	return f.Fetch(urlStr, opts.MaxLength, opts.StartIndex, opts.Raw)
+}
+
 // FetchMultiple - Mock implementation
 func (f *MockFetcher) FetchMultiple(urls []string, maxLength int, raw bool) (*types.MultipleFetchResponse, error) {
 	// Create a response with each URL getting the same content
 	response := &types.MultipleFetchResponse{
-		Responses: make(map[string]*types.FetchResponse),
-		Errors:    make(map[string]string),
+		Results:   []*types.FetchResult{},
+		Unit:      "chars",
+		MaxLength: maxLength,
 	}
 
 	totalLength := 0
 	for _, url := range urls {
 		// Get a response for this URL
-		urlResponse, _ := f.Fetch(url, 0, 0, raw)
-		
+		urlResponse, err := f.Fetch(url, 0, 0, raw)
+		if err != nil {
+			response.Results = append(response.Results, &types.FetchResult{URL: url, Error: &types.FetchError{Message: err.Error()}})
+			response.Failed++
+			continue
+		}
+		result := &types.FetchResult{
+			URL:         url,
+			FinalURL:    urlResponse.URL,
+			StatusCode:  urlResponse.StatusCode,
+			ContentType: urlResponse.ContentType,
+			Content:     urlResponse.Content,
+			FullLength:  len(urlResponse.Content),
+		}
+
 		// Check if adding this would exceed the total maxLength
 		if maxLength > 0 {
-			contentLength := len(urlResponse.Content)
-			if totalLength + contentLength > maxLength {
+			contentLength := len(result.Content)
+			if totalLength+contentLength > maxLength {
 				remainingLength := maxLength - totalLength
 				if remainingLength > 0 {
 					// Trim to fit
-					urlResponse.Content = urlResponse.Content[:remainingLength]
-					response.Responses[url] = urlResponse
+					result.Content = result.Content[:remainingLength]
+					result.AllocatedLength = remainingLength
+					result.Truncated = true
+					response.Results = append(response.Results, result)
 					totalLength += remainingLength
 				}
 				// Stop processing more URLs once we hit the limit
@@ -65,13 +117,89 @@ func (f *MockFetcher) FetchMultiple(urls []string, maxLength int, raw bool) (*ty
 			}
 			totalLength += contentLength
 		}
-		
-		response.Responses[url] = urlResponse
+
+		result.AllocatedLength = len(result.Content)
+		response.Results = append(response.Results, result)
 	}
+	response.Succeeded = len(response.Results) - response.Failed
 
 	return response, nil
 }
 
+// FetchMultipleWithOptions - Mock implementation
+func (f *MockFetcher) FetchMultipleWithOptions(ctx context.Context, urls []string, opts fetcher.MultipleFetchOptions) (*types.MultipleFetchResponse, error) {
+	if opts.Progress != nil {
+		for i, url := range urls {
+			opts.Progress(float64(i+1), float64(len(urls)), "fetched "+url)
+		}
+	}
+	return f.FetchMultiple(urls, opts.MaxLength, opts.Raw)
+}
+
+// FetchLinks - Mock implementation
+func (f *MockFetcher) FetchLinks(urlStr string, opts fetcher.LinkOptions) (*types.LinksResponse, error) {
+	return &types.LinksResponse{
+		URL:        urlStr,
+		StatusCode: f.defaultResponse.StatusCode,
+		Links:      []types.Link{},
+	}, nil
+}
+
+// FetchOutline - Mock implementation
+func (f *MockFetcher) FetchOutline(urlStr string) (*types.OutlineResponse, error) {
+	return &types.OutlineResponse{
+		URL:        urlStr,
+		StatusCode: f.defaultResponse.StatusCode,
+		Headings:   []*types.Heading{},
+	}, nil
+}
+
+// FetchSearch - Mock implementation
+func (f *MockFetcher) FetchSearch(urlStr string, opts fetcher.SearchOptions) (*types.SearchResponse, error) {
+	return &types.SearchResponse{
+		URL:        urlStr,
+		StatusCode: f.defaultResponse.StatusCode,
+		Query:      opts.Query,
+		Matches:    []types.SearchMatch{},
+	}, nil
+}
+
+// Crawl - Mock implementation
+func (f *MockFetcher) Crawl(ctx context.Context, startURL string, opts fetcher.CrawlOptions) (*types.CrawlResponse, error) {
+	return &types.CrawlResponse{
+		StartURL:   startURL,
+		Pages:      []types.CrawlPage{},
+		StopReason: "complete",
+	}, nil
+}
+
+// FetchSitemap - Mock implementation
+func (f *MockFetcher) FetchSitemap(urlStr string, opts fetcher.SitemapOptions) (*types.SitemapResponse, error) {
+	return &types.SitemapResponse{
+		URL:      urlStr,
+		Sitemaps: []string{},
+		Entries:  []types.SitemapEntry{},
+		URLs:     []string{},
+	}, nil
+}
+
+// FetchSummary - Mock implementation
+func (f *MockFetcher) FetchSummary(ctx context.Context, urlStr string, opts fetcher.SummaryOptions) (*types.SummaryResponse, error) {
+	result, err := opts.Sampler.Sample(ctx, fetcher.SampleRequest{Prompt: f.defaultResponse.Content, MaxTokens: opts.MaxTokens})
+	if err != nil {
+		return nil, err
+	}
+	return &types.SummaryResponse{
+		URL:              urlStr,
+		StatusCode:       f.defaultResponse.StatusCode,
+		Summary:          result.Text,
+		Sections:         []types.SummarySection{},
+		Chunks:           1,
+		SamplingRequests: 1,
+		Model:            result.Model,
+	}, nil
+}
+
 // TestFetchFunctionality tests the basic fetch functionality with parameters
 func TestFetchFunctionality(t *testing.T) {
 	// Create mock fetcher with sample data
@@ -107,6 +235,36 @@ func TestFetchFunctionality(t *testing.T) {
 	assert.Equal(t, "sample con", resp4.Content)
 }
 
+// TestFetchToolDefaultMaxLength tests that the default max_length applies
+// unless a token budget is given
+func TestFetchToolDefaultMaxLength(t *testing.T) {
+	mockFetcher := &MockFetcher{
+		defaultResponse: &types.FetchResponse{Content: "This is a sample content string for testing purposes.", StatusCode: 200},
+	}
+	cfg := &config.Config{}
+	cfg.Fetch.DefaultMaxLength = 10
+
+	mcpServer := server.NewMCPServer("test", "1.0.0")
+	require.NoError(t, RegisterFetchTool(mcpServer, mockFetcher, cfg))
+
+	fetchContent := func(arguments string) string {
+		t.Helper()
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"fetch","arguments":` + arguments + `}}`
+		response, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse)
+		require.True(t, ok)
+		result, ok := response.Result.(mcp.CallToolResult)
+		require.True(t, ok)
+		require.False(t, result.IsError)
+		var fetchResponse types.FetchResponse
+		require.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &fetchResponse))
+		return fetchResponse.Content
+	}
+
+	assert.Equal(t, "This is a ", fetchContent(`{"url":"https://example.com"}`))
+	assert.Equal(t, "This is a ", fetchContent(`{"url":"https://example.com","start_token":2}`))
+	assert.Len(t, fetchContent(`{"url":"https://example.com","max_tokens":100}`), 53)
+}
+
 // TestFetchMultipleFunctionality tests the fetch_multiple functionality
 func TestFetchMultipleFunctionality(t *testing.T) {
 	// Create mock fetcher with sample data
@@ -123,18 +281,780 @@ func TestFetchMultipleFunctionality(t *testing.T) {
 	urls := []string{"https://example1.com", "https://example2.com", "https://example3.com"}
 	resp1, err := mockFetcher.FetchMultiple(urls, 0, false)
 	assert.NoError(t, err)
-	assert.Equal(t, 3, len(resp1.Responses))
-	assert.Equal(t, 0, len(resp1.Errors))
+	assert.Equal(t, 3, len(resp1.Results))
+	assert.Equal(t, 0, resp1.Failed)
 
 	// Test 2: Fetch multiple URLs with a total length limit that allows only partial content
 	resp2, err := mockFetcher.FetchMultiple(urls, 150, false)
 	assert.NoError(t, err)
-	assert.LessOrEqual(t, len(resp1.Responses), 3)
-	
+	assert.LessOrEqual(t, len(resp1.Results), 3)
+
 	// Calculate total content length
 	totalLength := 0
-	for _, resp := range resp2.Responses {
+	for _, resp := range resp2.Results {
 		totalLength += len(resp.Content)
 	}
 	assert.LessOrEqual(t, totalLength, 150)
 }
+
+// TestFetchMultipleItems tests parsing of plain and per-URL entries of fetch_multiple
+func TestFetchMultipleItems(t *testing.T) {
+	// Plain URL strings need no per-URL settings
+	urls, items, err := fetchMultipleItems([]interface{}{"https://a.example", "https://b.example"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, urls)
+	assert.Nil(t, items)
+
+	urls, items, err = fetchMultipleItems([]interface{}{
+		"https://a.example",
+		map[string]interface{}{"url": "https://b.example", "weight": 2.0, "max_length": 500.0, "start_index": 100.0, "raw": true, "format": "text", "selector": "main"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://a.example", "https://b.example"}, urls)
+	assert.Len(t, items, 2)
+	assert.Equal(t, fetcher.MultipleFetchItem{}, items[0])
+	assert.Equal(t, 2.0, items[1].Weight)
+	assert.Equal(t, 500, items[1].MaxLength)
+	assert.Equal(t, 100, items[1].StartIndex)
+	assert.True(t, *items[1].Raw)
+	assert.Equal(t, fetcher.FormatText, items[1].Format)
+	assert.Equal(t, "main", items[1].Selector)
+
+	_, _, err = fetchMultipleItems([]interface{}{map[string]interface{}{"weight": 2.0}})
+	assert.Error(t, err)
+	_, _, err = fetchMultipleItems([]interface{}{map[string]interface{}{"url": "https://a.example", "format": "pdf"}})
+	assert.Error(t, err)
+}
+
+// testSession is a client session collecting the notifications sent to it
+type testSession struct {
+	id            string // "test" if empty
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *testSession) Initialize()       {}
+func (s *testSession) Initialized() bool { return true }
+func (s *testSession) SessionID() string {
+	if s.id == "" {
+		return "test"
+	}
+	return s.id
+}
+func (s *testSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+// TestFetchMultipleProgress tests that progress notifications are sent when a progress token is given
+func TestFetchMultipleProgress(t *testing.T) {
+	mockFetcher := &MockFetcher{
+		defaultResponse: &types.FetchResponse{Content: "content", StatusCode: 200},
+	}
+	cfg := &config.Config{}
+	cfg.Fetch.DefaultMaxLength = 1000
+
+	mcpServer := server.NewMCPServer("test", "1.0.0")
+	require.NoError(t, RegisterFetchMultipleTool(mcpServer, mockFetcher, 20, cfg))
+
+	session := &testSession{notifications: make(chan mcp.JSONRPCNotification, 10)}
+	ctx := mcpServer.WithContext(context.Background(), session)
+
+	call := func(meta string) {
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"fetch_multiple","arguments":{"urls":["https://a.example","https://b.example"]}` + meta + `}}`
+		response := mcpServer.HandleMessage(ctx, json.RawMessage(message))
+		_, ok := response.(mcp.JSONRPCResponse)
+		require.True(t, ok, "unexpected response: %#v", response)
+	}
+
+	call(`,"_meta":{"progressToken":"tok-1"}`)
+	require.Len(t, session.notifications, 2)
+	for i := 1; i <= 2; i++ {
+		notification := <-session.notifications
+		assert.Equal(t, "notifications/progress", notification.Method)
+		params := notification.Params.AdditionalFields
+		assert.Equal(t, "tok-1", params["progressToken"])
+		assert.Equal(t, float64(i), params["progress"])
+		assert.Equal(t, float64(2), params["total"])
+	}
+
+	// No notifications without a progress token
+	call("")
+	assert.Len(t, session.notifications, 0)
+}
+
+// TestToolError tests the {code, message, retryable} objects of tool errors
+func TestToolError(t *testing.T) {
+	decode := func(result *mcp.CallToolResult) types.FetchError {
+		t.Helper()
+		require.True(t, result.IsError)
+		require.Len(t, result.Content, 1)
+		text, ok := result.Content[0].(mcp.TextContent)
+		require.True(t, ok)
+		var fetchErr types.FetchError
+		require.NoError(t, json.Unmarshal([]byte(text.Text), &fetchErr))
+		return fetchErr
+	}
+
+	fetchErr := decode(toolError("failed to fetch URL", context.DeadlineExceeded))
+	assert.Equal(t, types.FetchError{Code: "timeout", Message: "failed to fetch URL: context deadline exceeded", Retryable: true}, fetchErr)
+
+	fetchErr = decode(toolError("blocked by robots.txt", &fetcher.RobotsError{URL: "https://example.com/private", UserAgent: "mcp-fetch"}))
+	assert.Equal(t, "robots", fetchErr.Code)
+	assert.False(t, fetchErr.Retryable)
+
+	fetchErr = decode(invalidArgument("URL is required"))
+	assert.Equal(t, types.FetchError{Code: "invalid_argument", Message: "URL is required"}, fetchErr)
+}
+
+// TestFetchResources tests the fetch:// resource template and the fetch-history:// resource
+func TestFetchResources(t *testing.T) {
+	mockFetcher := &MockFetcher{
+		defaultResponse: &types.FetchResponse{
+			URL:         "https://example.com/docs",
+			ContentType: "text/html; charset=utf-8",
+			Content:     "# Docs\n\nSome content.",
+			StatusCode:  200,
+		},
+	}
+	history := fetcher.NewHistory(10)
+	mcpServer := server.NewMCPServer("test", "1.0.0")
+	require.NoError(t, RegisterFetchResources(mcpServer, fetcher.RecordHistory(mockFetcher, history), history, 10))
+
+	read := func(uri string) mcp.TextResourceContents {
+		t.Helper()
+		message := `{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"` + uri + `"}}`
+		response, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse)
+		require.True(t, ok)
+		result, ok := response.Result.(mcp.ReadResourceResult)
+		require.True(t, ok)
+		require.Len(t, result.Contents, 1)
+		contents, ok := result.Contents[0].(mcp.TextResourceContents)
+		require.True(t, ok)
+		return contents
+	}
+
+	contents := read("fetch://https://example.com/docs?page=2")
+	assert.Equal(t, "fetch://https://example.com/docs?page=2", contents.URI)
+	assert.Equal(t, "text/html", contents.MIMEType)
+	assert.Equal(t, "# Docs\n\nSo", contents.Text) // Capped at the resource max length
+
+	contents = read(fetchHistoryURI)
+	assert.Equal(t, "application/json", contents.MIMEType)
+	var historyResponse types.FetchHistoryResponse
+	require.NoError(t, json.Unmarshal([]byte(contents.Text), &historyResponse))
+	require.Len(t, historyResponse.Documents, 1)
+	assert.Equal(t, "https://example.com/docs", historyResponse.Documents[0].URL)
+	assert.Equal(t, "fetch://https://example.com/docs", historyResponse.Documents[0].Resource)
+
+	// Invalid URLs are rejected
+	message := `{"jsonrpc":"2.0","id":1,"method":"resources/read","params":{"uri":"fetch://ftp://example.com/"}}`
+	_, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCError)
+	assert.True(t, ok)
+}
+
+// TestFetchResourceURL tests the URLs accepted in fetch:// resource URIs
+func TestFetchResourceURL(t *testing.T) {
+	tests := []struct {
+		uri      string
+		expected string
+	}{
+		{uri: "fetch://https://example.com/docs#intro", expected: "https://example.com/docs#intro"},
+		{uri: "fetch://http://example.com", expected: "http://example.com"},
+		{uri: "fetch://https%3A%2F%2Fexample.com%2Fa%3Fb%3Dc", expected: "https://example.com/a?b=c"},
+		{uri: "fetch://example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			request := mcp.ReadResourceRequest{}
+			request.Params.URI = tt.uri
+			urlStr, err := fetchResourceURL(request)
+			if tt.expected == "" {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, urlStr)
+		})
+	}
+}
+
+// TestSubscriptions tests resource subscription requests and change notifications
+func TestSubscriptions(t *testing.T) {
+	mockFetcher := &MockFetcher{
+		defaultResponse: &types.FetchResponse{Content: "# Status\n\nAll systems operational", StatusCode: 200},
+	}
+	subs := NewSubscriptions(mockFetcher, fetcher.WatcherConfig{MaxWatches: 1})
+	t.Cleanup(subs.Close)
+	session := &testSession{notifications: make(chan mcp.JSONRPCNotification, 10)}
+	subs.RegisterSession(context.Background(), session)
+
+	handle := func(method string, uri string) mcp.JSONRPCMessage {
+		t.Helper()
+		message := `{"jsonrpc":"2.0","id":1,"method":"` + method + `","params":{"uri":"` + uri + `"}}`
+		response, handled := subs.HandleMessage(context.Background(), session, json.RawMessage(message))
+		require.True(t, handled)
+		return response
+	}
+
+	_, ok := handle("resources/subscribe", "fetch://https://status.example.com").(mcp.JSONRPCResponse)
+	require.True(t, ok)
+	require.Len(t, subs.Watches(context.Background()), 1)
+	assert.Equal(t, "fetch://https://status.example.com", subs.Watches(context.Background())[0].Resource)
+
+	_, ok = handle("resources/subscribe", "fetch://ftp://example.com").(mcp.JSONRPCError)
+	assert.True(t, ok)
+	_, ok = handle("resources/subscribe", "fetch://https://other.example.com").(mcp.JSONRPCError)
+	assert.True(t, ok, "max_watches exceeded")
+
+	// Other messages are left to the MCP server
+	_, handled := subs.HandleMessage(context.Background(), session, json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	assert.False(t, handled)
+
+	subs.notifyChange("", types.WatchChange{
+		URL:  "https://status.example.com",
+		Diff: &types.DiffSummary{Summary: "1 lines added, 1 removed", AddedLines: 1, RemovedLines: 1},
+	})
+	require.Len(t, session.notifications, 1)
+	notification := <-session.notifications
+	assert.Equal(t, "notifications/resources/updated", notification.Method)
+	assert.Equal(t, "fetch://https://status.example.com", notification.Params.AdditionalFields["uri"])
+	assert.Equal(t, "1 lines added, 1 removed", notification.Params.AdditionalFields["summary"])
+
+	_, ok = handle("resources/unsubscribe", "fetch://https://status.example.com").(mcp.JSONRPCResponse)
+	require.True(t, ok)
+	assert.Empty(t, subs.Watches(context.Background()))
+}
+
+// TestSubscriptionsPolicy tests that each client has its own watches, whose
+// polls count against its rate limit and stop when its policy refuses them
+func TestSubscriptionsPolicy(t *testing.T) {
+	mockFetcher := &MockFetcher{
+		defaultResponse: &types.FetchResponse{Content: "# Status", StatusCode: 200},
+	}
+	subs := NewSubscriptions(mockFetcher, fetcher.WatcherConfig{MaxWatches: 1})
+	t.Cleanup(subs.Close)
+	docs := &Client{Name: "docs-bot", policy: fetcher.NewPolicy("docs-bot", []string{"example.com"}, 0, 2)}
+	ci := &Client{Name: "ci", policy: fetcher.NewPolicy("ci", nil, 0, 0)}
+	docsCtx := withClient(context.Background(), docs)
+	ciCtx := withClient(context.Background(), ci)
+	docsSession := &testSession{id: "docs", notifications: make(chan mcp.JSONRPCNotification, 10)}
+	ciSession := &testSession{id: "ci", notifications: make(chan mcp.JSONRPCNotification, 10)}
+
+	// The baseline fetch takes the first fetch of the rate limit
+	_, err := subs.Watch(docsCtx, docsSession, "https://status.example.com", 0)
+	require.NoError(t, err)
+
+	// Other clients have their own watches, intervals and max_watches
+	status, err := subs.Watch(ciCtx, ciSession, "https://status.example.com", 2*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 7200, status.IntervalSeconds)
+	_, err = subs.Watch(ciCtx, ciSession, "https://other.org", 0)
+	assert.Error(t, err, "max_watches exceeded")
+	require.Len(t, subs.Watches(docsCtx), 1)
+	assert.Equal(t, 300, subs.Watches(docsCtx)[0].IntervalSeconds)
+	assert.Empty(t, subs.Watches(context.Background()))
+	assert.Nil(t, subs.Status(context.Background(), "https://status.example.com"))
+
+	// Redirects to other domains are refused and stop the client's watch
+	other, _ := url.Parse("https://other.org/status")
+	assert.Error(t, subs.pollGuard(docs, "https://status.example.com")(other, true))
+	assert.Empty(t, subs.Watches(docsCtx))
+	assert.Len(t, subs.Watches(ciCtx), 1)
+
+	// Polls count against the rate limit
+	_, err = subs.Watch(docsCtx, docsSession, "https://status.example.com", 0)
+	require.NoError(t, err)
+	poll, _ := url.Parse("https://status.example.com")
+	assert.Error(t, subs.pollGuard(docs, "https://status.example.com")(poll, false))
+	assert.Empty(t, subs.Watches(docsCtx))
+}
+
+// TestFetchDiffTool tests the fetch_diff baseline and argument validation
+func TestFetchDiffTool(t *testing.T) {
+	mockFetcher := &MockFetcher{
+		defaultResponse: &types.FetchResponse{Content: "# Terms\n\nSome terms.", StatusCode: 200},
+	}
+	cfg := &config.Config{}
+	cfg.Fetch.DefaultMaxLength = 1000
+
+	mcpServer := server.NewMCPServer("test", "1.0.0")
+	differ := fetcher.NewDiffer(mockFetcher, fetcher.NewSnapshots(10, 5))
+	require.NoError(t, RegisterFetchDiffTool(mcpServer, differ, cfg))
+
+	call := func(arguments string) *mcp.CallToolResult {
+		t.Helper()
+		message := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"fetch_diff","arguments":` + arguments + `}}`
+		response, ok := mcpServer.HandleMessage(context.Background(), json.RawMessage(message)).(mcp.JSONRPCResponse)
+		require.True(t, ok)
+		result, ok := response.Result.(mcp.CallToolResult)
+		require.True(t, ok)
+		return &result
+	}
+
+	result := call(`{"url":"https://example.com/terms"}`)
+	require.False(t, result.IsError)
+	var diffResponse types.PageDiffResponse
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &diffResponse))
+	assert.True(t, diffResponse.Baseline)
+
+	result = call(`{"url":"https://example.com/terms"}`)
+	var secondResponse types.PageDiffResponse
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &secondResponse))
+	assert.False(t, secondResponse.Baseline)
+	assert.False(t, secondResponse.Changed)
+
+	assert.True(t, call(`{"url":"https://example.com/terms","since":"yesterday"}`).IsError)
+	assert.True(t, call(`{"url":"https://example.com/terms","mode":"side-by-side"}`).IsError)
+	assert.True(t, call(`{"url":"https://example.com/terms","since":"2024-01-31T12:00:00Z","compare_url":"https://example.com/other"}`).IsError)
+}
+
+// TestPrompts tests that prompts embed the fetched pages
+func TestPrompts(t *testing.T) {
+	mockFetcher := &MockFetcher{
+		defaultResponse: &types.FetchResponse{
+			ContentType: "text/html",
+			Content:     "# Docs\n\nSome content.",
+			StatusCode:  200,
+		},
+	}
+	cfg := &config.Config{}
+	cfg.Fetch.DefaultMaxLength = 1000
+	cfg.Fetch.MaxURLs = 3
+
+	mcpServer := server.NewMCPServer("test", "1.0.0")
+	require.NoError(t, RegisterPrompts(mcpServer, mockFetcher, cfg))
+
+	get := func(name string, arguments string) mcp.JSONRPCMessage {
+		t.Helper()
+		message := `{"jsonrpc":"2.0","id":1,"method":"prompts/get","params":{"name":"` + name + `","arguments":` + arguments + `}}`
+		return mcpServer.HandleMessage(context.Background(), json.RawMessage(message))
+	}
+
+	response, ok := get("summarize_url", `{"url":"https://example.com/docs","focus":"pricing"}`).(mcp.JSONRPCResponse)
+	require.True(t, ok)
+	result, ok := response.Result.(mcp.GetPromptResult)
+	require.True(t, ok)
+	require.Len(t, result.Messages, 2)
+	resource, ok := result.Messages[0].Content.(mcp.EmbeddedResource)
+	require.True(t, ok)
+	contents, ok := resource.Resource.(mcp.TextResourceContents)
+	require.True(t, ok)
+	assert.Equal(t, "fetch://https://example.com/docs", contents.URI)
+	assert.Equal(t, "text/html", contents.MIMEType)
+	assert.Equal(t, "# Docs\n\nSome content.", contents.Text)
+	instructions, ok := result.Messages[1].Content.(mcp.TextContent)
+	require.True(t, ok)
+	assert.Contains(t, instructions.Text, "Focus on: pricing.")
+
+	response, ok = get("compare_urls", `{"urls":"https://a.example, https://b.example"}`).(mcp.JSONRPCResponse)
+	require.True(t, ok)
+	result, ok = response.Result.(mcp.GetPromptResult)
+	require.True(t, ok)
+	assert.Len(t, result.Messages, 3)
+
+	response, ok = get("answer_from_docs", `{"url":"https://example.com/docs","question":"How much?"}`).(mcp.JSONRPCResponse)
+	require.True(t, ok)
+	result, ok = response.Result.(mcp.GetPromptResult)
+	require.True(t, ok)
+	instructions, ok = result.Messages[len(result.Messages)-1].Content.(mcp.TextContent)
+	require.True(t, ok)
+	assert.Contains(t, instructions.Text, "Question: How much?")
+
+	// Missing or invalid arguments
+	_, ok = get("extract_facts", `{}`).(mcp.JSONRPCError)
+	assert.True(t, ok)
+	_, ok = get("compare_urls", `{"urls":"https://a.example"}`).(mcp.JSONRPCError)
+	assert.True(t, ok)
+	_, ok = get("compare_urls", `{"urls":"https://a.example https://b.example https://c.example https://d.example"}`).(mcp.JSONRPCError)
+	assert.True(t, ok)
+}
+
+// TestFetchSummaryTool tests that fetch_summary samples the client's model
+func TestFetchSummaryTool(t *testing.T) {
+	mockFetcher := &MockFetcher{
+		defaultResponse: &types.FetchResponse{Content: "# Release notes\n\nVersion 2 is out.", StatusCode: 200},
+	}
+	sampling := NewSampling()
+	hooks := &server.Hooks{}
+	hooks.AddAfterInitialize(sampling.AfterInitialize)
+	mcpServer := server.NewMCPServer("test", "1.0.0", server.WithHooks(hooks))
+	require.NoError(t, RegisterFetchSummaryTool(mcpServer, mockFetcher, sampling))
+
+	session := &testSession{notifications: make(chan mcp.JSONRPCNotification, 10)}
+	ctx := mcpServer.WithContext(context.Background(), session)
+
+	// The client answers every sampling request with the same summary
+	var requests []map[string]any
+	sampling.RegisterSender(session.SessionID(), func(message []byte) error {
+		var request struct {
+			ID     string         `json:"id"`
+			Method string         `json:"method"`
+			Params map[string]any `json:"params"`
+		}
+		require.NoError(t, json.Unmarshal(message, &request))
+		assert.Equal(t, "sampling/createMessage", request.Method)
+		requests = append(requests, request.Params)
+		response := `{"jsonrpc":"2.0","id":"` + request.ID + `","result":{"role":"assistant","content":{"type":"text","text":"Version 2 was released [S1]."},"model":"client-model","stopReason":"endTurn"}}`
+		go sampling.HandleResponse(session.SessionID(), json.RawMessage(response))
+		return nil
+	})
+
+	initialize := func(capabilities string) {
+		t.Helper()
+		message := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":` + capabilities + `,"clientInfo":{"name":"test","version":"1.0.0"}}}`
+		_, ok := mcpServer.HandleMessage(ctx, json.RawMessage(message)).(mcp.JSONRPCResponse)
+		require.True(t, ok)
+	}
+	call := func() *mcp.CallToolResult {
+		t.Helper()
+		message := `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"fetch_summary","arguments":{"url":"https://example.com/releases","max_tokens":200}}}`
+		response, ok := mcpServer.HandleMessage(ctx, json.RawMessage(message)).(mcp.JSONRPCResponse)
+		require.True(t, ok)
+		result, ok := response.Result.(mcp.CallToolResult)
+		require.True(t, ok)
+		return &result
+	}
+
+	// Clients without the sampling capability get a sampling error
+	initialize(`{}`)
+	result := call()
+	require.True(t, result.IsError)
+	var fetchErr types.FetchError
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &fetchErr))
+	assert.Equal(t, "sampling", fetchErr.Code)
+	assert.Empty(t, requests)
+
+	initialize(`{"sampling":{}}`)
+	result = call()
+	require.False(t, result.IsError)
+	var summary types.SummaryResponse
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &summary))
+	assert.Equal(t, "Version 2 was released [S1].", summary.Summary)
+	assert.Equal(t, "client-model", summary.Model)
+	require.Len(t, requests, 1)
+	assert.Equal(t, "none", requests[0]["includeContext"])
+	assert.Equal(t, float64(200), requests[0]["maxTokens"])
+
+	// Only responses to sampling requests are taken
+	assert.False(t, sampling.HandleResponse("test", json.RawMessage(`{"jsonrpc":"2.0","id":2,"result":{}}`)))
+	assert.False(t, sampling.HandleResponse("test", json.RawMessage(`{"jsonrpc":"2.0","id":"mcp-fetch-sampling-9","method":"ping"}`)))
+	assert.True(t, sampling.HandleResponse("test", json.RawMessage(`{"jsonrpc":"2.0","id":"mcp-fetch-sampling-9","result":{}}`)))
+}
+
+// newTransportTestServer serves a test MCP server with fetch, fetch_summary
+// and resource subscriptions over transport, authenticated by auth if not nil
+func newTransportTestServer(t *testing.T, transport string, auth *Authenticator) *httptest.Server {
+	t.Helper()
+	mockFetcher := &MockFetcher{
+		defaultResponse: &types.FetchResponse{Content: "# Status\n\nAll systems operational", StatusCode: 200},
+	}
+	subs := NewSubscriptions(mockFetcher, fetcher.WatcherConfig{MaxWatches: 5})
+	t.Cleanup(subs.Close)
+	sampling := NewSampling()
+	hooks := &server.Hooks{}
+	hooks.AddOnRegisterSession(subs.RegisterSession)
+	hooks.AddAfterInitialize(sampling.AfterInitialize)
+	mcpServer := server.NewMCPServer("test", "1.0.0", server.WithHooks(hooks), server.WithResourceCapabilities(true, false))
+	require.NoError(t, RegisterFetchSummaryTool(mcpServer, mockFetcher, sampling))
+	cfg := &config.Config{}
+	cfg.Fetch.DefaultMaxLength = 5000
+	require.NoError(t, RegisterFetchTool(mcpServer, mockFetcher, cfg))
+
+	httpTransport := newHTTPTransport(mcpServer, transport, "", subs, sampling, auth)
+	hooks.AddOnRegisterSession(httpTransport.registerSession)
+	testServer := httptest.NewServer(httpTransport.handler())
+	t.Cleanup(testServer.Close)
+	return testServer
+}
+
+// readEvent returns the data of the next server-sent event of reader
+func readEvent(t *testing.T, reader *bufio.Reader) (string, string) {
+	t.Helper()
+	var event, data string
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && data != "":
+			return event, data
+		}
+	}
+}
+
+// TestStreamableHTTPTransport tests sessions, JSON and event stream responses
+// and sampling over the streamable HTTP transport
+func TestStreamableHTTPTransport(t *testing.T) {
+	testServer := newTransportTestServer(t, TransportHTTP, nil)
+
+	post := func(sessionID string, body string) *http.Response {
+		t.Helper()
+		request, err := http.NewRequest(http.MethodPost, testServer.URL+"/mcp", strings.NewReader(body))
+		require.NoError(t, err)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json, text/event-stream")
+		if sessionID != "" {
+			request.Header.Set("Mcp-Session-Id", sessionID)
+		}
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		t.Cleanup(func() { response.Body.Close() })
+		return response
+	}
+
+	response, err := http.Get(testServer.URL + "/healthz")
+	require.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	var health map[string]string
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&health))
+	assert.Equal(t, map[string]string{"status": "ok", "transport": "http"}, health)
+
+	response = post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{"sampling":{}},"clientInfo":{"name":"test","version":"1.0.0"}}}`)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "application/json", response.Header.Get("Content-Type"))
+	sessionID := response.Header.Get("Mcp-Session-Id")
+	require.NotEmpty(t, sessionID)
+
+	assert.Equal(t, http.StatusBadRequest, post("", `{"jsonrpc":"2.0","id":2,"method":"ping"}`).StatusCode)
+	assert.Equal(t, http.StatusNotFound, post("unknown", `{"jsonrpc":"2.0","id":2,"method":"ping"}`).StatusCode)
+	assert.Equal(t, http.StatusAccepted, post(sessionID, `{"jsonrpc":"2.0","method":"notifications/initialized"}`).StatusCode)
+
+	// Batches are answered with a batch
+	response = post(sessionID, `[{"jsonrpc":"2.0","id":3,"method":"ping"},{"jsonrpc":"2.0","id":4,"method":"resources/subscribe","params":{"uri":"fetch://https://status.example.com"}}]`)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	var batch []map[string]any
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&batch))
+	require.Len(t, batch, 2)
+	assert.Equal(t, float64(4), batch[1]["id"])
+	assert.NotContains(t, batch[1], "error")
+
+	// The sampling request of fetch_summary turns the response into an event
+	// stream, and the client posts the sampling response separately
+	response = post(sessionID, `{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"fetch_summary","arguments":{"url":"https://status.example.com"}}}`)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+	events := bufio.NewReader(response.Body)
+	_, data := readEvent(t, events)
+	var samplingRequest struct {
+		ID     string `json:"id"`
+		Method string `json:"method"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(data), &samplingRequest))
+	assert.Equal(t, "sampling/createMessage", samplingRequest.Method)
+	assert.Equal(t, http.StatusAccepted, post(sessionID, `{"jsonrpc":"2.0","id":"`+samplingRequest.ID+`","result":{"role":"assistant","content":{"type":"text","text":"All systems operational [S1]."},"model":"client-model"}}`).StatusCode)
+
+	event, data := readEvent(t, events)
+	assert.Equal(t, "message", event)
+	var toolResponse struct {
+		ID     int `json:"id"`
+		Result struct {
+			IsError bool `json:"isError"`
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(data), &toolResponse))
+	assert.Equal(t, 5, toolResponse.ID)
+	require.False(t, toolResponse.Result.IsError)
+	require.Len(t, toolResponse.Result.Content, 1)
+	assert.Contains(t, toolResponse.Result.Content[0].Text, "All systems operational [S1].")
+
+	request, err := http.NewRequest(http.MethodDelete, testServer.URL+"/mcp", nil)
+	require.NoError(t, err)
+	request.Header.Set("Mcp-Session-Id", sessionID)
+	response, err = http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+	assert.Equal(t, http.StatusNotFound, post(sessionID, `{"jsonrpc":"2.0","id":6,"method":"ping"}`).StatusCode)
+}
+
+// TestSSETransport tests that subscription requests posted to the SSE
+// transport are answered on the event stream
+func TestSSETransport(t *testing.T) {
+	testServer := newTransportTestServer(t, TransportSSE, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, testServer.URL+"/sse", nil)
+	require.NoError(t, err)
+	stream, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer stream.Body.Close()
+	events := bufio.NewReader(stream.Body)
+	event, endpoint := readEvent(t, events)
+	require.Equal(t, "endpoint", event)
+
+	post := func(body string) {
+		t.Helper()
+		response, err := http.Post(testServer.URL+endpoint, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		response.Body.Close()
+		assert.Equal(t, http.StatusAccepted, response.StatusCode)
+	}
+
+	post(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`)
+	_, data := readEvent(t, events)
+	assert.Contains(t, data, `"protocolVersion"`)
+
+	post(`{"jsonrpc":"2.0","id":2,"method":"resources/subscribe","params":{"uri":"fetch://https://status.example.com"}}`)
+	_, data = readEvent(t, events)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":2,"result":{}}`, data)
+}
+
+// TestAuthenticatedTransport tests token authentication, session ownership
+// and client policies over the streamable HTTP transport
+func TestAuthenticatedTransport(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Fetch.DefaultMaxLength = 5000
+	cfg.Auth.Clients = []config.AuthClient{
+		{Name: "docs-bot", Token: "docs-token", AllowedDomains: []string{"example.com"}, DefaultMaxLength: 6},
+		{Name: "ci", Token: "ci-token"},
+	}
+	auth, err := NewAuthenticator(cfg)
+	require.NoError(t, err)
+	testServer := newTransportTestServer(t, TransportHTTP, auth)
+
+	post := func(header string, token string, sessionID string, body string) *http.Response {
+		t.Helper()
+		request, err := http.NewRequest(http.MethodPost, testServer.URL+"/mcp", strings.NewReader(body))
+		require.NoError(t, err)
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Accept", "application/json, text/event-stream")
+		if token != "" {
+			request.Header.Set(header, token)
+		}
+		if sessionID != "" {
+			request.Header.Set("Mcp-Session-Id", sessionID)
+		}
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		t.Cleanup(func() { response.Body.Close() })
+		return response
+	}
+	callFetch := func(sessionID string, url string) string {
+		t.Helper()
+		response := post("Authorization", "Bearer docs-token", sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"fetch","arguments":{"url":"`+url+`"}}}`)
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		var toolResponse struct {
+			Result struct {
+				Content []struct {
+					Text string `json:"text"`
+				} `json:"content"`
+			} `json:"result"`
+		}
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&toolResponse))
+		require.Len(t, toolResponse.Result.Content, 1)
+		return toolResponse.Result.Content[0].Text
+	}
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`
+
+	// The health check stays open
+	response, err := http.Get(testServer.URL + "/healthz")
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	response = post("Authorization", "", "", initialize)
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	assert.Equal(t, `Bearer realm="mcp-fetch"`, response.Header.Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, post("Authorization", "Bearer wrong-token", "", initialize).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, post("Authorization", "Basic docs-token", "", initialize).StatusCode)
+
+	response = post("X-API-Key", "ci-token", "", initialize)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	ciSessionID := response.Header.Get("Mcp-Session-Id")
+	response = post("Authorization", "Bearer docs-token", "", initialize)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	sessionID := response.Header.Get("Mcp-Session-Id")
+
+	// Sessions cannot be used by other clients
+	assert.Equal(t, http.StatusNotFound, post("Authorization", "Bearer docs-token", ciSessionID, `{"jsonrpc":"2.0","id":2,"method":"ping"}`).StatusCode)
+	assert.Equal(t, http.StatusOK, post("X-API-Key", "ci-token", ciSessionID, `{"jsonrpc":"2.0","id":2,"method":"ping"}`).StatusCode)
+
+	// The client's default max length applies
+	var fetchResponse types.FetchResponse
+	require.NoError(t, json.Unmarshal([]byte(callFetch(sessionID, "https://docs.example.com/status")), &fetchResponse))
+	assert.Equal(t, "# Stat", fetchResponse.Content)
+
+	var toolErr struct {
+		Code string `json:"code"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(callFetch(sessionID, "https://internal.test/status")), &toolErr))
+	assert.Equal(t, "blocked_by_policy", toolErr.Code)
+
+	// Sessions with no recorded owner cannot be used once auth is enabled
+	transport := newHTTPTransport(server.NewMCPServer("test", "1.0.0"), TransportSSE, "", nil, nil, auth)
+	transport.owners["owned"] = "ci"
+	request := httptest.NewRequest(http.MethodPost, "/message", nil)
+	request.Header.Set("X-API-Key", "ci-token")
+	request = request.WithContext(withClient(request.Context(), auth.authenticate(request)))
+	assert.True(t, transport.ownedBy("owned", request))
+	assert.False(t, transport.ownedBy("orphan", request))
+	assert.True(t, newHTTPTransport(server.NewMCPServer("test", "1.0.0"), TransportSSE, "", nil, nil, nil).ownedBy("orphan", request))
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	cfg := &config.Config{}
+	auth, err := NewAuthenticator(cfg)
+	require.NoError(t, err)
+	assert.Nil(t, auth)
+
+	cfg.Auth.Clients = []config.AuthClient{{Name: "a", Token: "token"}, {Name: "b", Token: "token"}}
+	_, err = NewAuthenticator(cfg)
+	assert.ErrorContains(t, err, "reuses the token")
+
+	cfg.Auth.Clients = []config.AuthClient{{Name: "a", Token: "token"}, {Name: "a", Token: "other"}}
+	_, err = NewAuthenticator(cfg)
+	assert.ErrorContains(t, err, "duplicate auth client")
+
+	cfg.Auth.Clients = []config.AuthClient{{Name: "a"}}
+	_, err = NewAuthenticator(cfg)
+	assert.ErrorContains(t, err, "has no token")
+}
+
+// TestRunFetch tests the content and JSON output of the fetch command
+func TestRunFetch(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("All systems operational"))
+	}))
+	defer testServer.Close()
+
+	cfg := &config.Config{}
+	cfg.Fetch.Timeout = 5
+	cfg.Fetch.MaxWorkers = 1
+	cfg.Fetch.DefaultMaxLength = 11
+	cfg.Fetch.Tokenizer = "heuristic"
+	cfg.Fetch.Robots = "off"
+	cfg.Fetch.HTTPErrors = "error"
+
+	var stdout, stderr strings.Builder
+	err := RunFetch(cfg, []string{testServer.URL + "/status", testServer.URL + "/status"}, FetchCommandOptions{StartIndex: 4}, &stdout, &stderr)
+	require.NoError(t, err)
+	assert.Equal(t, "systems ope\n\n---\n\nsystems ope\n", stdout.String())
+	assert.Empty(t, stderr.String())
+
+	stdout.Reset()
+	err = RunFetch(cfg, []string{testServer.URL + "/status", testServer.URL + "/missing"}, FetchCommandOptions{MaxLength: 3, JSON: true}, &stdout, &stderr)
+	assert.ErrorContains(t, err, "failed to fetch 1 of 2 URLs")
+	decoder := json.NewDecoder(strings.NewReader(stdout.String()))
+	var response types.FetchResponse
+	require.NoError(t, decoder.Decode(&response))
+	assert.Equal(t, "All", response.Content)
+	var fetchErr types.FetchError
+	require.NoError(t, decoder.Decode(&fetchErr))
+	assert.Equal(t, "http_status", fetchErr.Code)
+
+	assert.Error(t, RunFetch(cfg, nil, FetchCommandOptions{}, &stdout, &stderr))
+	assert.Error(t, RunFetch(cfg, []string{testServer.URL}, FetchCommandOptions{Format: "pdf"}, &stdout, &stderr))
+}
@@ -0,0 +1,735 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// Transports of the MCP server.
+const (
+	TransportStdio = "stdio"
+	TransportSSE   = "sse"
+	TransportHTTP  = "http" // Streamable HTTP
+)
+
+// Transports lists the supported transports.
+var Transports = []string{TransportStdio, TransportSSE, TransportHTTP}
+
+const (
+	// streamableEndpoint is the single endpoint of the streamable HTTP
+	// transport. The SSE transport uses mcp-go's /sse and /message.
+	streamableEndpoint = "/mcp"
+	// healthzEndpoint reports that the server is up, on both transports.
+	healthzEndpoint = "/healthz"
+	// headerSessionID carries the streamable HTTP session ID.
+	headerSessionID = "Mcp-Session-Id"
+	// maxMessageSize bounds the body of a posted message.
+	maxMessageSize = 4 << 20
+	// streamQueueSize is the number of messages queued for an event stream.
+	streamQueueSize = 100
+	// sessionIdleTimeout is how long a streamable HTTP session without an
+	// open event stream is kept after its last request.
+	sessionIdleTimeout = time.Hour
+)
+
+// httpTransport serves the MCP server over HTTP, with mcp-go's SSE transport
+// or the streamable HTTP transport, which mcp-go v0.18 does not implement.
+// Like on stdio, resource subscription requests and the responses to
+// sampling requests are routed before mcp-go sees them.
+type httpTransport struct {
+	transport string
+	mcpServer *server.MCPServer
+	subs      *Subscriptions
+	sampling  *Sampling
+	auth      *Authenticator    // nil if authentication is disabled
+	sse       *server.SSEServer // SSE transport only
+
+	// streams is canceled on shutdown to close the long-lived event streams,
+	// which http.Server.Shutdown would otherwise wait for.
+	streams context.Context
+
+	mu       sync.Mutex
+	sessions map[string]*streamableSession // Streamable HTTP sessions by ID
+	owners   map[string]string             // Authenticated client of each session by ID
+}
+
+// newHTTPTransport creates the transport serving mcpServer. baseURL, if set,
+// is the public URL of the server announced to SSE clients. If auth is not
+// nil, requests must carry the token of a configured client.
+func newHTTPTransport(mcpServer *server.MCPServer, transport string, baseURL string, subs *Subscriptions, sampling *Sampling, auth *Authenticator) *httpTransport {
+	t := &httpTransport{
+		transport: transport,
+		mcpServer: mcpServer,
+		subs:      subs,
+		sampling:  sampling,
+		auth:      auth,
+		streams:   context.Background(),
+		sessions:  make(map[string]*streamableSession),
+		owners:    make(map[string]string),
+	}
+	if transport == TransportSSE {
+		t.sse = server.NewSSEServer(mcpServer, server.WithBaseURL(baseURL))
+	}
+	return t
+}
+
+// registerSession records the authenticated client owning a new session and
+// sets up sampling for it, and forgets the session once its context is done.
+// It is meant to be called from the server's OnRegisterSession hook.
+func (t *httpTransport) registerSession(ctx context.Context, session server.ClientSession) {
+	sessionID := session.SessionID()
+	zap.S().Infow("client session opened",
+		"transport", t.transport,
+		"session", sessionID,
+		"client", clientName(ctx))
+	t.mu.Lock()
+	t.owners[sessionID] = clientName(ctx)
+	t.mu.Unlock()
+	if t.sse != nil {
+		t.sampling.RegisterSender(sessionID, func(message []byte) error {
+			return t.sse.SendEventToSession(sessionID, json.RawMessage(message))
+		})
+	}
+	go func() {
+		<-ctx.Done()
+		zap.S().Infow("client session closed", "transport", t.transport, "session", sessionID)
+		t.mu.Lock()
+		delete(t.owners, sessionID)
+		t.mu.Unlock()
+		t.subs.UnregisterSession(sessionID)
+		t.sampling.UnregisterSession(sessionID)
+	}()
+}
+
+// serve listens on addr until SIGINT or SIGTERM, then shuts down gracefully:
+// event streams are closed and in-flight requests are given shutdownTimeout
+// to complete.
+func (t *httpTransport) serve(addr string, shutdownTimeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	streams, closeStreams := context.WithCancel(context.Background())
+	defer closeStreams()
+	t.streams = streams
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return ierrors.Wrapf(err, "failed to listen on %s", addr)
+	}
+	srv := &http.Server{
+		Handler:           t.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	zap.S().Infow("serving MCP over HTTP",
+		"transport", t.transport,
+		"addr", listener.Addr().String())
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(listener)
+	}()
+	go t.expireSessions(streams)
+
+	select {
+	case err := <-served:
+		return ierrors.Wrap(err, "HTTP server failed")
+	case <-ctx.Done():
+	}
+
+	zap.S().Infow("shutting down HTTP server", "timeout", shutdownTimeout)
+	closeStreams()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		zap.S().Warnw("closing requests still in flight", "error", err)
+		return srv.Close()
+	}
+	return nil
+}
+
+// handler returns the HTTP handler of the transport. Only /healthz is
+// served without authentication.
+func (t *httpTransport) handler() http.Handler {
+	authenticated := func(handler http.HandlerFunc) http.Handler {
+		if t.auth == nil {
+			return handler
+		}
+		return t.auth.Middleware(handler)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(healthzEndpoint, t.handleHealthz)
+	switch t.transport {
+	case TransportSSE:
+		mux.Handle(t.sse.CompleteSsePath(), authenticated(t.handleSSEStream))
+		mux.Handle(t.sse.CompleteMessagePath(), authenticated(t.handleSSEMessage))
+	case TransportHTTP:
+		mux.Handle(streamableEndpoint, authenticated(t.handleStreamable))
+	}
+	return mux
+}
+
+// ownedBy reports whether the session with sessionID belongs to the
+// authenticated client of r. Without authentication, every session may be
+// used; with it, sessions with no recorded owner may not.
+func (t *httpTransport) ownedBy(sessionID string, r *http.Request) bool {
+	if t.auth == nil {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	owner, ok := t.owners[sessionID]
+	return ok && owner == clientName(r.Context())
+}
+
+// handleHealthz reports that the server is up.
+func (t *httpTransport) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":    "ok",
+		"transport": t.transport,
+	})
+}
+
+// handleSSEStream opens an SSE stream, which is closed on shutdown.
+func (t *httpTransport) handleSSEStream(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	defer context.AfterFunc(t.streams, cancel)()
+	t.sse.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// handleSSEMessage delivers a message posted to the SSE transport that is a
+// sampling response, answers a subscription request on the session's stream,
+// and passes any other message to mcp-go.
+func (t *httpTransport) handleSSEMessage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		t.sse.ServeHTTP(w, r)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		writeJSONRPCError(w, http.StatusRequestEntityTooLarge, mcp.INVALID_REQUEST, "message too large")
+		return
+	}
+
+	sessionID := r.URL.Query().Get("sessionId")
+	if !t.ownedBy(sessionID, r) {
+		writeJSONRPCError(w, http.StatusNotFound, mcp.INVALID_PARAMS, "Invalid session ID")
+		return
+	}
+	if session := t.subs.session(sessionID); session != nil {
+		if t.sampling.HandleResponse(sessionID, body) {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if response, handled := t.subs.HandleMessage(r.Context(), session, body); handled {
+			if err := t.sse.SendEventToSession(sessionID, response); err != nil {
+				zap.S().Warnw("failed to send subscription response", "session", sessionID, "error", err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	t.sse.ServeHTTP(w, r)
+}
+
+// handleStreamable serves the streamable HTTP endpoint: clients post
+// messages, open an event stream for server messages with GET and end their
+// session with DELETE.
+func (t *httpTransport) handleStreamable(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		t.handlePost(w, r)
+	case http.MethodGet:
+		t.handleGet(w, r)
+	case http.MethodDelete:
+		session := t.session(w, r)
+		if session == nil {
+			return
+		}
+		t.closeSession(session)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// rpcEnvelope is the part of a JSON-RPC message telling its type.
+type rpcEnvelope struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+}
+
+// handlePost handles a message, or batch of messages, posted to the
+// streamable HTTP endpoint. Responses and notifications are accepted with no
+// body. The responses to requests are returned as JSON, unless the server
+// sends messages while handling them, e.g. progress notifications or
+// sampling requests, in which case the response becomes an event stream.
+func (t *httpTransport) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		writeJSONRPCError(w, http.StatusRequestEntityTooLarge, mcp.INVALID_REQUEST, "message too large")
+		return
+	}
+	messages, batch, err := splitBatch(body)
+	if err != nil {
+		writeJSONRPCError(w, http.StatusBadRequest, mcp.PARSE_ERROR, "parse error")
+		return
+	}
+
+	envelopes := make([]rpcEnvelope, len(messages))
+	initialize := false
+	for i, message := range messages {
+		if err := json.Unmarshal(message, &envelopes[i]); err != nil {
+			writeJSONRPCError(w, http.StatusBadRequest, mcp.INVALID_REQUEST, "invalid JSON-RPC message")
+			return
+		}
+		initialize = initialize || envelopes[i].Method == string(mcp.MethodInitialize)
+	}
+
+	var session *streamableSession
+	if initialize {
+		if len(messages) > 1 {
+			writeJSONRPCError(w, http.StatusBadRequest, mcp.INVALID_REQUEST, "initialize must not be batched")
+			return
+		}
+		session = t.openSession(clientFromContext(r.Context()))
+		w.Header().Set(headerSessionID, session.id)
+	} else if session = t.session(w, r); session == nil {
+		return
+	}
+	defer session.touch()
+
+	rs := &requestSession{
+		streamableSession: session,
+		streaming:         acceptsEventStream(r),
+		events:            make(chan []byte, streamQueueSize),
+		notifications:     session.notifications,
+	}
+	if rs.streaming {
+		rs.notifications = make(chan mcp.JSONRPCNotification, streamQueueSize)
+	}
+	ctx := t.mcpServer.WithContext(r.Context(), rs)
+
+	var requests []json.RawMessage
+	for i, message := range messages {
+		switch {
+		case envelopes[i].Method == "":
+			if !t.sampling.HandleResponse(session.id, message) {
+				zap.S().Debugw("dropping response to unknown request", "session", session.id)
+			}
+		case len(envelopes[i].ID) == 0 || string(envelopes[i].ID) == "null":
+			t.mcpServer.HandleMessage(ctx, message)
+		default:
+			requests = append(requests, message)
+		}
+	}
+	if len(requests) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	done := make(chan []mcp.JSONRPCMessage, 1)
+	go func() {
+		responses := make([]mcp.JSONRPCMessage, 0, len(requests))
+		for _, request := range requests {
+			if response := t.handleMessage(ctx, session, request); response != nil {
+				responses = append(responses, response)
+			}
+		}
+		done <- responses
+	}()
+
+	// Without an event stream, the notifications go to the session's stream
+	var notifications chan mcp.JSONRPCNotification
+	if rs.streaming {
+		notifications = rs.notifications
+	}
+	var events *eventWriter
+	write := func(message []byte) {
+		if events == nil {
+			if events = startEventStream(w); events == nil {
+				return
+			}
+		}
+		events.write(message)
+	}
+	for {
+		select {
+		case message := <-rs.events:
+			write(message)
+		case notification := <-notifications:
+			if message, err := json.Marshal(notification); err == nil {
+				write(message)
+			}
+		case responses := <-done:
+			if len(responses) == 0 && events == nil {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			if events == nil {
+				w.Header().Set("Content-Type", "application/json")
+				if batch {
+					json.NewEncoder(w).Encode(responses)
+				} else {
+					json.NewEncoder(w).Encode(responses[0])
+				}
+				return
+			}
+			// Notifications queued while the last request completed come
+			// before the responses
+			for len(notifications) > 0 {
+				if message, err := json.Marshal(<-notifications); err == nil {
+					events.write(message)
+				}
+			}
+			for _, response := range responses {
+				if message, err := json.Marshal(response); err == nil {
+					events.write(message)
+				}
+			}
+			return
+		}
+	}
+}
+
+// handleMessage answers a request of session, which may be a resource
+// subscription request mcp-go does not route.
+func (t *httpTransport) handleMessage(ctx context.Context, session *streamableSession, message json.RawMessage) mcp.JSONRPCMessage {
+	if response, handled := t.subs.HandleMessage(ctx, session, message); handled {
+		return response
+	}
+	return t.mcpServer.HandleMessage(ctx, message)
+}
+
+// handleGet opens the event stream of a streamable HTTP session, on which
+// the server sends the messages not tied to a request, e.g. resource
+// updates. A session has at most one such stream.
+func (t *httpTransport) handleGet(w http.ResponseWriter, r *http.Request) {
+	session := t.session(w, r)
+	if session == nil {
+		return
+	}
+	if !acceptsEventStream(r) {
+		http.Error(w, "the Accept header must include text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+	stream := make(chan []byte, streamQueueSize)
+	if !session.openStream(stream) {
+		http.Error(w, "an event stream is already open for this session", http.StatusConflict)
+		return
+	}
+	defer session.closeStream()
+
+	events := startEventStream(w)
+	if events == nil {
+		return
+	}
+	for {
+		select {
+		case message := <-stream:
+			events.write(message)
+		case <-r.Context().Done():
+			return
+		case <-session.ctx.Done():
+			return
+		case <-t.streams.Done():
+			return
+		}
+	}
+}
+
+// session returns the streamable HTTP session of r, or writes an error and
+// returns nil.
+func (t *httpTransport) session(w http.ResponseWriter, r *http.Request) *streamableSession {
+	sessionID := r.Header.Get(headerSessionID)
+	if sessionID == "" {
+		writeJSONRPCError(w, http.StatusBadRequest, mcp.INVALID_REQUEST, "missing "+headerSessionID+" header")
+		return nil
+	}
+	t.mu.Lock()
+	session := t.sessions[sessionID]
+	t.mu.Unlock()
+	if session == nil || !t.ownedBy(sessionID, r) {
+		writeJSONRPCError(w, http.StatusNotFound, mcp.INVALID_REQUEST, "unknown session")
+		return nil
+	}
+	session.touch()
+	return session
+}
+
+// openSession creates and registers a streamable HTTP session of client.
+func (t *httpTransport) openSession(client *Client) *streamableSession {
+	session := newStreamableSession()
+	t.mu.Lock()
+	t.sessions[session.id] = session
+	t.mu.Unlock()
+	if err := t.mcpServer.RegisterSession(withClient(session.ctx, client), session); err != nil {
+		zap.S().Errorw("failed to register session", "session", session.id, "error", err)
+	}
+	return session
+}
+
+// closeSession ends a streamable HTTP session.
+func (t *httpTransport) closeSession(session *streamableSession) {
+	t.mu.Lock()
+	delete(t.sessions, session.id)
+	t.mu.Unlock()
+	t.mcpServer.UnregisterSession(session.id)
+	session.cancel()
+}
+
+// expireSessions closes the streamable HTTP sessions idle for longer than
+// sessionIdleTimeout, until ctx is done.
+func (t *httpTransport) expireSessions(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		t.mu.Lock()
+		var idle []*streamableSession
+		for _, session := range t.sessions {
+			if session.idleSince(sessionIdleTimeout) {
+				idle = append(idle, session)
+			}
+		}
+		t.mu.Unlock()
+		for _, session := range idle {
+			zap.S().Infow("closing idle session", "session", session.id)
+			t.closeSession(session)
+		}
+	}
+}
+
+// streamableSession is a client session of the streamable HTTP transport.
+// Notifications not tied to a request, e.g. resource updates, are sent on
+// the session's event stream if the client opened one, and dropped
+// otherwise.
+type streamableSession struct {
+	id            string
+	notifications chan mcp.JSONRPCNotification
+	initialized   atomic.Bool
+	ctx           context.Context // Done when the session is closed
+	cancel        context.CancelFunc
+
+	mu       sync.Mutex
+	stream   chan []byte // Messages for the open event stream, nil if none
+	lastUsed time.Time
+}
+
+// newStreamableSession creates a session with a random ID.
+func newStreamableSession() *streamableSession {
+	id := make([]byte, 16)
+	rand.Read(id)
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &streamableSession{
+		id:            hex.EncodeToString(id),
+		notifications: make(chan mcp.JSONRPCNotification, streamQueueSize),
+		ctx:           ctx,
+		cancel:        cancel,
+		lastUsed:      time.Now(),
+	}
+	go s.forwardNotifications()
+	return s
+}
+
+func (s *streamableSession) SessionID() string { return s.id }
+func (s *streamableSession) Initialize()       { s.initialized.Store(true) }
+func (s *streamableSession) Initialized() bool { return s.initialized.Load() }
+func (s *streamableSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+// forwardNotifications sends the notifications of the session to its event
+// stream until the session is closed.
+func (s *streamableSession) forwardNotifications() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case notification := <-s.notifications:
+			message, err := json.Marshal(notification)
+			if err != nil {
+				continue
+			}
+			if err := s.sendRequest(message); err != nil {
+				zap.S().Debugw("dropping notification",
+					"session", s.id,
+					"method", notification.Method,
+					"error", err)
+			}
+		}
+	}
+}
+
+// sendRequest writes message on the session's event stream.
+func (s *streamableSession) sendRequest(message []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stream == nil {
+		return ierrors.New(ierrors.ErrSampling, "the client has no event stream open")
+	}
+	select {
+	case s.stream <- message:
+		return nil
+	default:
+		return ierrors.New(ierrors.ErrSampling, "the event stream is full")
+	}
+}
+
+// openStream sets the session's event stream, unless one is already open.
+func (s *streamableSession) openStream(stream chan []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stream != nil {
+		return false
+	}
+	s.stream = stream
+	return true
+}
+
+// closeStream unsets the session's event stream.
+func (s *streamableSession) closeStream() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stream = nil
+	s.lastUsed = time.Now()
+}
+
+// touch records that the session is in use.
+func (s *streamableSession) touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastUsed = time.Now()
+}
+
+// idleSince reports whether the session has no event stream open and was
+// last used more than timeout ago.
+func (s *streamableSession) idleSince(timeout time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stream == nil && time.Since(s.lastUsed) > timeout
+}
+
+// requestSession is the session of a streamable HTTP request. When the
+// client accepts an event stream, the notifications and sampling requests
+// sent while handling the request go on the response stream; otherwise they
+// go to the session's own event stream.
+type requestSession struct {
+	*streamableSession
+	streaming     bool
+	events        chan []byte
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *requestSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+// sendRequest writes message on the response stream of the request.
+func (s *requestSession) sendRequest(message []byte) error {
+	if !s.streaming {
+		return s.streamableSession.sendRequest(message)
+	}
+	select {
+	case s.events <- message:
+		return nil
+	default:
+		return ierrors.New(ierrors.ErrSampling, "the event stream is full")
+	}
+}
+
+// eventWriter writes JSON-RPC messages as server-sent events.
+type eventWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// startEventStream starts an event stream response, or writes an error and
+// returns nil if w cannot stream.
+func startEventStream(w http.ResponseWriter) *eventWriter {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return nil
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &eventWriter{w: w, flusher: flusher}
+}
+
+// write sends message as a "message" event.
+func (e *eventWriter) write(message []byte) {
+	fmt.Fprintf(e.w, "event: message\ndata: %s\n\n", message)
+	e.flusher.Flush()
+}
+
+// acceptsEventStream reports whether the client accepts an event stream
+// response.
+func acceptsEventStream(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		if strings.Contains(accept, "text/event-stream") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitBatch returns the messages of body, a single JSON-RPC message or a
+// batch, and whether it was a batch.
+func splitBatch(body []byte) ([]json.RawMessage, bool, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var messages []json.RawMessage
+		if err := json.Unmarshal(body, &messages); err != nil {
+			return nil, false, err
+		}
+		if len(messages) == 0 {
+			return nil, false, ierrors.New(ierrors.ErrInvalidArgument, "empty batch")
+		}
+		return messages, true, nil
+	}
+	if !json.Valid(body) {
+		return nil, false, ierrors.New(ierrors.ErrInvalidArgument, "invalid JSON")
+	}
+	return []json.RawMessage{body}, false, nil
+}
+
+// writeJSONRPCError writes a JSON-RPC error with no ID and the given HTTP
+// status.
+func writeJSONRPCError(w http.ResponseWriter, status int, code int, message string) {
+	response := mcp.JSONRPCError{JSONRPC: mcp.JSONRPC_VERSION}
+	response.Error.Code = code
+	response.Error.Message = message
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cnosuke/mcp-fetch/fetcher"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// progressNotifier returns a fetcher.ProgressFunc that sends MCP
+// notifications/progress messages for request, or nil if the client did not
+// ask for progress with a progress token.
+func progressNotifier(ctx context.Context, request mcp.CallToolRequest) fetcher.ProgressFunc {
+	if request.Params.Meta == nil || request.Params.Meta.ProgressToken == nil {
+		return nil
+	}
+	mcpServer := server.ServerFromContext(ctx)
+	if mcpServer == nil {
+		return nil
+	}
+	token := request.Params.Meta.ProgressToken
+
+	mu := &sync.Mutex{}
+	last := 0.0
+	return func(progress, total float64, message string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Progress must increase with every notification; updates from
+		// concurrent fetches may arrive out of order
+		if progress <= last {
+			return
+		}
+		last = progress
+
+		params := map[string]any{
+			"progressToken": token,
+			"progress":      progress,
+		}
+		if total > 0 {
+			params["total"] = total
+		}
+		if message != "" {
+			params["message"] = message
+		}
+		if err := mcpServer.SendNotificationToClient(ctx, "notifications/progress", params); err != nil {
+			zap.S().Debugw("failed to send progress notification", "progress", progress, "error", err)
+		}
+	}
+}
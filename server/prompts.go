@@ -0,0 +1,288 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cnosuke/mcp-fetch/config"
+	"github.com/cnosuke/mcp-fetch/fetcher"
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// answerPassages is the number of passages matching the question embedded by
+// answer_from_docs when the document is longer than the content embedded.
+const answerPassages = 5
+
+// RegisterPrompts - Register the prompts orchestrating the fetch tools. The
+// prompts embed the pages they are about, fetched with f and capped at the
+// default max length, so the model does not have to call the tools first
+func RegisterPrompts(mcpServer *server.MCPServer, f fetcher.Fetcher, cfg *config.Config) error {
+	zap.S().Debugw("registering prompts")
+
+	p := &prompts{fetcher: f, maxLength: cfg.Fetch.DefaultMaxLength, maxURLs: cfg.Fetch.MaxURLs}
+
+	mcpServer.AddPrompt(mcp.NewPrompt("summarize_url",
+		mcp.WithPromptDescription("Summarize a web page"),
+		mcp.WithArgument("url",
+			mcp.ArgumentDescription("URL of the page to summarize"),
+			mcp.RequiredArgument(),
+		),
+		mcp.WithArgument("focus",
+			mcp.ArgumentDescription("Topic or question the summary should focus on"),
+		),
+	), p.summarizeURL)
+
+	mcpServer.AddPrompt(mcp.NewPrompt("compare_urls",
+		mcp.WithPromptDescription("Compare web pages, e.g. products, libraries or versions of a document"),
+		mcp.WithArgument("urls",
+			mcp.ArgumentDescription(fmt.Sprintf("Two to %d URLs, separated by commas, spaces or newlines", cfg.Fetch.MaxURLs)),
+			mcp.RequiredArgument(),
+		),
+		mcp.WithArgument("aspects",
+			mcp.ArgumentDescription("Aspects to compare, e.g. pricing, features and limits"),
+		),
+	), p.compareURLs)
+
+	mcpServer.AddPrompt(mcp.NewPrompt("extract_facts",
+		mcp.WithPromptDescription("Extract the facts stated in a web page, with supporting quotes"),
+		mcp.WithArgument("url",
+			mcp.ArgumentDescription("URL of the page to extract facts from"),
+			mcp.RequiredArgument(),
+		),
+		mcp.WithArgument("topic",
+			mcp.ArgumentDescription("Only extract facts about this topic"),
+		),
+	), p.extractFacts)
+
+	mcpServer.AddPrompt(mcp.NewPrompt("answer_from_docs",
+		mcp.WithPromptDescription("Answer a question using only the documentation at a URL"),
+		mcp.WithArgument("question",
+			mcp.ArgumentDescription("Question to answer"),
+			mcp.RequiredArgument(),
+		),
+		mcp.WithArgument("url",
+			mcp.ArgumentDescription("URL of the documentation"),
+			mcp.RequiredArgument(),
+		),
+	), p.answerFromDocs)
+
+	return nil
+}
+
+// prompts builds the messages of the registered prompts.
+type prompts struct {
+	fetcher   fetcher.Fetcher
+	maxLength int
+	maxURLs   int
+}
+
+// summarizeURL handles the summarize_url prompt.
+func (p *prompts) summarizeURL(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	urlStr, err := requiredPromptArgument(request, "url")
+	if err != nil {
+		return nil, err
+	}
+	focus := strings.TrimSpace(request.Params.Arguments["focus"])
+
+	zap.S().Infow("getting summarize_url prompt", "url", urlStr, "focus", focus)
+
+	page, err := p.fetchPage(ctx, urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	instructions := "Summarize the web page above in a few paragraphs, starting with its main point. Keep the key facts, figures and conclusions, and mention the page's title and source."
+	if focus != "" {
+		instructions += fmt.Sprintf(" Focus on: %s.", focus)
+	}
+	return mcp.NewGetPromptResult("Summarize "+urlStr, []mcp.PromptMessage{
+		page,
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(instructions)),
+	}), nil
+}
+
+// compareURLs handles the compare_urls prompt.
+func (p *prompts) compareURLs(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	urlsArg, err := requiredPromptArgument(request, "urls")
+	if err != nil {
+		return nil, err
+	}
+	urls := strings.FieldsFunc(urlsArg, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\t'
+	})
+	if len(urls) < 2 {
+		return nil, ierrors.New(ierrors.ErrInvalidArgument, "compare_urls needs at least two URLs")
+	}
+	if len(urls) > p.maxURLs {
+		return nil, ierrors.Newf(ierrors.ErrInvalidArgument, "too many URLs: %d (max %d)", len(urls), p.maxURLs)
+	}
+	aspects := strings.TrimSpace(request.Params.Arguments["aspects"])
+
+	zap.S().Infow("getting compare_urls prompt", "urls", urls, "aspects", aspects)
+
+	// Each page gets the default max length, from a shared budget so that
+	// short pages leave room for longer ones
+	response, err := clientFetcher(ctx, p.fetcher).FetchMultipleWithOptions(ctx, urls, fetcher.MultipleFetchOptions{MaxLength: clientMaxLength(ctx, p.maxLength) * len(urls)})
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to fetch URLs")
+	}
+	messages := make([]mcp.PromptMessage, 0, len(response.Results)+1)
+	for _, result := range response.Results {
+		if result.Error != nil {
+			return nil, ierrors.Newf(ierrors.ErrUnknown, "failed to fetch %s (%s): %s", result.URL, result.Error.Code, result.Error.Message)
+		}
+		content := result.Content
+		if result.Truncated {
+			content += truncationNote(result.StartIndex + len(result.Content))
+		}
+		messages = append(messages, pageMessage(result.URL, resourceMIMEType(&types.FetchResponse{ContentType: result.ContentType, Format: result.Format}), content))
+	}
+
+	instructions := fmt.Sprintf("Compare the %d web pages above. Describe what they have in common and how they differ, with a table when it helps, and cite the page each point comes from.", len(urls))
+	if aspects != "" {
+		instructions += fmt.Sprintf(" Compare them on: %s.", aspects)
+	}
+	messages = append(messages, mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(instructions)))
+	return mcp.NewGetPromptResult("Compare "+strings.Join(urls, ", "), messages), nil
+}
+
+// extractFacts handles the extract_facts prompt.
+func (p *prompts) extractFacts(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	urlStr, err := requiredPromptArgument(request, "url")
+	if err != nil {
+		return nil, err
+	}
+	topic := strings.TrimSpace(request.Params.Arguments["topic"])
+
+	zap.S().Infow("getting extract_facts prompt", "url", urlStr, "topic", topic)
+
+	page, err := p.fetchPage(ctx, urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	instructions := "List the facts stated in the web page above as bullet points, one verifiable claim each (names, dates, figures, definitions), each followed by a short supporting quote from the page. Do not include opinions or facts that are not in the page."
+	if topic != "" {
+		instructions += fmt.Sprintf(" Only include facts about: %s.", topic)
+	}
+	return mcp.NewGetPromptResult("Extract facts from "+urlStr, []mcp.PromptMessage{
+		page,
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(instructions)),
+	}), nil
+}
+
+// answerFromDocs handles the answer_from_docs prompt. When the document is
+// longer than the content embedded, the passages matching the question are
+// embedded as well.
+func (p *prompts) answerFromDocs(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	question, err := requiredPromptArgument(request, "question")
+	if err != nil {
+		return nil, err
+	}
+	urlStr, err := requiredPromptArgument(request, "url")
+	if err != nil {
+		return nil, err
+	}
+
+	zap.S().Infow("getting answer_from_docs prompt", "url", urlStr, "question", question)
+
+	response, err := clientFetcher(ctx, p.fetcher).FetchWithOptions(urlStr, fetcher.FetchOptions{MaxLength: clientMaxLength(ctx, p.maxLength)})
+	if err != nil {
+		return nil, fetchPromptError(urlStr, err)
+	}
+	content := response.Content
+	truncated := response.Tokens < response.TotalTokens
+	if truncated {
+		content += truncationNote(len(response.Content))
+	}
+	messages := []mcp.PromptMessage{pageMessage(urlStr, resourceMIMEType(response), content)}
+
+	if truncated {
+		search, err := clientFetcher(ctx, p.fetcher).FetchSearch(urlStr, fetcher.SearchOptions{Query: question, MaxResults: 2 * answerPassages})
+		if err != nil {
+			// The beginning of the document is still worth answering from
+			zap.S().Warnw("failed to search document", "url", urlStr, "error", err)
+		} else if passages := laterPassages(search.Matches, len(response.Content)); len(passages) > 0 {
+			var sb strings.Builder
+			sb.WriteString("Passages of the rest of the document matching the question:\n")
+			for _, match := range passages {
+				fmt.Fprintf(&sb, "\n--- offset %d", match.Offset)
+				if len(match.HeadingPath) > 0 {
+					fmt.Fprintf(&sb, " (%s)", strings.Join(match.HeadingPath, " > "))
+				}
+				sb.WriteString(" ---\n")
+				sb.WriteString(match.Text)
+				sb.WriteString("\n")
+			}
+			messages = append(messages, mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(sb.String())))
+		}
+	}
+
+	instructions := fmt.Sprintf("Answer the following question using only the documentation above, citing the sections the answer comes from. If the documentation does not answer it, say so instead of guessing.\n\nQuestion: %s", question)
+	messages = append(messages, mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(instructions)))
+	return mcp.NewGetPromptResult("Answer from "+urlStr, messages), nil
+}
+
+// laterPassages returns up to answerPassages matches starting at or after
+// offset, that is, not in the content already embedded.
+func laterPassages(matches []types.SearchMatch, offset int) []types.SearchMatch {
+	var passages []types.SearchMatch
+	for _, match := range matches {
+		if match.Offset >= offset && len(passages) < answerPassages {
+			passages = append(passages, match)
+		}
+	}
+	return passages
+}
+
+// fetchPage fetches urlStr up to the client's default max length and returns
+// it as a prompt message embedding its fetch:// resource.
+func (p *prompts) fetchPage(ctx context.Context, urlStr string) (mcp.PromptMessage, error) {
+	response, err := clientFetcher(ctx, p.fetcher).FetchWithOptions(urlStr, fetcher.FetchOptions{MaxLength: clientMaxLength(ctx, p.maxLength)})
+	if err != nil {
+		return mcp.PromptMessage{}, fetchPromptError(urlStr, err)
+	}
+	content := response.Content
+	if response.Tokens < response.TotalTokens {
+		content += truncationNote(len(response.Content))
+	}
+	return pageMessage(urlStr, resourceMIMEType(response), content), nil
+}
+
+// pageMessage returns a user message embedding the fetch:// resource of a page.
+func pageMessage(urlStr string, mimeType string, content string) mcp.PromptMessage {
+	return mcp.NewPromptMessage(mcp.RoleUser, mcp.NewEmbeddedResource(mcp.TextResourceContents{
+		URI:      fetchResourceURI(urlStr),
+		MIMEType: mimeType,
+		Text:     content,
+	}))
+}
+
+// truncationNote tells the model the content was cut at nextIndex, where the
+// fetch tool can resume.
+func truncationNote(nextIndex int) string {
+	return fmt.Sprintf("\n\n[Content truncated; use the fetch tool with start_index=%d to read more.]", nextIndex)
+}
+
+// requiredPromptArgument returns the named prompt argument, or an error if it
+// is missing.
+func requiredPromptArgument(request mcp.GetPromptRequest, name string) (string, error) {
+	value := strings.TrimSpace(request.Params.Arguments[name])
+	if value == "" {
+		return "", ierrors.Newf(ierrors.ErrInvalidArgument, "%s is required", name)
+	}
+	return value, nil
+}
+
+// fetchPromptError wraps the error of fetching the page of a prompt.
+func fetchPromptError(urlStr string, err error) error {
+	zap.S().Errorw("failed to fetch prompt page",
+		"url", urlStr,
+		"error", err)
+	return ierrors.Wrapf(err, "failed to fetch %s (%s)", urlStr, ierrors.Code(err))
+}
@@ -18,5 +18,30 @@ func RegisterAllTools(mcpServer *server.MCPServer, f fetcher.Fetcher, maxURLs in
 		return err
 	}
 
+	// Register fetch_links tool
+	if err := RegisterFetchLinksTool(mcpServer, f); err != nil {
+		return err
+	}
+
+	// Register fetch_outline tool
+	if err := RegisterFetchOutlineTool(mcpServer, f); err != nil {
+		return err
+	}
+
+	// Register fetch_search tool
+	if err := RegisterFetchSearchTool(mcpServer, f); err != nil {
+		return err
+	}
+
+	// Register crawl tool
+	if err := RegisterCrawlTool(mcpServer, f, cfg); err != nil {
+		return err
+	}
+
+	// Register fetch_sitemap tool
+	if err := RegisterFetchSitemapTool(mcpServer, f, maxURLs); err != nil {
+		return err
+	}
+
 	return nil
 }
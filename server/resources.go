@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cnosuke/mcp-fetch/fetcher"
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+const (
+	// fetchResourceScheme prefixes the URL of a page read as a resource.
+	fetchResourceScheme = "fetch://"
+	// fetchResourceTemplate is the URI template of fetched pages. Reserved
+	// expansion lets the URL be written as is: fetch://https://example.com/docs
+	fetchResourceTemplate = fetchResourceScheme + "{+url}"
+	// fetchHistoryURI is the URI of the list of recently fetched documents.
+	fetchHistoryURI = "fetch-history://"
+)
+
+// fetchResourceURI returns the resource URI reading urlStr.
+func fetchResourceURI(urlStr string) string {
+	return fetchResourceScheme + urlStr
+}
+
+// RegisterFetchResources - Register the fetch:// resource template and, if
+// history is set, the fetch-history:// resource
+func RegisterFetchResources(mcpServer *server.MCPServer, f fetcher.Fetcher, history *fetcher.History, maxLength int) error {
+	zap.S().Debugw("registering fetch resources", "max_length", maxLength, "history", history != nil)
+
+	template := mcp.NewResourceTemplate(fetchResourceTemplate, "Web page",
+		mcp.WithTemplateDescription(fmt.Sprintf("A web page fetched and converted to Markdown, e.g. fetch://https://example.com/docs (up to %d characters; use the fetch tool to page through longer documents)", maxLength)),
+		mcp.WithTemplateMIMEType("text/markdown"),
+	)
+	mcpServer.AddResourceTemplate(template, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		urlStr, err := fetchResourceURL(request)
+		if err != nil {
+			return nil, err
+		}
+
+		zap.S().Infow("reading fetch resource", "uri", request.Params.URI, "url", urlStr)
+
+		response, err := clientFetcher(ctx, f).FetchWithOptions(urlStr, fetcher.FetchOptions{MaxLength: maxLength})
+		if err != nil {
+			zap.S().Errorw("failed to fetch resource",
+				"url", urlStr,
+				"error", err)
+			return nil, ierrors.Wrapf(err, "failed to fetch %s (%s)", urlStr, ierrors.Code(err))
+		}
+
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: resourceMIMEType(response),
+				Text:     response.Content,
+			},
+		}, nil
+	})
+
+	if history == nil {
+		return nil
+	}
+
+	resource := mcp.NewResource(fetchHistoryURI, "Fetch history",
+		mcp.WithResourceDescription("Documents recently fetched by this server, most recent first, with the fetch:// URI reading each of them"),
+		mcp.WithMIMEType("application/json"),
+	)
+	mcpServer.AddResource(resource, func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		documents := history.Entries()
+		for i := range documents {
+			documents[i].Resource = fetchResourceURI(documents[i].URL)
+		}
+
+		jsonResponse, err := json.Marshal(&types.FetchHistoryResponse{Documents: documents})
+		if err != nil {
+			return nil, ierrors.Wrap(err, "failed to marshal response to JSON")
+		}
+
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      fetchHistoryURI,
+				MIMEType: "application/json",
+				Text:     string(jsonResponse),
+			},
+		}, nil
+	})
+
+	return nil
+}
+
+// fetchResourceURL returns the page URL of a fetch:// resource request.
+func fetchResourceURL(request mcp.ReadResourceRequest) (string, error) {
+	urlStr, _ := request.Params.Arguments["url"].(string)
+	return pageURL(request.Params.URI, urlStr)
+}
+
+// pageURL returns the page URL of the fetch:// resource uri, or urlStr if set
+// (the url variable matched by the template). The URL may also be
+// percent-encoded as a whole, as simple template expansion does.
+func pageURL(uri string, urlStr string) (string, error) {
+	if urlStr == "" {
+		if !strings.HasPrefix(uri, fetchResourceScheme) {
+			return "", ierrors.Newf(ierrors.ErrInvalidArgument, "invalid resource URI %q: expected fetch://http(s)://...", uri)
+		}
+		urlStr = strings.TrimPrefix(uri, fetchResourceScheme)
+	}
+	if !strings.Contains(urlStr, "://") {
+		if unescaped, err := url.PathUnescape(urlStr); err == nil {
+			urlStr = unescaped
+		}
+	}
+	if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
+		return "", ierrors.Newf(ierrors.ErrInvalidArgument, "invalid resource URI %q: expected fetch://http(s)://...", uri)
+	}
+	return urlStr, nil
+}
+
+// resourceMIMEType returns the MIME type of the content of a fetch response:
+// the converted format for HTML, or the response's content type.
+func resourceMIMEType(response *types.FetchResponse) string {
+	switch fetcher.Format(response.Format) {
+	case fetcher.FormatMarkdown:
+		return "text/markdown"
+	case fetcher.FormatText:
+		return "text/plain"
+	case fetcher.FormatHTML:
+		return "text/html"
+	case fetcher.FormatJSON:
+		return "application/json"
+	}
+	mediaType, _, _ := strings.Cut(response.ContentType, ";")
+	return strings.TrimSpace(mediaType)
+}
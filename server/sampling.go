@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cnosuke/mcp-fetch/fetcher"
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+const (
+	// methodSamplingCreateMessage is the MCP method asking the client's model
+	// for a completion.
+	methodSamplingCreateMessage = "sampling/createMessage"
+	// samplingRequestPrefix prefixes the IDs of sampling requests, which
+	// share no ID space with the client's requests.
+	samplingRequestPrefix = "mcp-fetch-sampling-"
+	// samplingTimeout bounds the wait for a sampling response, which the
+	// user may have to approve first.
+	samplingTimeout = 5 * time.Minute
+)
+
+// SamplingSender writes a JSON-RPC message to a client session.
+type SamplingSender func(message []byte) error
+
+// requestSender is implemented by client sessions scoped to one request,
+// which send sampling requests on that request's response stream rather than
+// with the sender registered for the session.
+type requestSender interface {
+	sendRequest(message []byte) error
+}
+
+// Sampling sends sampling/createMessage requests to the clients that declared
+// the sampling capability and routes their responses back. mcp-go v0.18
+// cannot send requests to clients, so transports register how to write to
+// their sessions and pass the responses they read to HandleResponse.
+type Sampling struct {
+	mu      sync.Mutex
+	nextID  int64
+	pending map[string]pendingSample  // By request ID
+	capable map[string]bool           // Session IDs of clients supporting sampling
+	senders map[string]SamplingSender // By session ID
+}
+
+// pendingSample is a sampling request waiting for its response.
+type pendingSample struct {
+	sessionID string // Session the request was sent to
+	response  chan samplingResponse
+}
+
+// samplingResponse is the response of a client to a sampling request.
+type samplingResponse struct {
+	ID     string               `json:"id"`
+	Method string               `json:"method"`
+	Result *samplingResult      `json:"result"`
+	Error  *samplingClientError `json:"error"`
+}
+
+// samplingResult is the result of a sampling/createMessage request.
+type samplingResult struct {
+	Role    string `json:"role"`
+	Content struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Model      string `json:"model"`
+	StopReason string `json:"stopReason"`
+}
+
+// samplingClientError is the JSON-RPC error of a rejected sampling request.
+type samplingClientError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewSampling creates a Sampling with no sessions.
+func NewSampling() *Sampling {
+	return &Sampling{
+		pending: make(map[string]pendingSample),
+		capable: make(map[string]bool),
+		senders: make(map[string]SamplingSender),
+	}
+}
+
+// AfterInitialize records whether the client of the session supports
+// sampling. It is meant to be called from the server's AfterInitialize hook.
+func (s *Sampling) AfterInitialize(ctx context.Context, id any, request *mcp.InitializeRequest, result *mcp.InitializeResult) {
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return
+	}
+	supported := request.Params.Capabilities.Sampling != nil
+	zap.S().Debugw("client sampling capability", "session", session.SessionID(), "sampling", supported)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.capable[session.SessionID()] = supported
+}
+
+// RegisterSender sets how sampling requests are written to a session.
+func (s *Sampling) RegisterSender(sessionID string, send SamplingSender) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.senders[sessionID] = send
+}
+
+// UnregisterSession forgets a closed session.
+func (s *Sampling) UnregisterSession(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.capable, sessionID)
+	delete(s.senders, sessionID)
+}
+
+// Sampler returns a fetcher.Sampler asking the client of session, or an
+// ErrSampling error if it cannot be sampled.
+func (s *Sampling) Sampler(session server.ClientSession) (fetcher.Sampler, error) {
+	if session == nil {
+		return nil, ierrors.New(ierrors.ErrSampling, "no client session to sample")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.capable[session.SessionID()] {
+		return nil, ierrors.New(ierrors.ErrSampling, "the client does not support MCP sampling")
+	}
+	send := s.senders[session.SessionID()]
+	if sender, ok := session.(requestSender); ok {
+		send = sender.sendRequest
+	}
+	if send == nil {
+		return nil, ierrors.New(ierrors.ErrSampling, "the transport does not support MCP sampling")
+	}
+	return &sessionSampler{sampling: s, sessionID: session.SessionID(), send: send}, nil
+}
+
+// HandleResponse delivers message, read from the client of session sessionID,
+// if it is the response to a sampling request, and reports whether it was.
+// Responses to requests sent to other sessions are dropped.
+func (s *Sampling) HandleResponse(sessionID string, message json.RawMessage) bool {
+	var response samplingResponse
+	if err := json.Unmarshal(message, &response); err != nil {
+		return false
+	}
+	if response.Method != "" || !strings.HasPrefix(response.ID, samplingRequestPrefix) {
+		return false
+	}
+
+	s.mu.Lock()
+	pending, ok := s.pending[response.ID]
+	if ok && pending.sessionID != sessionID {
+		ok = false
+	}
+	if ok {
+		delete(s.pending, response.ID)
+	}
+	s.mu.Unlock()
+	if !ok {
+		zap.S().Warnw("dropping response to unknown sampling request", "id", response.ID, "session", sessionID)
+		return true
+	}
+	pending.response <- response
+	return true
+}
+
+// sessionSampler samples the model of one client session.
+type sessionSampler struct {
+	sampling  *Sampling
+	sessionID string
+	send      SamplingSender
+}
+
+// Sample implements fetcher.Sampler.
+func (c *sessionSampler) Sample(ctx context.Context, request fetcher.SampleRequest) (*fetcher.SampleResult, error) {
+	s := c.sampling
+	s.mu.Lock()
+	s.nextID++
+	id := fmt.Sprintf("%s%d", samplingRequestPrefix, s.nextID)
+	ch := make(chan samplingResponse, 1)
+	s.pending[id] = pendingSample{sessionID: c.sessionID, response: ch}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+
+	params := map[string]any{
+		"messages": []mcp.SamplingMessage{
+			{Role: mcp.RoleUser, Content: mcp.NewTextContent(request.Prompt)},
+		},
+		"includeContext": "none",
+		"maxTokens":      request.MaxTokens,
+	}
+	if request.SystemPrompt != "" {
+		params["systemPrompt"] = request.SystemPrompt
+	}
+	message, err := json.Marshal(map[string]any{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      id,
+		"method":  methodSamplingCreateMessage,
+		"params":  params,
+	})
+	if err != nil {
+		return nil, ierrors.Wrap(err, "failed to marshal sampling request")
+	}
+
+	zap.S().Debugw("sending sampling request", "id", id, "session", c.sessionID, "prompt_length", len(request.Prompt))
+	if err := c.send(message); err != nil {
+		return nil, ierrors.Wrap(err, "failed to send sampling request")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, samplingTimeout)
+	defer cancel()
+	select {
+	case <-ctx.Done():
+		return nil, ierrors.Wrap(ctx.Err(), "no sampling response")
+	case response := <-ch:
+		if response.Error != nil {
+			return nil, ierrors.Newf(ierrors.ErrSampling, "sampling request rejected: %s (code %d)", response.Error.Message, response.Error.Code)
+		}
+		if response.Result == nil || response.Result.Content.Type != "text" {
+			return nil, ierrors.New(ierrors.ErrSampling, "sampling response has no text content")
+		}
+		zap.S().Debugw("received sampling response",
+			"id", id,
+			"model", response.Result.Model,
+			"stop_reason", response.Result.StopReason)
+		return &fetcher.SampleResult{Text: response.Result.Content.Text, Model: response.Result.Model}, nil
+	}
+}
@@ -2,6 +2,8 @@ package server
 
 import (
 	"context"
+	"slices"
+	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -12,9 +14,48 @@ import (
 	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
 )
 
+// NewFetcher - Create the HTTP Fetcher configured by cfg
+func NewFetcher(cfg *config.Config) (fetcher.Fetcher, error) {
+	zap.S().Debugw("creating HTTP Fetcher")
+	httpFetcher, err := fetcher.NewHTTPFetcher(&fetcher.Config{
+		Timeout:          cfg.Fetch.Timeout,
+		UserAgent:        cfg.Fetch.UserAgent,
+		MaxURLs:          cfg.Fetch.MaxURLs,
+		MaxWorkers:       cfg.Fetch.MaxWorkers,
+		DefaultMaxLength: cfg.Fetch.DefaultMaxLength,
+		Tokenizer:        cfg.Fetch.Tokenizer,
+		Robots:           cfg.Fetch.Robots,
+		RobotsUserAgent:  cfg.Fetch.RobotsUserAgent,
+		HTTPErrors:       cfg.Fetch.HTTPErrors,
+		MaxBodySize:      cfg.Fetch.MaxBodySize,
+	})
+	if err != nil {
+		zap.S().Errorw("failed to create HTTP Fetcher", "error", err)
+		return nil, err
+	}
+	return httpFetcher, nil
+}
+
 // Run - Execute the MCP server
 func Run(cfg *config.Config, name string, version string, revision string) error {
-	zap.S().Infow("starting MCP Fetch Server")
+	zap.S().Infow("starting MCP Fetch Server", "transport", cfg.Transport.Type)
+
+	if !slices.Contains(Transports, cfg.Transport.Type) {
+		return ierrors.Newf(ierrors.ErrInvalidArgument, "invalid transport %q: expected one of %v", cfg.Transport.Type, Transports)
+	}
+
+	// Authenticate the clients of the network transports
+	auth, err := NewAuthenticator(cfg)
+	if err != nil {
+		zap.S().Errorw("failed to configure authentication", "error", err)
+		return err
+	}
+	switch {
+	case cfg.Transport.Type == TransportStdio && auth != nil:
+		zap.S().Infow("auth clients are ignored on the stdio transport")
+	case cfg.Transport.Type != TransportStdio && auth == nil:
+		zap.S().Warnw("no auth clients configured: anyone who can reach the server can use it", "addr", cfg.Transport.Addr)
+	}
 
 	// Format version string with revision if available
 	versionString := version
@@ -23,21 +64,51 @@ func Run(cfg *config.Config, name string, version string, revision string) error
 	}
 
 	// Create Fetcher
-	zap.S().Debugw("creating HTTP Fetcher")
-	httpFetcher, err := fetcher.NewHTTPFetcher(&fetcher.Config{
-		Timeout:          cfg.Fetch.Timeout,
-		UserAgent:        cfg.Fetch.UserAgent,
-		MaxURLs:          cfg.Fetch.MaxURLs,
-		MaxWorkers:       cfg.Fetch.MaxWorkers,
-		DefaultMaxLength: cfg.Fetch.DefaultMaxLength,
-	})
+	httpFetcher, err := NewFetcher(cfg)
 	if err != nil {
-		zap.S().Errorw("failed to create HTTP Fetcher", "error", err)
 		return err
 	}
 
+	// Record the documents returned by the tools and resources for
+	// fetch-history://. The history is shared by every client, so it is not
+	// kept when clients authenticate
+	var f fetcher.Fetcher = httpFetcher
+	var history *fetcher.History
+	switch {
+	case cfg.Fetch.HistorySize > 0 && auth != nil:
+		zap.S().Infow("fetch history disabled: auth clients are configured")
+	case cfg.Fetch.HistorySize > 0:
+		history = fetcher.NewHistory(cfg.Fetch.HistorySize)
+		f = fetcher.RecordHistory(httpFetcher, history)
+	}
+
+	// Keep snapshots of fetched pages for fetch_diff. The differ stores the
+	// versions it fetches itself; the tools record the complete documents
+	// they return
+	var differ *fetcher.Differ
+	if cfg.Fetch.SnapshotURLs > 0 {
+		snapshots := fetcher.NewSnapshots(cfg.Fetch.SnapshotURLs, cfg.Fetch.SnapshotsPerURL)
+		differ = fetcher.NewDiffer(f, snapshots)
+		f = fetcher.RecordSnapshots(f, snapshots)
+	}
+
+	// Watch pages for resource subscriptions and watch_url. Polls go to the
+	// HTTP fetcher directly, so they are not recorded in the history, and are
+	// checked against the policies of the subscribed clients
+	subs := NewSubscriptions(httpFetcher, fetcher.WatcherConfig{
+		Interval:    time.Duration(cfg.Fetch.WatchInterval) * time.Second,
+		MinInterval: time.Duration(cfg.Fetch.WatchMinInterval) * time.Second,
+		MaxWatches:  cfg.Fetch.MaxWatches,
+	})
+	defer subs.Close()
+
 	// Create custom hooks for error handling
 	hooks := &server.Hooks{}
+	hooks.AddOnRegisterSession(subs.RegisterSession)
+
+	// Send sampling requests to the clients supporting them, for fetch_summary
+	sampling := NewSampling()
+	hooks.AddAfterInitialize(sampling.AfterInitialize)
 	hooks.AddOnError(func(ctx context.Context, id any, method mcp.MCPMethod, message any, err error) {
 		zap.S().Errorw("MCP error occurred",
 			"id", id,
@@ -55,24 +126,72 @@ func Run(cfg *config.Config, name string, version string, revision string) error
 		name,
 		versionString,
 		server.WithHooks(hooks),
+		server.WithResourceCapabilities(true, false),
+		server.WithPromptCapabilities(false),
 	)
 
 	// Register all tools
 	zap.S().Debugw("registering tools")
-	if err := RegisterAllTools(mcpServer, httpFetcher, cfg.Fetch.MaxURLs, cfg); err != nil {
+	if err := RegisterAllTools(mcpServer, f, cfg.Fetch.MaxURLs, cfg); err != nil {
 		zap.S().Errorw("failed to register tools", "error", err)
 		return err
 	}
 
-	// Start the server with stdio transport
+	// Register watch_url tool
+	if err := RegisterWatchURLTool(mcpServer, subs, cfg); err != nil {
+		zap.S().Errorw("failed to register tools", "error", err)
+		return err
+	}
+
+	// Register fetch_summary tool
+	if err := RegisterFetchSummaryTool(mcpServer, f, sampling); err != nil {
+		zap.S().Errorw("failed to register tools", "error", err)
+		return err
+	}
+
+	// Register fetch_diff tool
+	if differ != nil {
+		if err := RegisterFetchDiffTool(mcpServer, differ, cfg); err != nil {
+			zap.S().Errorw("failed to register tools", "error", err)
+			return err
+		}
+	}
+
+	// Register prompts
+	zap.S().Debugw("registering prompts")
+	if err := RegisterPrompts(mcpServer, f, cfg); err != nil {
+		zap.S().Errorw("failed to register prompts", "error", err)
+		return err
+	}
+
+	// Register resources. Reads are cached, so that attaching a page again
+	// does not refetch it
+	zap.S().Debugw("registering resources")
+	resourceFetcher := f
+	if cfg.Fetch.ResourceCacheTTL > 0 {
+		cache := fetcher.NewCache(fetcher.DefaultCacheSize, time.Duration(cfg.Fetch.ResourceCacheTTL)*time.Second)
+		resourceFetcher = fetcher.CacheResponses(f, cache)
+	}
+	if err := RegisterFetchResources(mcpServer, resourceFetcher, history, cfg.Fetch.ResourceMaxLength); err != nil {
+		zap.S().Errorw("failed to register resources", "error", err)
+		return err
+	}
+
+	// Start the server with the configured transport
 	zap.S().Infow("starting MCP server")
-	err = server.ServeStdio(mcpServer)
+	if cfg.Transport.Type == TransportStdio {
+		err = serveStdio(mcpServer, subs, sampling)
+	} else {
+		transport := newHTTPTransport(mcpServer, cfg.Transport.Type, cfg.Transport.BaseURL, subs, sampling, auth)
+		hooks.AddOnRegisterSession(transport.registerSession)
+		err = transport.serve(cfg.Transport.Addr, time.Duration(cfg.Transport.ShutdownTimeout)*time.Second)
+	}
 	if err != nil {
 		zap.S().Errorw("failed to start server", "error", err)
 		return ierrors.Wrap(err, "failed to start server")
 	}
 
-	// ServeStdio will block until the server is terminated
+	// The transports block until the server is terminated
 	zap.S().Infow("server shutting down")
 	return nil
 }
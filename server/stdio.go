@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// stdioSessionID is the ID of the single client session of mcp-go's stdio
+// transport.
+const stdioSessionID = "stdio"
+
+// forwardQueueSize is the number of input messages queued for the stdio
+// server while it handles a request.
+const forwardQueueSize = 256
+
+// serveStdio serves mcpServer on stdin and stdout like server.ServeStdio. It
+// answers the resource subscription requests mcp-go does not route with subs,
+// and sends sampling requests and routes their responses with sampling.
+// It returns when stdin is closed or on SIGINT or SIGTERM.
+func serveStdio(mcpServer *server.MCPServer, subs *Subscriptions, sampling *Sampling) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
+	stdout := &lockedWriter{writer: os.Stdout}
+	sampling.RegisterSender(stdioSessionID, func(message []byte) error {
+		_, err := stdout.Write(append(message, '\n'))
+		return err
+	})
+
+	input, forward := io.Pipe()
+	go func() {
+		forward.CloseWithError(filterInput(ctx, os.Stdin, forward, stdout, subs, sampling))
+	}()
+
+	stdioServer := server.NewStdioServer(mcpServer)
+	stdioServer.SetErrorLogger(log.New(os.Stderr, "", log.LstdFlags))
+	return stdioServer.Listen(ctx, input, stdout)
+}
+
+// filterInput copies the messages read from in to forward, except the
+// resource subscription requests, which subs answers directly on out, and
+// the responses to sampling requests, which go to sampling. Messages are
+// forwarded from a queue, so that sampling responses are still read while
+// the stdio server waits for them in a tool call.
+func filterInput(ctx context.Context, in io.Reader, forward io.Writer, out io.Writer, subs *Subscriptions, sampling *Sampling) error {
+	queue := make(chan []byte, forwardQueueSize)
+	forwardErr := make(chan error, 1)
+	go func() {
+		for line := range queue {
+			if _, err := forward.Write(line); err != nil {
+				forwardErr <- err
+				// Drain the queue so the reader does not block
+				for range queue {
+				}
+				return
+			}
+		}
+		forwardErr <- nil
+	}()
+
+	err := readInput(ctx, in, queue, out, subs, sampling)
+	close(queue)
+	if ferr := <-forwardErr; err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// readInput reads the messages of in and dispatches them for filterInput.
+func readInput(ctx context.Context, in io.Reader, queue chan<- []byte, out io.Writer, subs *Subscriptions, sampling *Sampling) error {
+	reader := bufio.NewReader(in)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if err := dispatchInput(ctx, line, queue, out, subs, sampling); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			zap.S().Errorw("failed to read input", "error", err)
+			return err
+		}
+	}
+}
+
+// dispatchInput delivers a sampling response, answers a subscription request
+// on out, or queues any other message for the stdio server.
+func dispatchInput(ctx context.Context, line []byte, queue chan<- []byte, out io.Writer, subs *Subscriptions, sampling *Sampling) error {
+	if sampling.HandleResponse(stdioSessionID, json.RawMessage(line)) {
+		return nil
+	}
+	response, handled := subs.HandleMessage(ctx, subs.session(stdioSessionID), json.RawMessage(line))
+	if !handled {
+		queue <- line
+		return nil
+	}
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		return ierrors.Wrap(err, "failed to marshal subscription response")
+	}
+	if _, err := out.Write(append(responseBytes, '\n')); err != nil {
+		return ierrors.Wrap(err, "failed to write subscription response")
+	}
+	return nil
+}
+
+// lockedWriter serializes writes, so that the messages written by the stdio
+// server, filterInput and sampling requests are not interleaved.
+type lockedWriter struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// Write implements io.Writer.
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writer.Write(p)
+}
@@ -0,0 +1,383 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cnosuke/mcp-fetch/fetcher"
+	ierrors "github.com/cnosuke/mcp-fetch/internal/errors"
+	"github.com/cnosuke/mcp-fetch/types"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// MCP methods of resource subscriptions. mcp-go v0.18 declares the messages
+// but does not route these requests, so they are answered by
+// Subscriptions.HandleMessage before the server sees them.
+const (
+	methodResourcesSubscribe   = "resources/subscribe"
+	methodResourcesUnsubscribe = "resources/unsubscribe"
+	methodResourcesUpdated     = "notifications/resources/updated"
+)
+
+// watchOperation is the operation the polls of watched pages are checked and
+// logged as against the policies of the subscribed clients.
+const watchOperation = "watch"
+
+// Subscriptions tracks the fetch:// resources clients subscribed to, directly
+// or with the watch_url tool, and notifies them when the watcher detects a
+// change. A page is watched as long as a client is subscribed to it.
+//
+// Each authenticated client has its own watches, with their own intervals,
+// its own max_watches pool and listings limited to them. Every poll of a page
+// is checked against the client's policy and counts against its rate limit;
+// a page whose poll the policy refuses stops being watched. Sessions without
+// a client, e.g. on stdio, share the watches of the unnamed client.
+type Subscriptions struct {
+	fetcher fetcher.Fetcher
+	cfg     fetcher.WatcherConfig
+
+	mu       sync.Mutex
+	sessions map[string]server.ClientSession
+	clients  map[string]*Client        // Authenticated clients by session ID
+	watches  map[string]*clientWatches // By client name, "" for sessions without one
+}
+
+// clientWatches are the pages watched for one client.
+type clientWatches struct {
+	watcher *fetcher.Watcher
+	byURI   map[string]map[string]bool // Subscribed session IDs by resource URI
+}
+
+// NewSubscriptions creates the subscriptions of watched pages, polled with f
+// according to cfg.
+func NewSubscriptions(f fetcher.Fetcher, cfg fetcher.WatcherConfig) *Subscriptions {
+	return &Subscriptions{
+		fetcher:  f,
+		cfg:      cfg,
+		sessions: make(map[string]server.ClientSession),
+		clients:  make(map[string]*Client),
+		watches:  make(map[string]*clientWatches),
+	}
+}
+
+// clientWatches returns the watches of client, nil for sessions without one,
+// creating them if create is set. s.mu must be held.
+func (s *Subscriptions) clientWatches(client *Client, create bool) *clientWatches {
+	name := ""
+	if client != nil {
+		name = client.Name
+	}
+	cw := s.watches[name]
+	if cw != nil || !create {
+		return cw
+	}
+	var f fetcher.Fetcher = s.fetcher
+	if client != nil {
+		f = &pollFetcher{Fetcher: s.fetcher, subs: s, client: client}
+	}
+	cw = &clientWatches{byURI: make(map[string]map[string]bool)}
+	cw.watcher = fetcher.NewWatcher(f, s.cfg, func(change types.WatchChange) {
+		s.notifyChange(name, change)
+	})
+	s.watches[name] = cw
+	return cw
+}
+
+// pollFetcher is the Fetcher of a client's watcher: its requests are guarded
+// by the client's policy.
+type pollFetcher struct {
+	fetcher.Fetcher
+	subs   *Subscriptions
+	client *Client
+}
+
+// FetchWithOptions implements fetcher.Fetcher.
+func (f *pollFetcher) FetchWithOptions(urlStr string, opts fetcher.FetchOptions) (*types.FetchResponse, error) {
+	return f.Fetcher.WithGuard(f.subs.pollGuard(f.client, urlStr)).FetchWithOptions(urlStr, opts)
+}
+
+// pollGuard returns the guard of the requests of client for the watched page
+// urlStr: the client's policy. When the policy refuses a request, the
+// client's sessions are unsubscribed and the page stops being watched.
+func (s *Subscriptions) pollGuard(client *Client, urlStr string) fetcher.URLGuard {
+	guard := client.policy.Guard(watchOperation)
+	return func(u *url.URL, redirect bool) error {
+		err := guard(u, redirect)
+		if err == nil {
+			return nil
+		}
+		zap.S().Warnw("stopped watching page refused by the client's policy",
+			"url", urlStr,
+			"client", client.Name,
+			"error", err)
+		s.mu.Lock()
+		cw := s.clientWatches(client, false)
+		if cw != nil {
+			delete(cw.byURI, fetchResourceURI(urlStr))
+		}
+		s.mu.Unlock()
+		if cw != nil {
+			cw.watcher.Unwatch(urlStr)
+		}
+		return err
+	}
+}
+
+// RegisterSession records a client session so it can be notified. It is
+// meant to be called from the server's OnRegisterSession hook.
+func (s *Subscriptions) RegisterSession(ctx context.Context, session server.ClientSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[session.SessionID()] = session
+}
+
+// UnregisterSession forgets a closed session and unsubscribes it from every
+// resource, so that pages no other session is subscribed to stop being
+// watched.
+func (s *Subscriptions) UnregisterSession(sessionID string) {
+	s.mu.Lock()
+	delete(s.sessions, sessionID)
+	cw := s.clientWatches(s.clients[sessionID], false)
+	delete(s.clients, sessionID)
+	var unwatched []string
+	if cw != nil {
+		for uri, subscribers := range cw.byURI {
+			if !subscribers[sessionID] {
+				continue
+			}
+			delete(subscribers, sessionID)
+			if len(subscribers) == 0 {
+				delete(cw.byURI, uri)
+				unwatched = append(unwatched, uri)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	for _, uri := range unwatched {
+		if urlStr, err := pageURL(uri, ""); err == nil {
+			cw.watcher.Unwatch(urlStr)
+		}
+	}
+}
+
+// session returns the registered session with the given ID, or nil.
+func (s *Subscriptions) session(sessionID string) server.ClientSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[sessionID]
+}
+
+// Close stops watching every page.
+func (s *Subscriptions) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cw := range s.watches {
+		cw.watcher.Close()
+	}
+}
+
+// Watch watches urlStr for the client of ctx every interval (the configured
+// default if zero) and subscribes session, if any, to its fetch:// resource.
+// The session is subscribed first, so that a refused baseline fetch
+// unsubscribes it again.
+func (s *Subscriptions) Watch(ctx context.Context, session server.ClientSession, urlStr string, interval time.Duration) (*types.WatchStatus, error) {
+	client := clientFromContext(ctx)
+	uri := fetchResourceURI(urlStr)
+
+	s.mu.Lock()
+	cw := s.clientWatches(client, true)
+	var subscribed bool
+	if session != nil {
+		// Keep the registered session: session may be scoped to the request
+		if _, ok := s.sessions[session.SessionID()]; !ok {
+			s.sessions[session.SessionID()] = session
+		}
+		if client != nil {
+			s.clients[session.SessionID()] = client
+		}
+		if cw.byURI[uri] == nil {
+			cw.byURI[uri] = make(map[string]bool)
+		}
+		subscribed = !cw.byURI[uri][session.SessionID()]
+		cw.byURI[uri][session.SessionID()] = true
+	}
+	s.mu.Unlock()
+
+	status, err := cw.watcher.Watch(urlStr, interval)
+	if err != nil {
+		if subscribed {
+			s.mu.Lock()
+			delete(cw.byURI[uri], session.SessionID())
+			if len(cw.byURI[uri]) == 0 {
+				delete(cw.byURI, uri)
+			}
+			s.mu.Unlock()
+		}
+		return nil, err
+	}
+	status.Resource = uri
+	return status, nil
+}
+
+// Unwatch unsubscribes session from the fetch:// resource of urlStr, and
+// stops watching the page for the client of ctx once none of its sessions is
+// subscribed to it. It reports whether the page is still watched.
+func (s *Subscriptions) Unwatch(ctx context.Context, session server.ClientSession, urlStr string) bool {
+	uri := fetchResourceURI(urlStr)
+
+	s.mu.Lock()
+	cw := s.clientWatches(clientFromContext(ctx), false)
+	if cw == nil {
+		s.mu.Unlock()
+		return false
+	}
+	if session != nil {
+		delete(cw.byURI[uri], session.SessionID())
+	}
+	remaining := len(cw.byURI[uri])
+	if remaining == 0 {
+		delete(cw.byURI, uri)
+	}
+	s.mu.Unlock()
+
+	if remaining > 0 {
+		return true
+	}
+	cw.watcher.Unwatch(urlStr)
+	return false
+}
+
+// Status returns the state of a page watched for the client of ctx, or nil
+// if it is not watched.
+func (s *Subscriptions) Status(ctx context.Context, urlStr string) *types.WatchStatus {
+	s.mu.Lock()
+	cw := s.clientWatches(clientFromContext(ctx), false)
+	s.mu.Unlock()
+	if cw == nil {
+		return nil
+	}
+	status := cw.watcher.Status(urlStr)
+	if status != nil {
+		status.Resource = fetchResourceURI(status.URL)
+	}
+	return status
+}
+
+// Watches returns the state of every page watched for the client of ctx.
+func (s *Subscriptions) Watches(ctx context.Context) []types.WatchStatus {
+	s.mu.Lock()
+	cw := s.clientWatches(clientFromContext(ctx), false)
+	s.mu.Unlock()
+	if cw == nil {
+		return []types.WatchStatus{}
+	}
+	watches := cw.watcher.Watches()
+	for i := range watches {
+		watches[i].Resource = fetchResourceURI(watches[i].URL)
+	}
+	return watches
+}
+
+// notifyChange sends notifications/resources/updated, with a summary of the
+// diff, to the sessions of the client named client subscribed to the changed
+// page.
+func (s *Subscriptions) notifyChange(client string, change types.WatchChange) {
+	uri := fetchResourceURI(change.URL)
+
+	s.mu.Lock()
+	var sessions []server.ClientSession
+	if cw := s.watches[client]; cw != nil {
+		for sessionID := range cw.byURI[uri] {
+			if session, ok := s.sessions[sessionID]; ok && session.Initialized() {
+				sessions = append(sessions, session)
+			}
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].SessionID() < sessions[j].SessionID() })
+
+	notification := mcp.JSONRPCNotification{
+		JSONRPC: mcp.JSONRPC_VERSION,
+		Notification: mcp.Notification{
+			Method: methodResourcesUpdated,
+			Params: mcp.NotificationParams{
+				AdditionalFields: map[string]any{
+					"uri":         uri,
+					"summary":     change.Diff.Summary,
+					"diff":        change.Diff,
+					"detected_at": change.DetectedAt,
+				},
+			},
+		},
+	}
+	for _, session := range sessions {
+		select {
+		case session.NotificationChannel() <- notification:
+			zap.S().Debugw("sent resource update", "uri", uri, "session", session.SessionID())
+		default:
+			zap.S().Warnw("failed to send resource update: notification channel full",
+				"uri", uri,
+				"session", session.SessionID())
+		}
+	}
+}
+
+// subscriptionRequest is a resources/subscribe or resources/unsubscribe
+// request.
+type subscriptionRequest struct {
+	ID     mcp.RequestId `json:"id"`
+	Method string        `json:"method"`
+	Params struct {
+		URI string `json:"uri"`
+	} `json:"params"`
+}
+
+// HandleMessage answers message if it is a resources/subscribe or
+// resources/unsubscribe request from session, and reports whether it did.
+// Other messages are left to the MCP server.
+func (s *Subscriptions) HandleMessage(ctx context.Context, session server.ClientSession, message json.RawMessage) (mcp.JSONRPCMessage, bool) {
+	var request subscriptionRequest
+	if err := json.Unmarshal(message, &request); err != nil {
+		return nil, false
+	}
+	if request.Method != methodResourcesSubscribe && request.Method != methodResourcesUnsubscribe {
+		return nil, false
+	}
+
+	zap.S().Infow("handling resource subscription", "method", request.Method, "uri", request.Params.URI)
+
+	urlStr, err := pageURL(request.Params.URI, "")
+	if err != nil {
+		return subscriptionError(request.ID, mcp.INVALID_PARAMS, err), true
+	}
+	if request.Method == methodResourcesUnsubscribe {
+		s.Unwatch(ctx, session, urlStr)
+		return mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: request.ID, Result: mcp.EmptyResult{}}, true
+	}
+	if err := checkDomains(ctx, request.Method, urlStr); err != nil {
+		return subscriptionError(request.ID, mcp.INVALID_REQUEST, err), true
+	}
+	if _, err := s.Watch(ctx, session, urlStr, 0); err != nil {
+		zap.S().Errorw("failed to subscribe to resource",
+			"uri", request.Params.URI,
+			"error", err)
+		return subscriptionError(request.ID, mcp.INTERNAL_ERROR, ierrors.Wrapf(err, "failed to watch %s (%s)", urlStr, ierrors.Code(err))), true
+	}
+	return mcp.JSONRPCResponse{JSONRPC: mcp.JSONRPC_VERSION, ID: request.ID, Result: mcp.EmptyResult{}}, true
+}
+
+// subscriptionError returns the JSON-RPC error response to a subscription
+// request.
+func subscriptionError(id mcp.RequestId, code int, err error) mcp.JSONRPCError {
+	response := mcp.JSONRPCError{JSONRPC: mcp.JSONRPC_VERSION, ID: id}
+	response.Error.Code = code
+	response.Error.Message = err.Error()
+	return response
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cnosuke/mcp-fetch/config"
+	"github.com/cnosuke/mcp-fetch/types"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+// WatchURLArgs - Arguments for watch_url tool
+type WatchURLArgs struct {
+	URL             string `json:"url" jsonschema:"description=URL to watch for changes,required=true"`
+	IntervalSeconds int    `json:"interval_seconds,omitempty" jsonschema:"description=Polling interval in seconds"`
+	Unwatch         bool   `json:"unwatch,omitempty" jsonschema:"description=Stop watching the URL"`
+}
+
+// RegisterWatchURLTool - Register the watch_url tool
+func RegisterWatchURLTool(mcpServer *server.MCPServer, subs *Subscriptions, cfg *config.Config) error {
+	zap.S().Debugw("registering watch_url tool")
+
+	// Define the tool
+	tool := mcp.NewTool("watch_url",
+		mcp.WithDescription("Watches a URL for meaningful changes, e.g. a status page, changelog or pricing page. The page is polled with conditional requests and its processed markdown compared line by line, ignoring whitespace-only changes. The caller is subscribed to the page's fetch:// resource and receives notifications/resources/updated with a summary of the diff when it changes. Returns the state of the watch and of every watched URL."),
+		mcp.WithString("url",
+			mcp.Description("URL to watch for changes"),
+			mcp.Required(),
+		),
+		mcp.WithNumber("interval_seconds",
+			mcp.Description(fmt.Sprintf("Polling interval in seconds (default: %d, minimum: %d)", cfg.Fetch.WatchInterval, cfg.Fetch.WatchMinInterval)),
+		),
+		mcp.WithBoolean("unwatch",
+			mcp.Description("Stop watching the URL instead (default: false)"),
+		),
+	)
+
+	// Register the tool handler
+	mcpServer.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		// Extract parameters
+		url, _ := request.Params.Arguments["url"].(string)
+		intervalSeconds := 0
+		if v, ok := request.Params.Arguments["interval_seconds"].(float64); ok {
+			intervalSeconds = int(v)
+		}
+		unwatch, _ := request.Params.Arguments["unwatch"].(bool)
+
+		zap.S().Infow("executing watch_url",
+			"url", url,
+			"interval_seconds", intervalSeconds,
+			"unwatch", unwatch)
+
+		// Validate parameters
+		if url == "" {
+			return invalidArgument("URL is required"), nil
+		}
+		if _, err := pageURL(fetchResourceURI(url), ""); err != nil {
+			return invalidArgument("URL must start with http:// or https://"), nil
+		}
+		if intervalSeconds < 0 {
+			return invalidArgument("interval_seconds must be positive"), nil
+		}
+
+		session := server.ClientSessionFromContext(ctx)
+		response := &types.WatchResponse{}
+		if unwatch {
+			response.Watching = subs.Unwatch(ctx, session, url)
+			response.Watch = subs.Status(ctx, url)
+		} else {
+			if err := checkDomains(ctx, "watch_url", url); err != nil {
+				return toolError("failed to watch URL", err), nil
+			}
+			status, err := subs.Watch(ctx, session, url, time.Duration(intervalSeconds)*time.Second)
+			if err != nil {
+				zap.S().Errorw("failed to watch URL",
+					"url", url,
+					"error", err)
+				return toolError("failed to watch URL", err), nil
+			}
+			response.Watching = true
+			response.Watch = status
+		}
+		response.Watches = subs.Watches(ctx)
+
+		// Convert response to JSON
+		jsonResponse, err := json.Marshal(response)
+		if err != nil {
+			zap.S().Errorw("failed to marshal response to JSON",
+				"error", err)
+			return toolError("failed to marshal response to JSON", err), nil
+		}
+
+		return mcp.NewToolResultText(string(jsonResponse)), nil
+	})
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package tokenizer
+
+import (
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktokenloader "github.com/pkoukk/tiktoken-go-loader"
+	"go.uber.org/zap"
+)
+
+// The cl100k_base vocabulary is embedded in the binary and loaded once per
+// process on first use, since parsing it takes a noticeable amount of time.
+var (
+	cl100kOnce     sync.Once
+	cl100kEncoding *tiktoken.Tiktoken
+	cl100kErr      error
+)
+
+func loadCL100K() (*tiktoken.Tiktoken, error) {
+	cl100kOnce.Do(func() {
+		tiktoken.SetBpeLoader(tiktokenloader.NewOfflineLoader())
+		cl100kEncoding, cl100kErr = tiktoken.GetEncoding("cl100k_base")
+		if cl100kErr != nil {
+			zap.S().Errorw("failed to load cl100k_base vocabulary, falling back to heuristic tokenizer", "error", cl100kErr)
+		}
+	})
+	return cl100kEncoding, cl100kErr
+}
+
+// cl100k is a cl100k_base compatible BPE tokenizer backed by tiktoken-go.
+type cl100k struct {
+	fallback Tokenizer
+}
+
+// NewCL100K returns a cl100k_base BPE tokenizer. If the embedded vocabulary
+// cannot be loaded, it degrades to the heuristic estimator.
+func NewCL100K() Tokenizer {
+	return &cl100k{fallback: NewHeuristic()}
+}
+
+// Name returns the tokenizer name.
+func (c *cl100k) Name() string {
+	return NameCL100K
+}
+
+// Count returns the number of cl100k_base tokens in text.
+func (c *cl100k) Count(text string) int {
+	encoding, err := loadCL100K()
+	if err != nil {
+		return c.fallback.Count(text)
+	}
+	return len(encoding.EncodeOrdinary(text))
+}
+
+// Offsets returns the byte offset at which each cl100k_base token starts.
+func (c *cl100k) Offsets(text string) []int {
+	encoding, err := loadCL100K()
+	if err != nil {
+		return c.fallback.Offsets(text)
+	}
+	tokens := encoding.EncodeOrdinary(text)
+	offsets := make([]int, len(tokens))
+	pos := 0
+	for i, token := range tokens {
+		offsets[i] = pos
+		// Decoding a single token yields its exact bytes, which may be a partial rune
+		pos += len(encoding.Decode([]int{token}))
+	}
+	return offsets
+}
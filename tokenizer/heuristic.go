@@ -0,0 +1,83 @@
+package tokenizer
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// heuristicWordChunk is the average number of letters or digits per token in
+// English-like text for BPE vocabularies of the cl100k size.
+const heuristicWordChunk = 4
+
+// heuristic estimates tokens without a vocabulary: words are split into
+// chunks of heuristicWordChunk characters, while punctuation, symbols and
+// ideographic (CJK) characters count as one token each. Whitespace is
+// attached to the following token, as BPE vocabularies do.
+type heuristic struct{}
+
+// NewHeuristic returns the fast heuristic token estimator.
+func NewHeuristic() Tokenizer {
+	return heuristic{}
+}
+
+// Name returns the tokenizer name.
+func (heuristic) Name() string {
+	return NameHeuristic
+}
+
+// Count returns the estimated number of tokens in text.
+func (h heuristic) Count(text string) int {
+	return len(h.Offsets(text))
+}
+
+// Offsets returns the byte offset at which each estimated token starts.
+func (heuristic) Offsets(text string) []int {
+	offsets := []int{}
+	tokenStart := -1 // Start of pending whitespace to attach to the next token
+	wordLen := 0     // Characters in the current word chunk
+
+	for i, r := range text {
+		start := i
+		if tokenStart >= 0 {
+			start = tokenStart
+		}
+
+		switch {
+		case unicode.IsSpace(r):
+			if tokenStart < 0 {
+				tokenStart = i
+			}
+			wordLen = 0
+			continue
+		case isWide(r):
+			offsets = append(offsets, start)
+			wordLen = 0
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			if wordLen == 0 || wordLen >= heuristicWordChunk {
+				offsets = append(offsets, start)
+				wordLen = 0
+			}
+			wordLen++
+		default:
+			offsets = append(offsets, start)
+			wordLen = 0
+		}
+		tokenStart = -1
+	}
+
+	// Trailing whitespace forms a token of its own
+	if tokenStart >= 0 && tokenStart < len(text) {
+		offsets = append(offsets, tokenStart)
+	}
+	return offsets
+}
+
+// isWide reports whether r belongs to a script where each character is
+// typically encoded as at least one token.
+func isWide(r rune) bool {
+	return utf8.RuneLen(r) >= 3 && (unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r) ||
+		unicode.Is(unicode.Thai, r))
+}
@@ -0,0 +1,90 @@
+// Package tokenizer counts and slices text in model tokens, so content limits
+// can be expressed in the unit agents actually budget.
+package tokenizer
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tokenizer splits text into model tokens. Implementations must be safe for
+// concurrent use and must not require network access.
+type Tokenizer interface {
+	// Name returns the identifier used to select the tokenizer in configuration.
+	Name() string
+
+	// Count returns the number of tokens in text.
+	Count(text string) int
+
+	// Offsets returns the byte offset at which each token of text starts.
+	// The returned slice has one entry per token, in increasing order.
+	Offsets(text string) []int
+}
+
+const (
+	// NameCL100K selects the cl100k_base BPE used by GPT-3.5/GPT-4 class models.
+	NameCL100K = "cl100k"
+	// NameHeuristic selects a fast estimator that needs no vocabulary.
+	NameHeuristic = "heuristic"
+)
+
+// Names lists every supported tokenizer name.
+var Names = []string{NameCL100K, NameHeuristic}
+
+// New returns the tokenizer registered under name. An empty name selects the
+// heuristic, which is cheap enough to count every document fetched.
+func New(name string) (Tokenizer, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case NameCL100K, "cl100k_base":
+		return NewCL100K(), nil
+	case "", NameHeuristic:
+		return NewHeuristic(), nil
+	}
+	return nil, fmt.Errorf("unsupported tokenizer %q (expected one of %s)", name, strings.Join(Names, ", "))
+}
+
+// Trim returns the part of text starting at token startToken and spanning at
+// most maxTokens tokens. A maxTokens of 0 or less means no limit.
+// The start is moved forward and the end backward to the nearest rune boundary,
+// so the result is valid UTF-8 and never exceeds the budget, even when a token
+// ends inside a multi-byte character.
+func Trim(t Tokenizer, text string, startToken int, maxTokens int) string {
+	if startToken <= 0 && maxTokens <= 0 {
+		return text
+	}
+	offsets := t.Offsets(text)
+
+	if startToken < 0 {
+		startToken = 0
+	}
+	if startToken >= len(offsets) {
+		return ""
+	}
+	start := alignForward(text, offsets[startToken])
+
+	end := len(text)
+	if maxTokens > 0 && startToken+maxTokens < len(offsets) {
+		end = alignBackward(text, offsets[startToken+maxTokens])
+	}
+	if end < start {
+		end = start
+	}
+	return text[start:end]
+}
+
+// alignForward moves offset forward until it sits on a rune boundary.
+func alignForward(text string, offset int) int {
+	for offset < len(text) && !utf8.RuneStart(text[offset]) {
+		offset++
+	}
+	return offset
+}
+
+// alignBackward moves offset backward until it sits on a rune boundary.
+func alignBackward(text string, offset int) int {
+	for offset > 0 && offset < len(text) && !utf8.RuneStart(text[offset]) {
+		offset--
+	}
+	return offset
+}
@@ -0,0 +1,106 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tok, err := New("")
+	require.NoError(t, err)
+	assert.Equal(t, NameHeuristic, tok.Name())
+
+	tok, err = New("CL100K")
+	require.NoError(t, err)
+	assert.Equal(t, NameCL100K, tok.Name())
+
+	_, err = New("gpt2")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported tokenizer")
+}
+
+func TestCL100K_Count(t *testing.T) {
+	tok := NewCL100K()
+
+	assert.Equal(t, 0, tok.Count(""))
+	assert.Equal(t, 2, tok.Count("hello world")) // [15339, 1917]
+	assert.Less(t, tok.Count(strings.Repeat("the ", 100)), 150)
+}
+
+func TestCL100K_OffsetsCoverText(t *testing.T) {
+	tok := NewCL100K()
+	text := "Hello, 世界! Tokens are fun."
+
+	offsets := tok.Offsets(text)
+	require.Len(t, offsets, tok.Count(text))
+	assert.Equal(t, 0, offsets[0])
+	for i := 1; i < len(offsets); i++ {
+		assert.Greater(t, offsets[i], offsets[i-1])
+	}
+	assert.Less(t, offsets[len(offsets)-1], len(text))
+}
+
+func TestHeuristic_Offsets(t *testing.T) {
+	tok := NewHeuristic()
+
+	tests := []struct {
+		name     string
+		text     string
+		expected []int
+	}{
+		{name: "empty", text: "", expected: []int{}},
+		{name: "short word", text: "Go", expected: []int{0}},
+		{name: "long word is chunked", text: "tokenizer", expected: []int{0, 4, 8}},
+		{name: "whitespace attaches to next token", text: "a  b", expected: []int{0, 1}},
+		{name: "punctuation", text: "hi, you!", expected: []int{0, 2, 3, 7}},
+		{name: "CJK characters", text: "日本語", expected: []int{0, 3, 6}},
+		{name: "trailing whitespace", text: "end \n", expected: []int{0, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tok.Offsets(tt.text))
+			assert.Equal(t, len(tt.expected), tok.Count(tt.text))
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	tok := NewHeuristic()
+	text := "one two three four"
+
+	tests := []struct {
+		name       string
+		startToken int
+		maxTokens  int
+		expected   string
+	}{
+		{name: "no limits", startToken: 0, maxTokens: 0, expected: text},
+		{name: "max tokens", startToken: 0, maxTokens: 2, expected: "one two"},
+		{name: "start token", startToken: 2, maxTokens: 0, expected: " three four"},
+		{name: "start and max", startToken: 1, maxTokens: 1, expected: " two"},
+		{name: "start beyond end", startToken: 10, maxTokens: 1, expected: ""},
+		{name: "negative start", startToken: -3, maxTokens: 1, expected: "one"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Trim(tok, text, tt.startToken, tt.maxTokens))
+		})
+	}
+}
+
+func TestTrim_KeepsValidUTF8(t *testing.T) {
+	tok := NewCL100K()
+	text := strings.Repeat("漢字とカタカナ ", 20)
+
+	for n := 1; n < 30; n++ {
+		trimmed := Trim(tok, text, 0, n)
+		assert.True(t, utf8.ValidString(trimmed), "max_tokens=%d produced invalid UTF-8", n)
+		assert.LessOrEqual(t, tok.Count(trimmed), n)
+	}
+}
@@ -6,12 +6,351 @@ type FetchResponse struct {
 	ContentType string `json:"content_type"`
 	Content     string `json:"content"`
 	StatusCode  int    `json:"status_code"`
+	// Format is the output format the content was converted to (markdown, text, html or json).
+	Format string `json:"format,omitempty"`
 	// OriginalURL is set only if a redirect occurred. It represents the initial URL before any redirects.
 	OriginalURL string `json:"original_url,omitempty"`
+	// FinalURL is set only if a redirect occurred. It is the URL the content was fetched from.
+	FinalURL string `json:"final_url,omitempty"`
+	// SelectorMatches is the number of elements matched when a CSS selector was used.
+	SelectorMatches int `json:"selector_matches,omitempty"`
+	// Metadata is set only when requested with include_metadata and the content is HTML.
+	Metadata *PageMetadata `json:"metadata,omitempty"`
+	// Section is the anchor of the heading the content was scoped to, if any.
+	Section string `json:"section,omitempty"`
+	// Tokens is the token count of Content; TotalTokens counts the whole processed document.
+	Tokens      int `json:"tokens"`
+	TotalTokens int `json:"total_tokens"`
+	// Flags warn that the content may not be what was asked for: http_error,
+	// soft_404, login_wall or bot_challenge.
+	Flags []string `json:"flags,omitempty"`
+	// ResponseInfo is set only when requested with include_response_info.
+	ResponseInfo *ResponseInfo `json:"response_info,omitempty"`
+	// ETag and LastModified are the validators of the response, used to make
+	// a later request conditional.
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	// NotModified is set when a conditional request got a 304 response; the
+	// content is then empty.
+	NotModified bool `json:"not_modified,omitempty"`
+}
+
+// ResponseInfo - Transfer details of the final response of a fetch
+type ResponseInfo struct {
+	RemoteAddr        string            `json:"remote_addr,omitempty"` // IP and port of the server
+	Protocol          string            `json:"protocol"`              // e.g. HTTP/1.1 or HTTP/2.0
+	TLSVersion        string            `json:"tls_version,omitempty"`
+	Headers           map[string]string `json:"headers"`            // Selected headers, lower-cased names
+	CompressedBytes   int64             `json:"compressed_bytes"`   // Body bytes received, before Content-Encoding is undone
+	DecompressedBytes int64             `json:"decompressed_bytes"` // Body bytes after decoding
+	SHA256            string            `json:"sha256"`             // Hex digest of the decoded body
+	Timings           *ResponseTimings  `json:"timings"`
+}
+
+// ResponseTimings - Durations of a fetch in milliseconds. DNS, connect and TLS
+// are zero when a connection was reused; TTFB and total count from the start
+// of the request, including redirects.
+type ResponseTimings struct {
+	DNSMs        float64 `json:"dns_ms"`
+	ConnectMs    float64 `json:"connect_ms"`
+	TLSMs        float64 `json:"tls_ms"`
+	TTFBMs       float64 `json:"ttfb_ms"`
+	TotalMs      float64 `json:"total_ms"`      // Until the body was read
+	ProcessingMs float64 `json:"processing_ms"` // Content extraction and conversion (readability, markdown)
+}
+
+// PageMetadata - Document-level metadata extracted from an HTML page
+type PageMetadata struct {
+	Title         string            `json:"title,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	CanonicalURL  string            `json:"canonical_url,omitempty"`
+	Language      string            `json:"language,omitempty"`
+	Author        string            `json:"author,omitempty"`
+	PublishedTime string            `json:"published_time,omitempty"`
+	ModifiedTime  string            `json:"modified_time,omitempty"`
+	OpenGraph     map[string]string `json:"open_graph,omitempty"` // og:* properties without the "og:" prefix
+	Twitter       map[string]string `json:"twitter,omitempty"`    // twitter:* card fields without the "twitter:" prefix
+	JSONLD        []map[string]any  `json:"json_ld,omitempty"`    // Supported schema.org JSON-LD items
+	Feeds         []FeedLink        `json:"feeds,omitempty"`
+}
+
+// FeedLink - RSS/Atom feed declared with <link rel="alternate">
+type FeedLink struct {
+	URL   string `json:"url"`
+	Type  string `json:"type"`
+	Title string `json:"title,omitempty"`
+}
+
+// StructuredDocument - Document returned by the json output format
+type StructuredDocument struct {
+	Title  string   `json:"title,omitempty"`
+	Byline string   `json:"byline,omitempty"`
+	Blocks []*Block `json:"blocks"`
+}
+
+// Block - Structured content block. Blocks following a heading are nested in
+// its Children until the next heading of the same or a higher level.
+type Block struct {
+	Type     string     `json:"type"`               // heading, paragraph, list, item, code, table or blockquote
+	Level    int        `json:"level,omitempty"`    // Heading level (1-6)
+	Text     string     `json:"text,omitempty"`     // Text of headings, paragraphs, list items and code
+	Ordered  bool       `json:"ordered,omitempty"`  // Set for ordered lists
+	Language string     `json:"language,omitempty"` // Code language, when declared with a language-* class
+	Rows     [][]string `json:"rows,omitempty"`     // Table cells by row
+	Children []*Block   `json:"children,omitempty"` // Section content, list items, nested lists or quoted blocks
 }
 
 // MultipleFetchResponse - Multiple URLs fetch response
 type MultipleFetchResponse struct {
-	Responses map[string]*FetchResponse `json:"responses"` // Map of responses with URLs as keys
-	Errors    map[string]string         `json:"errors"`    // Map of error messages with failed URLs as keys
+	// Results holds one entry per requested URL, in request order.
+	Results   []*FetchResult `json:"results"`
+	Unit      string         `json:"unit"`       // Unit of the lengths below: chars or tokens
+	MaxLength int            `json:"max_length"` // Total length distributed among the results
+	Succeeded int            `json:"succeeded"`
+	Failed    int            `json:"failed"`    // Including URLs that timed out
+	TimedOut  int            `json:"timed_out"` // URLs not fetched before the deadline
+	// Continuation is set when content was truncated. Passing it back to
+	// fetch_multiple returns the next slice of every truncated document.
+	Continuation string `json:"continuation,omitempty"`
+}
+
+// FetchResult - Result for one URL of a fetch_multiple request
+type FetchResult struct {
+	URL         string `json:"url"`                 // URL as requested
+	FinalURL    string `json:"final_url,omitempty"` // URL after redirects
+	StatusCode  int    `json:"status_code,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Format      string `json:"format,omitempty"`
+	Content     string `json:"content,omitempty"`
+	StartIndex  int    `json:"start_index,omitempty"` // Offset of Content in the processed document
+	// Error is set instead of the content if the URL could not be fetched or processed.
+	Error *FetchError `json:"error,omitempty"`
+	// TimedOut is set if the URL was not fetched before the deadline.
+	TimedOut bool `json:"timed_out,omitempty"`
+	// AllocatedLength is the length of Content and FullLength that of the
+	// whole processed document, in the response's unit.
+	AllocatedLength int  `json:"allocated_length"`
+	FullLength      int  `json:"full_length"`
+	Truncated       bool `json:"truncated"`
+	Tokens          int  `json:"tokens"`
+	TotalTokens     int  `json:"total_tokens"`
+	// Flags are the page flags of FetchResponse.Flags.
+	Flags []string `json:"flags,omitempty"`
+	// ResponseInfo is set only when requested with include_response_info.
+	ResponseInfo *ResponseInfo `json:"response_info,omitempty"`
+}
+
+// FetchError - Error returned by a tool, or for one URL of a fetch_multiple request
+type FetchError struct {
+	Code      string `json:"code"` // Error kind, e.g. dns, timeout, tls, http_status or robots
+	Message   string `json:"message"`
+	Retryable bool   `json:"retryable"`
+	Stage     string `json:"stage,omitempty"` // fetch_multiple: request (fetching failed) or processing (content conversion failed)
+}
+
+// Link - Anchor extracted from a page
+type Link struct {
+	URL      string `json:"url"`
+	Text     string `json:"text"`
+	Rel      string `json:"rel,omitempty"`
+	SameHost bool   `json:"same_host"`
+	// Section is the page region the anchor appears in (nav, header, footer, aside, main or body).
+	Section string `json:"section"`
+}
+
+// LinksResponse - Response from fetch_links operation
+type LinksResponse struct {
+	URL         string `json:"url"`
+	StatusCode  int    `json:"status_code"`
+	OriginalURL string `json:"original_url,omitempty"`
+	Links       []Link `json:"links"`
+	Total       int    `json:"total"` // Number of anchors found before filtering
+}
+
+// Heading - Entry of a document outline. Offsets refer to the Markdown
+// content returned by fetch with default options.
+type Heading struct {
+	Level    int        `json:"level"`
+	Text     string     `json:"text"`
+	Anchor   string     `json:"anchor"`             // HTML id of the heading, or a slug of its text
+	Offset   int        `json:"offset"`             // Character offset of the heading line
+	Length   int        `json:"length"`             // Length of the section including its subsections
+	Children []*Heading `json:"children,omitempty"` // Subsections
+}
+
+// OutlineResponse - Response from fetch_outline operation
+type OutlineResponse struct {
+	URL           string     `json:"url"`
+	StatusCode    int        `json:"status_code"`
+	OriginalURL   string     `json:"original_url,omitempty"`
+	ContentLength int        `json:"content_length"` // Length of the processed Markdown content
+	Headings      []*Heading `json:"headings"`
+}
+
+// SearchMatch - Paragraph matching a fetch_search query
+type SearchMatch struct {
+	Text        string   `json:"text"`                   // The matching paragraph
+	Offset      int      `json:"offset"`                 // Character offset of the paragraph in the processed content
+	Length      int      `json:"length"`                 // Length of the paragraph
+	Score       int      `json:"score"`                  // Number of query matches in the paragraph
+	HeadingPath []string `json:"heading_path,omitempty"` // Enclosing headings, outermost first
+	Before      []string `json:"before,omitempty"`       // Paragraphs preceding the match
+	After       []string `json:"after,omitempty"`        // Paragraphs following the match
+}
+
+// SearchResponse - Response from fetch_search operation
+type SearchResponse struct {
+	URL           string        `json:"url"`
+	StatusCode    int           `json:"status_code"`
+	OriginalURL   string        `json:"original_url,omitempty"`
+	Query         string        `json:"query"`
+	ContentLength int           `json:"content_length"` // Length of the processed content
+	Total         int           `json:"total"`          // Number of matching paragraphs before max_results
+	Matches       []SearchMatch `json:"matches"`
+}
+
+// CrawlPage - Page visited by a crawl
+type CrawlPage struct {
+	URL         string `json:"url"`
+	OriginalURL string `json:"original_url,omitempty"` // Set only if a redirect occurred
+	Depth       int    `json:"depth"`                  // Number of links followed from the start URL
+	StatusCode  int    `json:"status_code,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Summary     string `json:"summary,omitempty"` // Description or first paragraph, unless content was requested
+	Content     string `json:"content,omitempty"` // Processed content, trimmed to the remaining budget
+	// ContentLength is the length of the full processed content before trimming.
+	ContentLength int `json:"content_length"`
+	// Links are the in-scope links found on the page, forming the crawl's link graph.
+	Links []string `json:"links,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+// CrawlResponse - Response from crawl operation
+type CrawlResponse struct {
+	StartURL string      `json:"start_url"`
+	Pages    []CrawlPage `json:"pages"` // Pages in breadth-first order
+	// StopReason is why the crawl ended: complete, max_depth, max_pages or budget.
+	StopReason string `json:"stop_reason"`
+	// Unvisited counts in-scope links that were discovered but not fetched.
+	Unvisited int `json:"unvisited"`
+}
+
+// SitemapEntry - URL listed in a sitemap
+type SitemapEntry struct {
+	URL        string   `json:"url"`
+	LastMod    string   `json:"lastmod,omitempty"`
+	ChangeFreq string   `json:"changefreq,omitempty"`
+	Priority   *float64 `json:"priority,omitempty"`
+}
+
+// SitemapResponse - Response from fetch_sitemap operation
+type SitemapResponse struct {
+	URL      string         `json:"url"`
+	Sitemaps []string       `json:"sitemaps"` // Sitemaps read, including nested ones from sitemap indexes
+	Entries  []SitemapEntry `json:"entries"`
+	// URLs lists the entry URLs, ready to pass as the urls argument of fetch_multiple.
+	URLs   []string          `json:"urls"`
+	Total  int               `json:"total"`            // Number of matching entries before the limit
+	Errors map[string]string `json:"errors,omitempty"` // Sitemaps that could not be read, by URL
+}
+
+// HistoryEntry - Document recently returned by fetch or fetch_multiple
+type HistoryEntry struct {
+	URL         string   `json:"url"`
+	Resource    string   `json:"resource,omitempty"` // URI reading the document as an MCP resource
+	StatusCode  int      `json:"status_code"`
+	ContentType string   `json:"content_type"`
+	Format      string   `json:"format,omitempty"`
+	TotalTokens int      `json:"total_tokens"` // Tokens in the whole processed document
+	Flags       []string `json:"flags,omitempty"`
+	FetchedAt   string   `json:"fetched_at"` // RFC 3339
+}
+
+// FetchHistoryResponse - Content of the fetch-history resource
+type FetchHistoryResponse struct {
+	Documents []HistoryEntry `json:"documents"` // Most recent first
+}
+
+// DiffSummary - Lines added and removed between two versions of a document
+type DiffSummary struct {
+	Summary      string   `json:"summary"` // e.g. "2 lines added, 1 removed"
+	AddedLines   int      `json:"added_lines"`
+	RemovedLines int      `json:"removed_lines"`
+	Changes      []string `json:"changes,omitempty"` // First changed lines, prefixed with "+ " or "- "
+}
+
+// WatchStatus - State of a URL polled for changes
+type WatchStatus struct {
+	URL             string       `json:"url"`
+	Resource        string       `json:"resource,omitempty"` // URI of the resource notified on change
+	IntervalSeconds int          `json:"interval_seconds"`
+	ETag            string       `json:"etag,omitempty"`
+	LastModified    string       `json:"last_modified,omitempty"`
+	WatchedAt       string       `json:"watched_at"`           // RFC 3339
+	CheckedAt       string       `json:"checked_at,omitempty"` // Last poll, RFC 3339
+	ChangedAt       string       `json:"changed_at,omitempty"` // Last meaningful change, RFC 3339
+	Checks          int          `json:"checks"`
+	Changes         int          `json:"changes"`
+	LastChange      *DiffSummary `json:"last_change,omitempty"`
+	LastError       *FetchError  `json:"last_error,omitempty"` // Error of the last poll, if it failed
+}
+
+// WatchChange - Meaningful change detected on a watched URL
+type WatchChange struct {
+	URL        string       `json:"url"`
+	Diff       *DiffSummary `json:"diff"`
+	DetectedAt string       `json:"detected_at"` // RFC 3339
+}
+
+// WatchResponse - Response from the watch_url tool
+type WatchResponse struct {
+	Watching bool          `json:"watching"`        // Whether the URL is watched after the call
+	Watch    *WatchStatus  `json:"watch,omitempty"` // Set while the URL is watched
+	Watches  []WatchStatus `json:"watches"`         // Every watched URL
+}
+
+// PageVersion - Version of a page compared by fetch_diff
+type PageVersion struct {
+	URL       string `json:"url"`
+	FetchedAt string `json:"fetched_at"` // RFC 3339
+}
+
+// PageDiffResponse - Response from the fetch_diff tool
+type PageDiffResponse struct {
+	URL       string       `json:"url"`
+	Mode      string       `json:"mode"`               // unified or words
+	From      *PageVersion `json:"from,omitempty"`     // Earlier snapshot or compare_url; unset for a baseline
+	To        PageVersion  `json:"to"`                 // Version fetched now
+	Baseline  bool         `json:"baseline,omitempty"` // No earlier snapshot: the version fetched now was stored, with no diff
+	Changed   bool         `json:"changed"`
+	Summary   string       `json:"summary"` // e.g. "2 lines added, 1 removed"
+	Added     int          `json:"added"`   // Lines (unified) or words (words) added
+	Removed   int          `json:"removed"` // Lines (unified) or words (words) removed
+	Diff      string       `json:"diff"`
+	Truncated bool         `json:"truncated,omitempty"` // Whether the diff was cut at max_length
+	Snapshots []string     `json:"snapshots"`           // Fetch times of the stored versions of url, most recent first
+}
+
+// SummarySection - Section of a page cited by a summary as [ID]
+type SummarySection struct {
+	ID      string `json:"id"` // e.g. "S3"
+	Heading string `json:"heading"`
+	Anchor  string `json:"anchor,omitempty"` // HTML id of the heading, or a slug of its text
+	Level   int    `json:"level,omitempty"`  // Heading level, 0 for the introduction
+	Offset  int    `json:"offset"`           // Character offset of the section in the Markdown content
+}
+
+// SummaryResponse - Response from the fetch_summary tool
+type SummaryResponse struct {
+	URL              string           `json:"url"`
+	StatusCode       int              `json:"status_code"`
+	OriginalURL      string           `json:"original_url,omitempty"`
+	Summary          string           `json:"summary"`             // Cites sections as [S1], [S2], ...
+	Sections         []SummarySection `json:"sections"`            // Sections cited in the summary
+	ContentLength    int              `json:"content_length"`      // Length of the Markdown content
+	Chunks           int              `json:"chunks"`              // Chunks summarized separately
+	SamplingRequests int              `json:"sampling_requests"`   // sampling/createMessage requests sent
+	Truncated        bool             `json:"truncated,omitempty"` // Whether only the first chunks were summarized
+	Model            string           `json:"model,omitempty"`     // Model reported by the client
 }